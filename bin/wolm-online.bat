@@ -6,19 +6,14 @@ SET CATALOG=%USERPROFILE%\.wolm\catalog
 
 ECHO:
 ECHO Getting online content...
-C:\Users\WordofLifeMNMedia\Go\github.com\WordOfLifeMN\online\online.exe -v --output %CACHE% dump
+C:\Users\WordofLifeMNMedia\Go\github.com\WordOfLifeMN\online\online.exe -v --output %CACHE% dump --enrich
 
 ECHO:
-ECHO Validating online content...
-C:\Users\WordofLifeMNMedia\Go\github.com\WordOfLifeMN\online\online.exe --input %CACHE% check
+ECHO Validating, generating, and uploading online catalog...
+C:\Users\WordofLifeMNMedia\Go\github.com\WordOfLifeMN\online\online.exe -v --input %CACHE% publish --output=%CATALOG% --target=s3://wordoflife.mn.catalog
 IF %errorlevel% NEQ 0 (
     pause
     EXIT /b %errorlevel%
 )
 
-ECHO:
-ECHO Generating and uploading online catalog...
-C:\Users\WordofLifeMNMedia\Go\github.com\WordOfLifeMN\online\online.exe -v --input %CACHE% --output=%CATALOG% catalog
-aws s3 sync --size-only --delete --acl=public-read %CATALOG% s3://wordoflife.mn.catalog
-
 pause
@@ -1,6 +1,7 @@
 package catalog
 
 import (
+	"log"
 	"sort"
 	"strings"
 	"time"
@@ -116,6 +117,36 @@ func (c *Catalog) FindSeriByName(seriesName string) (seri *CatalogSeri, ok bool)
 	return nil, false
 }
 
+// AddScripturesFromTranscripts finds the scripture references in the transcripts of all the
+// messages and adds them to the messages, including the copies of the messages in the series.
+// Note this downloads every transcript, so it is slow
+func (c *Catalog) AddScripturesFromTranscripts() {
+	// scriptures by audio URL, which identifies the message in the series
+	found := map[string][]ScriptureReference{}
+	for index := range c.Messages {
+		msg := &c.Messages[index]
+		if !msg.HasTranscript() {
+			continue
+		}
+		text, err := msg.GetTranscriptText()
+		if err != nil {
+			log.Printf("WARNING: %s", err.Error())
+			continue
+		}
+		found[msg.Audio.URL] = ParseScriptureReferences(text)
+		msg.AddScriptures(found[msg.Audio.URL]...)
+	}
+
+	for seriIndex := range c.Series {
+		for msgIndex := range c.Series[seriIndex].Messages {
+			msg := &c.Series[seriIndex].Messages[msgIndex]
+			if msg.HasAudio() {
+				msg.AddScriptures(found[msg.Audio.URL]...)
+			}
+		}
+	}
+}
+
 // FindSeriesByMinistry searches the catalog for all series that are in a specific ministry.
 // Returns a copy of those series, or nil slice if no series with the ministry can be found
 func (c *Catalog) FindSeriesByMinistry(ministries ...Ministry) []CatalogSeri {
@@ -1,17 +1,17 @@
 package catalog
 
 import (
-	"fmt"
+	"context"
+	"html/template"
 	"log"
 	"net/http"
-	"net/url"
-	"os/exec"
-	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/WordOfLifeMN/online/util"
 )
 
 // CatalogMessage describes one message. The message may be part of a series or not. A message
@@ -20,19 +20,23 @@ import (
 // to an external process if that information will be used to assemble related messages into a
 // series
 type CatalogMessage struct {
-	Date        DateOnly          `json:"date"`                  // date message was given/recorded (required)
-	Name        string            `json:"name"`                  // name of the message (required)
-	Description string            `json:"description,omitempty"` // detailed description of this message
-	Speakers    []string          `json:"speakers"`              // names of significant speakers in the message, typically in order they spoke
-	Ministry    Ministry          `json:"ministry"`              // which ministry this message was presented for
-	Type        MessageType       `json:"type"`                  // category of this message
-	Visibility  View              `json:"visibility,omitempty"`  // visibility of this message
-	Series      []SeriesReference `json:"series,omitempty"`      // which series this message belongs to
-	Thumb       *OnlineResource   `json:"thumb,omitempty"`       // URL of the thumbnail
-	Audio       *OnlineResource   `json:"audio,omitempty"`       // URL of the audio file
-	Video       *OnlineResource   `json:"video,omitempty"`       // URL of the video. normally on YouTube, BitChute, Rumble, or S3
-	Resources   []OnlineResource  `json:"resources,omitempty"`   // list of online resources for this message (links, docs, video, etc)
-	initialized bool              `json:"-"`                     // has this object been initialized?
+	Date        DateOnly             `json:"date"`                  // date message was given/recorded (required)
+	Name        string               `json:"name"`                  // name of the message (required)
+	Description string               `json:"description,omitempty"` // detailed description of this message
+	Speakers    []string             `json:"speakers"`              // names of significant speakers in the message, typically in order they spoke
+	Tags        []string             `json:"tags,omitempty"`        // topics the message is about
+	Ministry    Ministry             `json:"ministry"`              // which ministry this message was presented for
+	AlsoIn      []Ministry           `json:"also-in,omitempty"`     // other ministries whose catalogs also list this message
+	Type        MessageType          `json:"type"`                  // category of this message
+	Visibility  View                 `json:"visibility,omitempty"`  // visibility of this message
+	Series      []SeriesReference    `json:"series,omitempty"`      // which series this message belongs to
+	Thumb       *OnlineResource      `json:"thumb,omitempty"`       // URL of the thumbnail
+	Audio       *OnlineResource      `json:"audio,omitempty"`       // URL of the audio file
+	Video       *OnlineResource      `json:"video,omitempty"`       // URL of the video. normally on YouTube, BitChute, Rumble, or S3
+	Resources   []OnlineResource     `json:"resources,omitempty"`   // list of online resources for this message (links, docs, video, etc)
+	Scriptures  []ScriptureReference `json:"scriptures,omitempty"`  // scripture passages the message refers to
+	Media       *MediaFacts          `json:"media,omitempty"`       // facts about the audio, from 'dump --enrich'. nil if not known
+	initialized bool                 `json:"-"`                     // has this object been initialized?
 }
 
 // transcript cache is a map of year to list of available transcript names. the list of names is
@@ -54,6 +58,9 @@ func (m *CatalogMessage) Copy() CatalogMessage {
 	copy(msg.Speakers, m.Speakers)
 	copy(msg.Series, m.Series)
 	copy(msg.Resources, m.Resources)
+	msg.AlsoIn = slices.Clone(m.AlsoIn)
+	msg.Scriptures = slices.Clone(m.Scriptures)
+	msg.Tags = slices.Clone(m.Tags)
 
 	return msg
 }
@@ -61,8 +68,9 @@ func (m *CatalogMessage) Copy() CatalogMessage {
 // initialize prepares the message for use. Performs the following checks:
 //   - If the audio/video URL isn't a URL, then deletes it (assumes it was one of the statuses,
 //     like "in progress", "rendering", etc)
-//   - If the speakers are one of the well-known ones (Vern, Mary, Dave), then make sure the name
-//     is correct
+//   - If the speakers are one of the well-known ones in the speaker registry, then make sure the
+//     name is correct
+//   - Adds the scripture references in the description to the message scriptures
 func (m *CatalogMessage) Initialize() error {
 	if m.initialized {
 		return nil
@@ -79,39 +87,16 @@ func (m *CatalogMessage) Initialize() error {
 		m.Video = nil
 	}
 
-	// clean the names
+	// clean the names (this is mostly a convenience so full titles and names don't need to be
+	// typed in the spreadsheet)
 	for index, speaker := range m.Speakers {
-		m.Speakers[index] = m.normalizeSpeakerName(speaker)
+		m.Speakers[index] = speakerRegistry.NormalizeName(speaker, m.Ministry)
 	}
 
-	return nil
-}
-
-// normalizeSpeakerName standardizes the speaker name abbreviations and adds titles where
-// appropriate (this is mostly a convenience function so I don't have to type full titles and
-// names in the spreadsheet)
-func (m *CatalogMessage) normalizeSpeakerName(speaker string) string {
-	speaker = strings.TrimSpace(speaker)
-
-	switch strings.ToLower(speaker) {
-	case "vp", "vern", "vern peltz", "pastor vern peltz", "pastor vern":
-		speaker = "Pastor Vern Peltz"
-	case "dw", "dave", "dave warren", "pastor dave", "pastor warren", "warren":
-		speaker = "Pastor Dave Warren"
-	case "ji", "jim", "jim isakson", "isakson":
-		speaker = "Pastor Jim Isakson"
-	case "ik", "igor", "igor kondratyuk", "pastor igor kondratyuk", "pastor igor", "pastor kondratyuk", "kondratyuk":
-		speaker = "Pastor Igor Kondratyuk"
-	case "tk", "tania", "tania kondratyuk":
-		speaker = "Pastor Tania Kondratyuk"
-	case "mp", "mary", "mary peltz", "pastor mary peltz", "pastor mary":
-		speaker = "Pastor Mary Peltz"
-		if m.Ministry == CenterOfRelationshipExperience {
-			speaker = "Mary Peltz"
-		}
-	}
+	// find the scriptures mentioned in the description
+	m.AddScriptures(ParseScriptureReferences(m.Description)...)
 
-	return speaker
+	return nil
 }
 
 // +---------------------------------------------------------------------------
@@ -135,6 +120,38 @@ func (m *CatalogMessage) SpeakerString() string {
 	return strings.Join(m.Speakers, ", ")
 }
 
+// SpeakerLinks gets all the speakers as HTML, where each speaker links to their page for the
+// ministry and view of the page the message is listed on
+func (m *CatalogMessage) SpeakerLinks(ministry Ministry, view View) template.HTML {
+	return getSpeakerLinks(m.Speakers, ministry, view)
+}
+
+// TagLinks gets all the tags as HTML, where each tag links to its page for the ministry and view
+// of the page the message is listed on
+func (m *CatalogMessage) TagLinks(ministry Ministry, view View) template.HTML {
+	return getTagLinks(m.Tags, ministry, view)
+}
+
+// HasTag determines if the message is about the topic
+func (m *CatalogMessage) HasTag(tag string) bool {
+	return hasTag(m.Tags, tag)
+}
+
+// DescriptionHTML gets the description as HTML, where each scripture reference links to its
+// chapter in the scripture index for the ministry and view of the page the message is listed on
+func (m *CatalogMessage) DescriptionHTML(ministry Ministry, view View) template.HTML {
+	return linkScriptureReferences(m.Description, ministry, view)
+}
+
+// AddScriptures adds scripture references to the message, skipping any it already has
+func (m *CatalogMessage) AddScriptures(refs ...ScriptureReference) {
+	for _, ref := range refs {
+		if !slices.Contains(m.Scriptures, ref) {
+			m.Scriptures = append(m.Scriptures, ref)
+		}
+	}
+}
+
 func (m *CatalogMessage) HasAudio() bool {
 	return m != nil && m.Audio != nil && strings.Contains(m.Audio.URL, "://")
 }
@@ -143,13 +160,20 @@ func (m *CatalogMessage) HasVideo() bool {
 	return m != nil && m.Video != nil && strings.Contains(m.Video.URL, "://")
 }
 
-// GetAudioSize gets the size of the audio file in bytes. Returns -1 on error,
-// or 0 if no audio URL. Note this makes network calls to get the content size
+// GetAudioSize gets the size of the audio file in bytes. Returns -1 on error or if the size is
+// unknown, or 0 if no audio URL. Note this makes network calls to get the content size, unless
+// the media facts were found, which are used even if they don't know the size
 func (m *CatalogMessage) GetAudioSize() int {
 	m.Initialize()
 	if m.Audio == nil {
 		return 0
 	}
+	if m.Media != nil {
+		if m.Media.AudioSize <= 0 {
+			return -1
+		}
+		return int(m.Media.AudioSize)
+	}
 
 	resp, err := http.Head(m.Audio.URL)
 	if err != nil {
@@ -171,6 +195,8 @@ func (m *CatalogMessage) GetAudioSize() int {
 	return length
 }
 
+// HasTranscript determines if there is a transcript of the audio of the message. This is in the
+// media facts if they were found, otherwise the transcripts are listed, which makes network calls
 func (m *CatalogMessage) HasTranscript() bool {
 	// fast fail if no audio
 	m.Initialize()
@@ -178,6 +204,16 @@ func (m *CatalogMessage) HasTranscript() bool {
 		return false
 	}
 
+	// already found it
+	if m.Media != nil {
+		return m.Media.Transcript
+	}
+
+	// with a local copy of the transcripts, just look for the file
+	if transcriptDir != "" {
+		return util.IsFile(getTranscriptFilePath(m.GetTranscriptURL(".text")))
+	}
+
 	// on first call, load all years 2005-current in parallel
 	transcriptCacheOnce.Do(m.LoadTranscriptsCache)
 
@@ -186,14 +222,10 @@ func (m *CatalogMessage) HasTranscript() bool {
 		return false
 	}
 
-	audioURL, err := url.Parse(m.Audio.URL)
-	if err != nil {
-		log.Printf("WARNING: Could not parse audio URL %q: %s", m.Audio.URL, err.Error())
+	audioName := m.getTranscriptName()
+	if audioName == "" {
 		return false
 	}
-	audioName := filepath.Base(audioURL.Path)
-	audioName = strings.TrimSuffix(audioName, filepath.Ext(audioName))
-	audioName = strings.ReplaceAll(audioName, "+", " ")
 
 	// TODO(km)
 	// if strings.Contains(audioName, "%") || strings.Contains(audioName, ",") {
@@ -222,45 +254,23 @@ func (m *CatalogMessage) LoadTranscriptsCache() {
 }
 
 // LoadTranscriptCacheForYear loads the transcript cache for the specified year. Given the year,
-// this will call the aws cli to list the transcript files for s3://wordoflife.mn.audio/<year>/xscript/
-// and return a list of the base names (without extensions) of each transcript file ending with
-// .text for that year.
+// this will list the transcript files in <year>/xscript/ of the audio storage and return a list
+// of the base names (without extensions) of each transcript file ending with .text for that year.
 func (m *CatalogMessage) LoadTranscriptCacheForYear(year int) []string {
-	// get the list of transcript files from S3
-	cmd := exec.Command("aws", "s3", "ls", fmt.Sprintf("s3://wordoflife.mn.audio/%d/xscript/", year))
-	output, err := cmd.Output()
+	xscriptBaseNames, err := listTranscriptNames(context.Background(), year)
 	if err != nil {
+		log.Printf("WARNING: Could not list the transcripts for %d: %s", year, err.Error())
 		return []string{}
 	}
-
-	// parse the output to get the base names
-	var xscriptBaseNames []string
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		if !strings.HasSuffix(line, ".text") {
-			continue
-		}
-		line = strings.TrimSuffix(line, ".text")
-
-		// the line is in the format "date time size filename", and filename may contain spaces.
-		// get the filename by compressing spaces, then parsing on up to 3 spaces
-		line = strings.Join(strings.Fields(line), " ")
-		parts := strings.SplitN(line, " ", 4)
-		xscriptBaseNames = append(xscriptBaseNames, strings.TrimSpace(parts[3]))
-	}
-
-	// TODO(km)
-	// log.Printf("List of transcripts for year %d:\n%#v", year, xscriptBaseNames)
-	// time.Sleep(5 * time.Second)
-
 	return xscriptBaseNames
 }
 
+// GetTranscriptText gets the plain text transcript of the message. Note this makes a network
+// call unless there is a transcript directory, so check HasTranscript first
+func (m *CatalogMessage) GetTranscriptText() (string, error) {
+	return m.GetTranscript(".text")
+}
+
 func (m *CatalogMessage) GetTranscriptURL(ext string) string {
 	if !m.HasAudio() {
 		return ""
@@ -299,3 +309,18 @@ func (m *CatalogMessage) FindSeriesReference(seriesName string) *SeriesReference
 	}
 	return nil
 }
+
+// IsListedIn determines if the message is displayed in the catalog of a ministry. A message is
+// listed in the catalog of its own ministry, of any ministry it is also in, and of any ministry
+// that includes one of those (see Ministry.Includes)
+func (m *CatalogMessage) IsListedIn(ministry Ministry) bool {
+	if ministry.Includes(m.Ministry) {
+		return true
+	}
+	for _, other := range m.AlsoIn {
+		if ministry.Includes(other) {
+			return true
+		}
+	}
+	return false
+}
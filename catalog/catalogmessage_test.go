@@ -74,6 +74,20 @@ func (t *CatalogMessageTestSuite) TestInitializeVideo() {
 	t.Nil(sut.Video)
 }
 
+func (t *CatalogMessageTestSuite) TestInitializeScriptures() {
+	msg := CatalogMessage{
+		Name:        "MESSAGE",
+		Description: "Walking in love (1 Cor 13:4-8) and in faith (Heb 11)",
+		Scriptures:  []ScriptureReference{{Book: "Hebrews", Chapter: 11}},
+	}
+
+	t.NoError(msg.Initialize())
+	t.Equal([]ScriptureReference{
+		{Book: "Hebrews", Chapter: 11},
+		{Book: "1 Corinthians", Chapter: 13, Verse: 4, EndVerse: 8},
+	}, msg.Scriptures)
+}
+
 func (t *CatalogMessageTestSuite) TestCopy() {
 	msg := CatalogMessage{
 		Date:        MustParseDateOnly("2021-02-03"),
@@ -99,6 +113,17 @@ func (t *CatalogMessageTestSuite) TestCopy() {
 
 }
 
+func (t *CatalogMessageTestSuite) TestIsListedIn() {
+	msg := CatalogMessage{Name: "MESSAGE", Ministry: CORE_HopeDealers}
+	t.True(msg.IsListedIn(CORE_HopeDealers))
+	t.True(msg.IsListedIn(CenterOfRelationshipExperience))
+	t.False(msg.IsListedIn(WordOfLife))
+
+	msg.AlsoIn = []Ministry{TheBridgeOutreach}
+	t.True(msg.IsListedIn(TheBridgeOutreach))
+	t.False(msg.IsListedIn(CORE_HealthMatters))
+}
+
 // +---------------------------------------------------------------------------
 // | Accessors
 // +---------------------------------------------------------------------------
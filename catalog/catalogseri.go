@@ -1,7 +1,9 @@
 package catalog
 
 import (
+	"html/template"
 	"log"
+	"slices"
 	"sort"
 	"strings"
 
@@ -23,6 +25,8 @@ type CatalogSeri struct {
 	Visibility  View             `json:"visibility"`            // visibility of this series as a whole
 	Jacket      string           `json:"jacket,omitempty"`      // link to the DVD (or CD) jacket for this series
 	Thumbnail   string           `json:"thumbnail,omitempty"`   // link to the thumbnail to use for the series
+	Ministry    Ministry         `json:"ministry,omitempty"`    // ministry the series belongs to, "" to use the ministry of its messages
+	AlsoIn      []Ministry       `json:"also-in,omitempty"`     // other ministries whose catalogs also list this series
 
 	// cached or generated data. note that this data could be customized for different views of
 	// the series. when read from the online content, the view is "Raw" and the list of messages
@@ -32,6 +36,7 @@ type CatalogSeri struct {
 	View        View             `json:"-"`                   // view of this cached data, "Raw" if unfiltered yet
 	Messages    []CatalogMessage `json:"-"`                   // list of messages in the series
 	Speakers    []string         `json:"speakers,omitempty"`  // list of speakers in the series (does not include message speakers)
+	Tags        []string         `json:"tags,omitempty"`      // topics of all the messages in the series
 	Resources   []OnlineResource `json:"resources,omitempty"` // any other online resources (links, docs, youtube, etc) (does not include message resources)
 	State       SeriesState      `json:"state,omitempty"`     // is the series in progress?
 	initialized bool             `json:"-"`                   // has this object been initialized?
@@ -90,6 +95,8 @@ func NewSeriesFromMessage(msg *CatalogMessage) CatalogSeri {
 	seri.Name = msg.Name
 	seri.Description = msg.Description
 	seri.Visibility = msg.Visibility
+	seri.Ministry = msg.Ministry
+	seri.AlsoIn = slices.Clone(msg.AlsoIn)
 	if msg.Thumb != nil {
 		seri.Thumbnail = (*msg.Thumb).URL
 	}
@@ -117,6 +124,8 @@ func (s *CatalogSeri) Copy() CatalogSeri {
 	copy(seri.Speakers, s.Speakers)
 	copy(seri.Booklets, s.Booklets)
 	copy(seri.Resources, s.Resources)
+	seri.Tags = slices.Clone(s.Tags)
+	seri.AlsoIn = slices.Clone(s.AlsoIn)
 
 	seri.Messages = nil
 	for _, message := range s.Messages {
@@ -152,7 +161,7 @@ func (s *CatalogSeri) Initialize() error {
 }
 
 // Normalize updates all the series fields to reflect the data in the messages list. This
-// includes start and stop dates, speakers, and resources.
+// includes start and stop dates, speakers, tags, and resources.
 func (s *CatalogSeri) Normalize() {
 	// fast bail if nothing to do
 	if len(s.Messages) == 0 {
@@ -178,6 +187,7 @@ func (s *CatalogSeri) Normalize() {
 	s.StartDate = DateOnly{}
 	s.StopDate = DateOnly{}
 	s.Speakers = nil
+	s.Tags = nil
 	s.Resources = nil
 
 	// iterate messages and update fields
@@ -197,6 +207,11 @@ func (s *CatalogSeri) Normalize() {
 			s.AddSpeakerToSeries(speaker)
 		}
 
+		// update tags
+		for _, tag := range msg.Tags {
+			s.AddTagToSeries(tag)
+		}
+
 		// update resources
 		for _, resource := range msg.Resources {
 			s.AddResourceToSeries(resource)
@@ -242,20 +257,9 @@ func (s *CatalogSeri) GetID() string {
 		return ""
 	}
 
-	// generate an ID from the name
-	prefix := "ID-"
-	switch s.GetMinistry() {
-	case WordOfLife:
-		prefix = "WOLS-"
-	case CenterOfRelationshipExperience:
-		prefix = "CORE-"
-	case AskThePastor:
-		prefix = "ATP-"
-	case FaithAndFreedom:
-		prefix = "FandF-"
-	case TheBridgeOutreach:
-		prefix = "TBO-"
-	}
+	// generate an ID from the name. the prefix is always the ministry of the first message, so the
+	// ID (and the URL of the series page) doesn't change when the series is given a ministry
+	prefix := s.Messages[0].Ministry.IDPrefix()
 	s.ID = prefix + util.ComputeHash(s.Name)
 
 	return s.ID
@@ -282,6 +286,12 @@ func (s *CatalogSeri) GetCatalogFileName(view View) string {
 	return s.GetViewID(view) + ".html"
 }
 
+// GetPodcastFileName returns the file name of the podcast feed for this seri with the specified
+// view
+func (s *CatalogSeri) GetPodcastFileName(view View) string {
+	return s.GetViewID(view) + ".rss.xml"
+}
+
 // DateString gets the date of the series in a displayable string
 func (s *CatalogSeri) DateString() string {
 	if s.State == State_Unknown || s.State == State_HasNotStarted {
@@ -311,19 +321,63 @@ func (s *CatalogSeri) DateString() string {
 	return s.StartDate.Time.Format("Jan 2, 2006") + " - " + s.StopDate.Time.Format("Jan 2, 2006")
 }
 
-// Gets the Ministry of a series
+// Gets the Ministry of a series. This is the ministry set on the series, or if there isn't one,
+// the ministry of the first message in the series
 func (s *CatalogSeri) GetMinistry() Ministry {
+	if s.Ministry != "" && s.Ministry != UnknownMinistry {
+		return s.Ministry
+	}
 	if len(s.Messages) == 0 {
 		return UnknownMinistry
 	}
 	return s.Messages[0].Ministry
 }
 
+// IsListedIn determines if the series is displayed in the catalog of a ministry. A series is
+// listed in the catalog of its own ministry, of any ministry it or one of its messages is also
+// in, and of any ministry that includes one of those (see Ministry.Includes)
+func (s *CatalogSeri) IsListedIn(ministry Ministry) bool {
+	if ministry.Includes(s.GetMinistry()) {
+		return true
+	}
+	for _, other := range s.AlsoIn {
+		if ministry.Includes(other) {
+			return true
+		}
+	}
+	for _, msg := range s.Messages {
+		for _, other := range msg.AlsoIn {
+			if ministry.Includes(other) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // SpeakerString gets the list of speakers as a display string
 func (s *CatalogSeri) SpeakerString() string {
 	return strings.Join(s.Speakers, ", ")
 }
 
+// SpeakerLinks gets the list of speakers as HTML, where each speaker links to their page for
+// the ministry and view of the page the series is listed on
+func (s *CatalogSeri) SpeakerLinks(ministry Ministry, view View) template.HTML {
+	return getSpeakerLinks(s.Speakers, ministry, view)
+}
+
+// TagLinks gets the list of tags as HTML, where each tag links to its page for the ministry and
+// view of the page the series is listed on
+func (s *CatalogSeri) TagLinks(ministry Ministry, view View) template.HTML {
+	return getTagLinks(s.Tags, ministry, view)
+}
+
+// DescriptionHTML gets the description as HTML, where each scripture reference links to its
+// chapter in the scripture index for the ministry and view of the page the series is listed on
+func (s *CatalogSeri) DescriptionHTML(ministry Ministry, view View) template.HTML {
+	return linkScriptureReferences(s.Description, ministry, view)
+}
+
 // MessageTitlesString gets the list of message titles as a display string. This is for display
 // and only produces output if the list of message titles is different than the series name
 // (i.e. if this isn't a stand-alone message)
@@ -350,6 +404,11 @@ func (s *CatalogSeri) AddSpeakerToSeries(speaker string) {
 	s.Speakers = append(s.Speakers, speaker)
 }
 
+// AddTagToSeries adds a tag to the list of series tags if it isn't already in the list
+func (s *CatalogSeri) AddTagToSeries(tag string) {
+	s.Tags = addTag(s.Tags, tag)
+}
+
 // AddResourceToSeries adds a resource to the list of series and message resources if it isn't
 // already in the list
 func (s *CatalogSeri) AddResourceToSeries(resource OnlineResource) {
@@ -372,6 +431,16 @@ func (s *CatalogSeri) IsBooklet() bool {
 	return len(s.Booklets) > 0 && len(s.Messages) == 0
 }
 
+// HasAudio reports whether any of the messages in the series has audio
+func (s *CatalogSeri) HasAudio() bool {
+	for index := range s.Messages {
+		if s.Messages[index].HasAudio() {
+			return true
+		}
+	}
+	return false
+}
+
 // IsMessageRelevant reports whether the specified message is relavant for this series. In order
 // for a message to be relevant, it needs to belong to the series, have a non-zero track number,
 // and have a visibility compatible with the series current view
@@ -399,15 +468,14 @@ func (s *CatalogSeri) IsMessageRelevant(msg *CatalogMessage) bool {
 // +---------------------------------------------------------------------------
 
 // FilterSeriesByMinistry takes a slice of series and returns another slice that only contains
-// the series that are in any of the specified ministries. Returns nil slice if none of the
-// series in the input slice is in the ministries
+// the series that are listed in any of the specified ministries (see IsListedIn). Returns nil
+// slice if none of the series in the input slice is in the ministries
 func FilterSeriesByMinistry(corpus []CatalogSeri, ministries ...Ministry) []CatalogSeri {
 	var series []CatalogSeri
 
 	for _, seri := range corpus {
-		seriMinistry := seri.GetMinistry()
 		for _, ministry := range ministries {
-			if seriMinistry == ministry {
+			if seri.IsListedIn(ministry) {
 				series = append(series, seri)
 				break
 			}
@@ -454,6 +522,63 @@ func FilterSeriesByView(corpus []CatalogSeri, view View) []CatalogSeri {
 	return series
 }
 
+// FilterSeriesBySpeaker takes a slice of series and returns another slice that contains the
+// series that have messages by the speaker. The resulting series only include the messages by
+// the speaker. Speaker names must match exactly, so the series should be from an initialized
+// catalog where the speaker names are normalized
+func FilterSeriesBySpeaker(corpus []CatalogSeri, speaker string) []CatalogSeri {
+	var series []CatalogSeri
+
+	for _, seri := range corpus {
+		// make a copy of the series with only the speaker's messages
+		candidate := seri.Copy()
+		candidate.Messages = nil
+		for _, msg := range seri.Messages {
+			if slices.Contains(msg.Speakers, speaker) {
+				candidate.Messages = append(candidate.Messages, msg)
+			}
+		}
+
+		// if the speaker has no messages then skip it
+		if len(candidate.Messages) == 0 {
+			continue
+		}
+
+		candidate.Normalize()
+		series = append(series, candidate)
+	}
+
+	return series
+}
+
+// FilterSeriesByTag takes a slice of series and returns another slice that contains the series
+// with messages about the topic. The series in the result only contain the messages with the
+// tag. Returns nil slice if no series has a message with the tag
+func FilterSeriesByTag(corpus []CatalogSeri, tag string) []CatalogSeri {
+	var series []CatalogSeri
+
+	for _, seri := range corpus {
+		// make a copy of the series with only the tagged messages
+		candidate := seri.Copy()
+		candidate.Messages = nil
+		for _, msg := range seri.Messages {
+			if msg.HasTag(tag) {
+				candidate.Messages = append(candidate.Messages, msg)
+			}
+		}
+
+		// if no message has the tag then skip it
+		if len(candidate.Messages) == 0 {
+			continue
+		}
+
+		candidate.Normalize()
+		series = append(series, candidate)
+	}
+
+	return series
+}
+
 // FilterSeriesByVisibility takes a slice of series and returns another slice that contains the
 // series that have the specific visibility. In other words, if you ask for a "public" view and
 // there is a "public" series with a "private" message, the "private" message will be removed
@@ -4,6 +4,7 @@ import (
 	"sort"
 	"testing"
 
+	"github.com/WordOfLifeMN/online/util"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -240,6 +241,25 @@ func (t *CatalogSeriTestSuite) TestSeriesNormalization_Speakers() {
 	t.Equal("Sven", sut.Speakers[3])
 }
 
+func (t *CatalogSeriTestSuite) TestSeriesNormalization_Tags() {
+	// given
+	seri := CatalogSeri{
+		Name: "SERIES",
+		Tags: []string{"old"},
+		Messages: []CatalogMessage{
+			{Name: "MSG-A", Tags: []string{"healing", "faith"}},
+			{Name: "MSG-B"},
+			{Name: "MSG-C", Tags: []string{"Healing", "marriage"}},
+		},
+	}
+
+	// when
+	seri.Normalize()
+
+	// then
+	t.Equal([]string{"healing", "faith", "marriage"}, seri.Tags)
+}
+
 func (t *CatalogSeriTestSuite) TestSeriesNormalization_Resources() {
 	// given
 	sut := CatalogSeri{
@@ -440,6 +460,21 @@ func (t *CatalogSeriTestSuite) TestSeriesViewID() {
 	t.NotEqual(partnerID, privateID)
 }
 
+func (t *CatalogSeriTestSuite) TestSeriesFileNames() {
+	// given
+	sut := CatalogSeri{
+		Name: "SERIES",
+		Messages: []CatalogMessage{
+			{Name: "MESSAGE", Ministry: WordOfLife},
+		},
+	}
+
+	// then
+	t.Equal(sut.GetViewID(Public)+".html", sut.GetCatalogFileName(Public))
+	t.Equal(sut.GetViewID(Public)+".rss.xml", sut.GetPodcastFileName(Public))
+	t.Equal(sut.GetViewID(Partner)+".rss.xml", sut.GetPodcastFileName(Partner))
+}
+
 func (t *CatalogSeriTestSuite) TestDateString() {
 	// given message in the future
 	sut := CatalogSeri{
@@ -560,10 +595,84 @@ func (t *CatalogSeriTestSuite) TestBooklet() {
 	t.False(sut.IsBooklet())
 }
 
+func (t *CatalogSeriTestSuite) TestHasAudio() {
+	// given a series without audio
+	sut := CatalogSeri{
+		Name: "SERIES",
+		Messages: []CatalogMessage{
+			{Name: "MESSAGE-A"},
+			{Name: "MESSAGE-B", Video: &OnlineResource{URL: "http://video"}},
+		},
+	}
+
+	// then
+	t.False(sut.HasAudio())
+
+	// when one of the messages has audio
+	sut.Messages[1].Audio = &OnlineResource{URL: "http://audio"}
+
+	// then
+	t.True(sut.HasAudio())
+}
+
 // +---------------------------------------------------------------------------
 // | Filters
 // +---------------------------------------------------------------------------
 
+func (t *CatalogSeriTestSuite) TestGetMinistry() {
+	// no messages
+	seri := CatalogSeri{}
+	t.Equal(UnknownMinistry, seri.GetMinistry())
+
+	// first message wins
+	seri.Messages = []CatalogMessage{
+		{Name: "MSG-A", Ministry: TheBridgeOutreach},
+		{Name: "MSG-B", Ministry: WordOfLife},
+		{Name: "MSG-C", Ministry: WordOfLife},
+	}
+	t.Equal(TheBridgeOutreach, seri.GetMinistry())
+
+	// explicit ministry wins
+	seri.Ministry = FaithAndFreedom
+	t.Equal(FaithAndFreedom, seri.GetMinistry())
+}
+
+func (t *CatalogSeriTestSuite) TestGetID_MixedMinistries() {
+	// the ID comes from the first message, even if the series has its own ministry
+	seri := CatalogSeri{
+		Name: "SERIES",
+		Messages: []CatalogMessage{
+			{Name: "MSG-A", Ministry: TheBridgeOutreach},
+			{Name: "MSG-B", Ministry: WordOfLife},
+			{Name: "MSG-C", Ministry: WordOfLife},
+		},
+	}
+	id := seri.GetID()
+	t.Equal(TheBridgeOutreach.IDPrefix()+util.ComputeHash("SERIES"), id)
+
+	other := seri
+	other.ID = ""
+	other.Ministry = WordOfLife
+	t.Equal(id, other.GetID())
+}
+
+func (t *CatalogSeriTestSuite) TestIsListedIn() {
+	seri := CatalogSeri{
+		Name: "SERIES",
+		Messages: []CatalogMessage{
+			{Name: "MSG-A", Ministry: TheBridgeOutreach},
+			{Name: "MSG-B", Ministry: TheBridgeOutreach, AlsoIn: []Ministry{FaithAndFreedom}},
+		},
+	}
+	t.True(seri.IsListedIn(TheBridgeOutreach))
+	t.True(seri.IsListedIn(FaithAndFreedom))
+	t.False(seri.IsListedIn(WordOfLife))
+
+	seri.AlsoIn = []Ministry{WordOfLife}
+	t.True(seri.IsListedIn(WordOfLife))
+	t.False(seri.IsListedIn(CenterOfRelationshipExperience))
+}
+
 func (t *CatalogSeriTestSuite) TestFilterByMinistry_Empty() {
 	corpus := []CatalogSeri{}
 	t.Nil(FilterSeriesByMinistry(corpus, WordOfLife))
@@ -634,6 +743,119 @@ func (t *CatalogSeriTestSuite) TestFilterByMinistries() {
 	t.Equal("Attachment Disorder", result[3].Name)
 }
 
+func (t *CatalogSeriTestSuite) TestFilterByMinistry_CrossListed() {
+	// given
+	corpus := []CatalogSeri{
+		{
+			Name: "ATP",
+			Messages: []CatalogMessage{
+				{Name: "MSG-A", Ministry: AskThePastor},
+			},
+		},
+		{
+			Name: "HEALTH",
+			Messages: []CatalogMessage{
+				{Name: "MSG-B", Ministry: CORE_HealthMatters},
+			},
+		},
+		{
+			Name:   "TBO",
+			AlsoIn: []Ministry{CORE_HopeDealers},
+			Messages: []CatalogMessage{
+				{Name: "MSG-C", Ministry: TheBridgeOutreach},
+			},
+		},
+	}
+
+	// when
+	result := FilterSeriesByMinistry(corpus, WordOfLife)
+
+	// then
+	t.Len(result, 1)
+	t.Equal("ATP", result[0].Name)
+
+	// when
+	result = FilterSeriesByMinistry(corpus, CenterOfRelationshipExperience)
+
+	// then
+	t.Len(result, 2)
+	t.Equal("HEALTH", result[0].Name)
+	t.Equal("TBO", result[1].Name)
+}
+
+func (t *CatalogSeriTestSuite) TestFilterBySpeaker() {
+	// given
+	corpus := []CatalogSeri{
+		{
+			Name: "SERIES-A",
+			Messages: []CatalogMessage{
+				{Name: "MESSAGE-A1", Speakers: []string{"Sven"}},
+				{Name: "MESSAGE-A2", Speakers: []string{"Ollie"}},
+			},
+		},
+		{
+			Name: "SERIES-B",
+			Messages: []CatalogMessage{
+				{Name: "MESSAGE-B1", Speakers: []string{"Ollie"}},
+			},
+		},
+		{
+			Name: "SERIES-C",
+			Messages: []CatalogMessage{
+				{Name: "MESSAGE-C1", Speakers: []string{"Ollie", "Sven"}},
+			},
+		},
+	}
+
+	// when
+	series := FilterSeriesBySpeaker(corpus, "Sven")
+
+	// then
+	t.Require().Len(series, 2)
+	t.Equal("SERIES-A", series[0].Name)
+	t.Require().Len(series[0].Messages, 1)
+	t.Equal("MESSAGE-A1", series[0].Messages[0].Name)
+	t.Equal([]string{"Sven"}, series[0].Speakers)
+	t.Equal("SERIES-C", series[1].Name)
+
+	// original is unchanged
+	t.Len(corpus[0].Messages, 2)
+
+	// when no messages by the speaker
+	t.Empty(FilterSeriesBySpeaker(corpus, "Lena"))
+}
+
+func (t *CatalogSeriTestSuite) TestFilterByTag() {
+	// given
+	corpus := []CatalogSeri{
+		{
+			Name: "SERIES-1",
+			Messages: []CatalogMessage{
+				{Name: "MSG-A", Tags: []string{"healing"}},
+				{Name: "MSG-B", Tags: []string{"marriage"}},
+			},
+		},
+		{
+			Name: "SERIES-2",
+			Messages: []CatalogMessage{
+				{Name: "MSG-C", Tags: []string{"faith"}},
+			},
+		},
+	}
+
+	// when
+	result := FilterSeriesByTag(corpus, "Healing")
+
+	// then
+	t.Len(result, 1)
+	t.Equal("SERIES-1", result[0].Name)
+	t.Len(result[0].Messages, 1)
+	t.Equal([]string{"healing"}, result[0].Tags)
+	t.Len(corpus[0].Messages, 2)
+
+	t.Nil(FilterSeriesByTag(corpus, "prayer"))
+}
+
 func (t *CatalogSeriTestSuite) TestFilterByView_Series() {
 	// given
 	corpus := []CatalogSeri{
@@ -0,0 +1,263 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// A CatalogDiff describes what changed between two catalogs (typically a copy of what was last
+// published and the current online content). Series are matched by their ID (or their name if
+// they don't have an ID) and messages are matched by their name and date
+
+// kinds of changes to an entry in the catalog
+type DiffChange string
+
+const (
+	DiffAdded   DiffChange = "added"   // entry is only in the new catalog
+	DiffRemoved DiffChange = "removed" // entry is only in the old catalog
+	DiffChanged DiffChange = "changed" // entry is in both catalogs, but some fields are different
+)
+
+// FieldDiff is a field of an entry that changed. The values are the JSON of the field, "" if the
+// field wasn't set
+type FieldDiff struct {
+	Field string `json:"field"`         // name of the field, as in the JSON of the catalog
+	Old   string `json:"old,omitempty"` // value in the old catalog
+	New   string `json:"new,omitempty"` // value in the new catalog
+}
+
+// EntryDiff is a series or message that was added, removed, or changed
+type EntryDiff struct {
+	Kind          string      `json:"kind"`                     // "series" or "message"
+	Key           string      `json:"key"`                      // what the entry was matched by
+	Name          string      `json:"name"`                     // name of the entry
+	Change        DiffChange  `json:"change"`                   // what happened to the entry
+	Fields        []FieldDiff `json:"fields,omitempty"`         // fields that changed, if Change is DiffChanged
+	OldVisibility View        `json:"old-visibility,omitempty"` // visibility in the old catalog, "" if added
+	NewVisibility View        `json:"new-visibility,omitempty"` // visibility in the new catalog, "" if removed
+}
+
+// CatalogDiff is all the differences between two catalogs
+type CatalogDiff struct {
+	Series   []EntryDiff `json:"series,omitempty"`   // series that are different
+	Messages []EntryDiff `json:"messages,omitempty"` // messages that are different
+}
+
+// +---------------------------------------------------------------------------
+// | Constructors
+// +---------------------------------------------------------------------------
+
+// NewCatalogDiff compares two catalogs. Entries in the diff are in the order of the new catalog,
+// followed by the entries that were removed in the order of the old catalog
+func NewCatalogDiff(oldCatalog, newCatalog *Catalog) (*CatalogDiff, error) {
+	diff := &CatalogDiff{}
+
+	oldEntries, err := getSeriDiffEntries(oldCatalog.Series)
+	if err != nil {
+		return nil, err
+	}
+	newEntries, err := getSeriDiffEntries(newCatalog.Series)
+	if err != nil {
+		return nil, err
+	}
+	diff.Series = compareDiffEntries("series", oldEntries, newEntries)
+
+	oldEntries, err = getMessageDiffEntries(oldCatalog.Messages)
+	if err != nil {
+		return nil, err
+	}
+	newEntries, err = getMessageDiffEntries(newCatalog.Messages)
+	if err != nil {
+		return nil, err
+	}
+	diff.Messages = compareDiffEntries("message", oldEntries, newEntries)
+
+	return diff, nil
+}
+
+// diffEntry is a series or message prepared for comparison
+type diffEntry struct {
+	key        string                     // what the entry is matched by
+	name       string                     // name of the entry
+	visibility View                       // visibility of the entry
+	fields     map[string]json.RawMessage // JSON of each field of the entry
+}
+
+// getSeriDiffEntries prepares series for comparison
+func getSeriDiffEntries(series []CatalogSeri) ([]diffEntry, error) {
+	entries := make([]diffEntry, 0, len(series))
+	for _, seri := range series {
+		key := seri.ID
+		if key == "" {
+			key = seri.Name
+		}
+		entry, err := newDiffEntry(key, seri.Name, seri.Visibility, seri)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return uniqueDiffKeys(entries), nil
+}
+
+// getMessageDiffEntries prepares messages for comparison
+func getMessageDiffEntries(messages []CatalogMessage) ([]diffEntry, error) {
+	entries := make([]diffEntry, 0, len(messages))
+	for _, msg := range messages {
+		key := fmt.Sprintf("%s (%s)", msg.Name, msg.Date.String())
+		entry, err := newDiffEntry(key, msg.Name, msg.Visibility, msg)
+		if err != nil {
+			return nil, err
+		}
+		// the media facts are found from the files, they aren't edited
+		delete(entry.fields, "media")
+		entries = append(entries, entry)
+	}
+	return uniqueDiffKeys(entries), nil
+}
+
+// newDiffEntry prepares a series or message for comparison by splitting its JSON into fields
+func newDiffEntry(key string, name string, visibility View, value any) (diffEntry, error) {
+	entry := diffEntry{key: key, name: name, visibility: visibility}
+
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return entry, fmt.Errorf("cannot convert '%s' to JSON: %w", key, err)
+	}
+	if err := json.Unmarshal(bytes, &entry.fields); err != nil {
+		return entry, fmt.Errorf("cannot read the fields of '%s': %w", key, err)
+	}
+	return entry, nil
+}
+
+// uniqueDiffKeys makes sure that each entry has its own key by numbering entries whose key was
+// already used, like "Faith (2021-09-10) #2"
+func uniqueDiffKeys(entries []diffEntry) []diffEntry {
+	counts := map[string]int{}
+	for index := range entries {
+		key := entries[index].key
+		counts[key]++
+		if counts[key] > 1 {
+			entries[index].key = fmt.Sprintf("%s #%d", key, counts[key])
+		}
+	}
+	return entries
+}
+
+// compareDiffEntries finds the entries of a kind ("series" or "message") that were added,
+// removed, or changed
+func compareDiffEntries(kind string, oldEntries, newEntries []diffEntry) []EntryDiff {
+	var diffs []EntryDiff
+
+	oldByKey := map[string]diffEntry{}
+	for _, entry := range oldEntries {
+		oldByKey[entry.key] = entry
+	}
+	newKeys := map[string]bool{}
+
+	for _, newEntry := range newEntries {
+		newKeys[newEntry.key] = true
+
+		oldEntry, ok := oldByKey[newEntry.key]
+		if !ok {
+			diffs = append(diffs, EntryDiff{
+				Kind:          kind,
+				Key:           newEntry.key,
+				Name:          newEntry.name,
+				Change:        DiffAdded,
+				NewVisibility: newEntry.visibility,
+			})
+			continue
+		}
+
+		fields := compareDiffFields(oldEntry.fields, newEntry.fields)
+		if len(fields) == 0 {
+			continue
+		}
+		diffs = append(diffs, EntryDiff{
+			Kind:          kind,
+			Key:           newEntry.key,
+			Name:          newEntry.name,
+			Change:        DiffChanged,
+			Fields:        fields,
+			OldVisibility: oldEntry.visibility,
+			NewVisibility: newEntry.visibility,
+		})
+	}
+
+	for _, oldEntry := range oldEntries {
+		if newKeys[oldEntry.key] {
+			continue
+		}
+		diffs = append(diffs, EntryDiff{
+			Kind:          kind,
+			Key:           oldEntry.key,
+			Name:          oldEntry.name,
+			Change:        DiffRemoved,
+			OldVisibility: oldEntry.visibility,
+		})
+	}
+
+	return diffs
+}
+
+// compareDiffFields finds the fields that are different, sorted by the name of the field
+func compareDiffFields(oldFields, newFields map[string]json.RawMessage) []FieldDiff {
+	var names []string
+	for name := range oldFields {
+		names = append(names, name)
+	}
+	for name := range newFields {
+		if _, ok := oldFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var diffs []FieldDiff
+	for _, name := range names {
+		oldValue, newValue := string(oldFields[name]), string(newFields[name])
+		if oldValue != newValue {
+			diffs = append(diffs, FieldDiff{Field: name, Old: oldValue, New: newValue})
+		}
+	}
+	return diffs
+}
+
+// +---------------------------------------------------------------------------
+// | Accessors
+// +---------------------------------------------------------------------------
+
+// IsEmpty determines if there are no differences between the catalogs
+func (d *CatalogDiff) IsEmpty() bool {
+	return len(d.Series) == 0 && len(d.Messages) == 0
+}
+
+// HasVisibilityChange determines if the visibility of an entry in both catalogs is different
+// in the new catalog
+func (e EntryDiff) HasVisibilityChange() bool {
+	return e.Change == DiffChanged && e.OldVisibility != e.NewVisibility
+}
+
+// BecamePublic determines if the entry is public in the new catalog and wasn't public before,
+// which includes entries that were added as public
+func (e EntryDiff) BecamePublic() bool {
+	return e.NewVisibility == Public && e.OldVisibility != Public
+}
+
+// GetVisibilityChanges gets all the series and messages whose visibility changed or that were
+// added as public, with the ones that became public first
+func (d *CatalogDiff) GetVisibilityChanges() []EntryDiff {
+	var changes []EntryDiff
+	for _, entry := range slices.Concat(d.Series, d.Messages) {
+		if entry.HasVisibilityChange() || entry.BecamePublic() {
+			changes = append(changes, entry)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].BecamePublic() && !changes[j].BecamePublic()
+	})
+	return changes
+}
@@ -0,0 +1,141 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// Runs the test suite as a test
+func TestDiffTestSuite(t *testing.T) {
+	suite.Run(t, new(DiffTestSuite))
+}
+
+type DiffTestSuite struct {
+	suite.Suite
+}
+
+func (t *DiffTestSuite) TestNoChanges() {
+	cat := &Catalog{
+		Series:   []CatalogSeri{{ID: "S1", Name: "Series", Visibility: Public}},
+		Messages: []CatalogMessage{{Name: "Message", Date: MustParseDateOnly("2021-09-10"), Visibility: Public}},
+	}
+
+	diff, err := NewCatalogDiff(cat, cat)
+	t.NoError(err)
+	t.True(diff.IsEmpty())
+	t.Empty(diff.GetVisibilityChanges())
+}
+
+func (t *DiffTestSuite) TestSeries() {
+	oldCatalog := &Catalog{Series: []CatalogSeri{
+		{ID: "S1", Name: "Same", Visibility: Public},
+		{ID: "S2", Name: "Renamed", Visibility: Public},
+		{ID: "S3", Name: "Removed", Visibility: Partner},
+		{Name: "No ID", Visibility: Private},
+	}}
+	newCatalog := &Catalog{Series: []CatalogSeri{
+		{ID: "S1", Name: "Same", Visibility: Public},
+		{ID: "S2", Name: "New Name", Visibility: Public},
+		{ID: "S4", Name: "Added", Visibility: Partner},
+		{Name: "No ID", Visibility: Public},
+	}}
+
+	diff, err := NewCatalogDiff(oldCatalog, newCatalog)
+	t.NoError(err)
+	t.Empty(diff.Messages)
+	if t.Len(diff.Series, 4) {
+		t.Equal(EntryDiff{
+			Kind:          "series",
+			Key:           "S2",
+			Name:          "New Name",
+			Change:        DiffChanged,
+			Fields:        []FieldDiff{{Field: "name", Old: `"Renamed"`, New: `"New Name"`}},
+			OldVisibility: Public,
+			NewVisibility: Public,
+		}, diff.Series[0])
+		t.Equal(DiffAdded, diff.Series[1].Change)
+		t.Equal("S4", diff.Series[1].Key)
+		t.Equal(DiffChanged, diff.Series[2].Change)
+		t.Equal("No ID", diff.Series[2].Key)
+		t.Equal(DiffRemoved, diff.Series[3].Change)
+		t.Equal("S3", diff.Series[3].Key)
+	}
+}
+
+func (t *DiffTestSuite) TestMessages() {
+	oldCatalog := &Catalog{Messages: []CatalogMessage{
+		{Name: "Faith", Date: MustParseDateOnly("2021-09-10"), Visibility: Public, Speakers: []string{"Vern Peltz"}},
+		{Name: "Faith", Date: MustParseDateOnly("2021-09-17"), Visibility: Public},
+	}}
+	newCatalog := &Catalog{Messages: []CatalogMessage{
+		{Name: "Faith", Date: MustParseDateOnly("2021-09-10"), Visibility: Public, Speakers: []string{"Mary Peltz"}, Tags: []string{"faith"}},
+		{Name: "Faith", Date: MustParseDateOnly("2021-09-24"), Visibility: Public},
+	}}
+
+	diff, err := NewCatalogDiff(oldCatalog, newCatalog)
+	t.NoError(err)
+	if t.Len(diff.Messages, 3) {
+		t.Equal("Faith (2021-09-10)", diff.Messages[0].Key)
+		t.Equal([]FieldDiff{
+			{Field: "speakers", Old: `["Vern Peltz"]`, New: `["Mary Peltz"]`},
+			{Field: "tags", Old: "", New: `["faith"]`},
+		}, diff.Messages[0].Fields)
+		t.Equal("Faith (2021-09-24)", diff.Messages[1].Key)
+		t.Equal(DiffAdded, diff.Messages[1].Change)
+		t.Equal("Faith (2021-09-17)", diff.Messages[2].Key)
+		t.Equal(DiffRemoved, diff.Messages[2].Change)
+	}
+}
+
+func (t *DiffTestSuite) TestDuplicateKeys() {
+	oldCatalog := &Catalog{Messages: []CatalogMessage{
+		{Name: "Faith", Date: MustParseDateOnly("2021-09-10"), Visibility: Public},
+	}}
+	newCatalog := &Catalog{Messages: []CatalogMessage{
+		{Name: "Faith", Date: MustParseDateOnly("2021-09-10"), Visibility: Public},
+		{Name: "Faith", Date: MustParseDateOnly("2021-09-10"), Visibility: Private},
+	}}
+
+	diff, err := NewCatalogDiff(oldCatalog, newCatalog)
+	t.NoError(err)
+	if t.Len(diff.Messages, 1) {
+		t.Equal("Faith (2021-09-10) #2", diff.Messages[0].Key)
+		t.Equal(DiffAdded, diff.Messages[0].Change)
+	}
+}
+
+func (t *DiffTestSuite) TestVisibilityChanges() {
+	oldCatalog := &Catalog{
+		Series: []CatalogSeri{{ID: "S1", Name: "Series", Visibility: Public}},
+		Messages: []CatalogMessage{
+			{Name: "Hidden", Date: MustParseDateOnly("2021-09-10"), Visibility: Public},
+			{Name: "Published", Date: MustParseDateOnly("2021-09-17"), Visibility: Partner},
+		},
+	}
+	newCatalog := &Catalog{
+		Series: []CatalogSeri{{ID: "S1", Name: "Series", Visibility: Private}},
+		Messages: []CatalogMessage{
+			{Name: "Hidden", Date: MustParseDateOnly("2021-09-10"), Visibility: Partner},
+			{Name: "Published", Date: MustParseDateOnly("2021-09-17"), Visibility: Public},
+			{Name: "New Public", Date: MustParseDateOnly("2021-09-24"), Visibility: Public},
+			{Name: "New Private", Date: MustParseDateOnly("2021-10-01"), Visibility: Private},
+		},
+	}
+
+	diff, err := NewCatalogDiff(oldCatalog, newCatalog)
+	t.NoError(err)
+
+	changes := diff.GetVisibilityChanges()
+	if t.Len(changes, 4) {
+		// public first, then in the order of the series and messages
+		t.Equal("Published", changes[0].Name)
+		t.True(changes[0].BecamePublic())
+		t.Equal("New Public", changes[1].Name)
+		t.True(changes[1].BecamePublic())
+		t.Equal("Series", changes[2].Name)
+		t.False(changes[2].BecamePublic())
+		t.Equal("Hidden", changes[3].Name)
+		t.False(changes[3].BecamePublic())
+	}
+}
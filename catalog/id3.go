@@ -0,0 +1,156 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ID3Tag is the information about a message that is written into the ID3v2 tag of its audio, so
+// podcast apps and phones can show what the audio is
+type ID3Tag struct {
+	Title     string // name of the message
+	Artist    string // speakers of the message
+	Album     string // series the message is in, "" if it isn't in a series
+	Track     int    // index of the message in the series, 0 if it isn't in a series
+	Year      int    // year the message was given, 0 if unknown
+	Publisher string // ministry the message was presented for
+	Comment   string // summary of the message
+	Cover     []byte // cover art image, nil for no cover art
+	CoverType string // MIME type of the cover art, like "image/png"
+}
+
+// NewID3TagFromMessage creates the tag for the audio of a message. The album is the first series
+// the message is in, other than a stand-alone series. There is no cover art, since that comes
+// from the catalog files
+func NewID3TagFromMessage(msg *CatalogMessage) ID3Tag {
+	tag := ID3Tag{
+		Title:     msg.Name,
+		Artist:    strings.Join(msg.Speakers, ", "),
+		Publisher: msg.Ministry.Description(),
+		Comment:   msg.Description,
+	}
+	if !msg.Date.IsZero() {
+		tag.Year = msg.Date.Year()
+	}
+	// stand-alone messages are in a series of their own, which isn't much of an album
+	for _, ref := range msg.Series {
+		if ref.Name != "SAM" && ref.Name != msg.Name {
+			tag.Album = ref.Name
+			tag.Track = ref.Index
+			break
+		}
+	}
+	return tag
+}
+
+// WriteID3Tag writes the tag into an audio file, replacing any ID3v2 tag that the file already
+// has. The file is replaced all at once, so it isn't left half written if something goes wrong
+func WriteID3Tag(audioPath string, tag *ID3Tag) error {
+	data, err := os.ReadFile(audioPath)
+	if err != nil {
+		return fmt.Errorf("cannot read audio %s: %w", audioPath, err)
+	}
+	audio := data[min(getID3TagSize(data), len(data)):]
+
+	tmpPath := filepath.Join(filepath.Dir(audioPath), "."+filepath.Base(audioPath)+".tmp")
+	if err := os.WriteFile(tmpPath, append(tag.Bytes(), audio...), 0666); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("cannot tag audio %s: %w", audioPath, err)
+	}
+	if err := os.Rename(tmpPath, audioPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("cannot tag audio %s: %w", audioPath, err)
+	}
+	return nil
+}
+
+// Bytes encodes the tag as an ID3v2.3 tag, which is the version that the most players read.
+// Fields that are empty are left out
+func (tag *ID3Tag) Bytes() []byte {
+	var frames bytes.Buffer
+	writeFrame := func(id string, content []byte) {
+		frames.WriteString(id)
+		binary.Write(&frames, binary.BigEndian, uint32(len(content)))
+		frames.Write([]byte{0, 0}) // flags
+		frames.Write(content)
+	}
+	writeTextFrame := func(id string, text string) {
+		if text == "" {
+			return
+		}
+		encoding := getID3TextEncoding(text)
+		writeFrame(id, append([]byte{encoding}, encodeID3Text(encoding, text, false)...))
+	}
+
+	writeTextFrame("TIT2", tag.Title)
+	writeTextFrame("TPE1", tag.Artist)
+	writeTextFrame("TALB", tag.Album)
+	if tag.Track > 0 {
+		writeTextFrame("TRCK", strconv.Itoa(tag.Track))
+	}
+	if tag.Year > 0 {
+		writeTextFrame("TYER", fmt.Sprintf("%04d", tag.Year))
+	}
+	writeTextFrame("TPUB", tag.Publisher)
+	if tag.Comment != "" {
+		// encoding, language, empty description, text
+		encoding := getID3TextEncoding(tag.Comment)
+		content := append([]byte{encoding, 'e', 'n', 'g'}, encodeID3Text(encoding, "", true)...)
+		writeFrame("COMM", append(content, encodeID3Text(encoding, tag.Comment, false)...))
+	}
+	if len(tag.Cover) > 0 {
+		// encoding, MIME type, picture type, empty description, image
+		content := []byte{0}
+		content = append(content, tag.CoverType...)
+		content = append(content, 0, 3) // 3 is the front cover
+		content = append(content, 0)
+		writeFrame("APIC", append(content, tag.Cover...))
+	}
+
+	// the size of the tag is "syncsafe", with only 7 bits in each byte
+	size := frames.Len()
+	header := []byte{'I', 'D', '3', 3, 0, 0,
+		byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)}
+	return append(header, frames.Bytes()...)
+}
+
+// getID3TextEncoding gets the encoding of text in an ID3v2.3 frame. Text is ISO-8859-1 (0) if it
+// can be, otherwise UTF-16 (1) since ID3v2.3 doesn't have UTF-8
+func getID3TextEncoding(text string) byte {
+	for _, r := range text {
+		if r > 0xff {
+			return 1
+		}
+	}
+	return 0
+}
+
+// encodeID3Text encodes text for an ID3v2.3 frame with an encoding, which is 0 for ISO-8859-1 or
+// 1 for UTF-16 with a byte order mark. Terminated text ends with a null character
+func encodeID3Text(encoding byte, text string, terminate bool) []byte {
+	var encoded []byte
+	if encoding == 0 {
+		for _, r := range text {
+			encoded = append(encoded, byte(r))
+		}
+		if terminate {
+			encoded = append(encoded, 0)
+		}
+		return encoded
+	}
+
+	encoded = []byte{0xff, 0xfe}
+	for _, unit := range utf16.Encode([]rune(text)) {
+		encoded = binary.LittleEndian.AppendUint16(encoded, unit)
+	}
+	if terminate {
+		encoded = append(encoded, 0, 0)
+	}
+	return encoded
+}
@@ -0,0 +1,135 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// Runs the test suite as a test
+func TestID3TestSuite(t *testing.T) {
+	suite.Run(t, new(ID3TestSuite))
+}
+
+type ID3TestSuite struct {
+	suite.Suite
+}
+
+// readID3Frames reads the frames of an ID3v2.3 tag by their ID
+func (t *ID3TestSuite) readID3Frames(data []byte) map[string][]byte {
+	t.Require().Equal("ID3", string(data[0:3]))
+	t.Require().Equal(byte(3), data[3])
+	size := getID3TagSize(data)
+	t.Require().LessOrEqual(size, len(data))
+
+	frames := map[string][]byte{}
+	for offset := 10; offset+10 <= size; {
+		id := string(data[offset : offset+4])
+		frameSize := int(binary.BigEndian.Uint32(data[offset+4 : offset+8]))
+		frames[id] = data[offset+10 : offset+10+frameSize]
+		offset += 10 + frameSize
+	}
+	return frames
+}
+
+func (t *ID3TestSuite) TestBytes() {
+	tag := ID3Tag{
+		Title:     "Faith & Hope",
+		Artist:    "Vern Peltz",
+		Album:     "Faith",
+		Track:     2,
+		Year:      2025,
+		Publisher: "Word of Life",
+		Comment:   "A message about faith",
+		Cover:     []byte("PNG"),
+		CoverType: "image/png",
+	}
+
+	frames := t.readID3Frames(tag.Bytes())
+
+	t.Equal("\x00Faith & Hope", string(frames["TIT2"]))
+	t.Equal("\x00Vern Peltz", string(frames["TPE1"]))
+	t.Equal("\x00Faith", string(frames["TALB"]))
+	t.Equal("\x002", string(frames["TRCK"]))
+	t.Equal("\x002025", string(frames["TYER"]))
+	t.Equal("\x00Word of Life", string(frames["TPUB"]))
+	t.Equal("\x00eng\x00A message about faith", string(frames["COMM"]))
+	t.Equal("\x00image/png\x00\x03\x00PNG", string(frames["APIC"]))
+}
+
+func (t *ID3TestSuite) TestBytes_Empty() {
+	tag := ID3Tag{Title: "Faith"}
+
+	frames := t.readID3Frames(tag.Bytes())
+
+	t.Equal(map[string][]byte{"TIT2": []byte("\x00Faith")}, frames)
+}
+
+func (t *ID3TestSuite) TestBytes_Encoding() {
+	tag := ID3Tag{Title: "Café", Comment: "“Faith”"}
+
+	frames := t.readID3Frames(tag.Bytes())
+
+	// ISO-8859-1 when it can be
+	t.Equal([]byte{0, 'C', 'a', 'f', 0xe9}, frames["TIT2"])
+	// otherwise UTF-16
+	t.Equal([]byte{1, 'e', 'n', 'g', 0xff, 0xfe, 0, 0,
+		0xff, 0xfe, 0x1c, 0x20, 'F', 0, 'a', 0, 'i', 0, 't', 0, 'h', 0, 0x1d, 0x20}, frames["COMM"])
+}
+
+func (t *ID3TestSuite) TestWriteID3Tag() {
+	audioPath := filepath.Join(t.T().TempDir(), "2025-03-09 Faith.mp3")
+	audio := newMP3(1, 0)
+	t.Require().NoError(os.WriteFile(audioPath, audio, 0666))
+
+	// tag a file without a tag
+	t.Require().NoError(WriteID3Tag(audioPath, &ID3Tag{Title: "Faith", Cover: bytes.Repeat([]byte("X"), 200)}))
+	data, err := os.ReadFile(audioPath)
+	t.Require().NoError(err)
+	t.Equal("\x00Faith", string(t.readID3Frames(data)["TIT2"]))
+	t.Equal(audio, data[getID3TagSize(data):])
+
+	// tags are replaced, and the audio stays the same
+	t.Require().NoError(WriteID3Tag(audioPath, &ID3Tag{Title: "Hope"}))
+	data, err = os.ReadFile(audioPath)
+	t.Require().NoError(err)
+	t.Equal(map[string][]byte{"TIT2": []byte("\x00Hope")}, t.readID3Frames(data))
+	t.Equal(audio, data[getID3TagSize(data):])
+
+	seconds, err := getMP3Duration(data[getID3TagSize(data):], int64(len(audio)))
+	t.NoError(err)
+	t.Equal(1.0, seconds)
+
+	t.Error(WriteID3Tag(filepath.Join(t.T().TempDir(), "missing.mp3"), &ID3Tag{Title: "Faith"}))
+}
+
+func (t *ID3TestSuite) TestNewID3TagFromMessage() {
+	msg := CatalogMessage{
+		Name:        "Faith",
+		Date:        MustParseDateOnly("2025-03-09"),
+		Description: "A message about faith",
+		Speakers:    []string{"Vern Peltz", "Mary Peltz"},
+		Ministry:    WordOfLife,
+		Series:      []SeriesReference{{Name: "SAM"}, {Name: "Faith & Hope", Index: 2}},
+	}
+
+	t.Equal(ID3Tag{
+		Title:     "Faith",
+		Artist:    "Vern Peltz, Mary Peltz",
+		Album:     "Faith & Hope",
+		Track:     2,
+		Year:      2025,
+		Publisher: "Word of Life",
+		Comment:   "A message about faith",
+	}, NewID3TagFromMessage(&msg))
+
+	// stand-alone messages aren't in an album
+	msg.Series = []SeriesReference{{Name: "Faith", Index: 1}}
+	tag := NewID3TagFromMessage(&msg)
+	t.Equal("", tag.Album)
+	t.Equal(0, tag.Track)
+}
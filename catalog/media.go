@@ -0,0 +1,309 @@
+package catalog
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Facts about the media of a message, like whether it has a transcript and how long the audio
+// is, take network calls to find. They are found once with LoadMediaFacts and kept with the
+// message in the catalog, so the catalog and podcasts can be generated from the cached catalog
+// without the network
+
+// MediaFacts are the facts about the media of a message
+type MediaFacts struct {
+	Transcript   bool   `json:"transcript"`              // there is a transcript of the audio
+	AudioSize    int64  `json:"audio-size,omitempty"`    // size of the audio file in bytes, 0 if unknown
+	AudioType    string `json:"audio-type,omitempty"`    // content type of the audio file
+	AudioSeconds int    `json:"audio-seconds,omitempty"` // duration of the audio in seconds, 0 if unknown
+}
+
+// number of audio files whose facts are found at the same time
+const MEDIA_FACTS_WORKERS = 8
+
+// number of bytes at the start of an audio file that are read to find its duration, which is
+// enough for the first frame
+const MEDIA_FACTS_HEADER_SIZE = 64 * 1024
+
+// LoadMediaFacts finds the facts about the media of all the messages with audio and keeps them
+// with the messages. It is an error if the transcripts can't be listed, but a message whose audio
+// can't be read still gets the facts that could be found, with a warning
+func (c *Catalog) LoadMediaFacts(ctx context.Context) error {
+	// find which messages have transcripts, by year
+	transcripts := map[int]map[string]bool{}
+	for index := range c.Messages {
+		msg := &c.Messages[index]
+		if !msg.HasAudio() {
+			msg.Media = nil
+			continue
+		}
+		year := msg.Date.Year()
+		if _, ok := transcripts[year]; ok {
+			continue
+		}
+
+		log.Printf("(Researching transcripts for %d)", year)
+		names, err := listTranscriptNames(ctx, year)
+		if err != nil {
+			return fmt.Errorf("cannot list the transcripts for %d: %w", year, err)
+		}
+		transcripts[year] = map[string]bool{}
+		for _, name := range names {
+			transcripts[year][name] = true
+		}
+	}
+
+	// find the facts of each audio file once, even if several messages have it
+	audioFacts := map[string]*MediaFacts{}
+	var audioURLs []string
+	for _, msg := range c.Messages {
+		if msg.HasAudio() && audioFacts[msg.Audio.URL] == nil {
+			audioFacts[msg.Audio.URL] = &MediaFacts{}
+			audioURLs = append(audioURLs, msg.Audio.URL)
+		}
+	}
+	log.Printf("Finding the facts of %d audio files", len(audioURLs))
+	urls := make(chan string)
+	var wg sync.WaitGroup
+	for range MEDIA_FACTS_WORKERS {
+		wg.Go(func() {
+			for audioURL := range urls {
+				facts := audioFacts[audioURL]
+				if err := getAudioFacts(ctx, http.DefaultClient, audioURL, facts); err != nil {
+					log.Printf("WARNING: %s", err.Error())
+				}
+			}
+		})
+	}
+	for _, audioURL := range audioURLs {
+		urls <- audioURL
+	}
+	close(urls)
+	wg.Wait()
+
+	// keep the facts with the messages
+	for index := range c.Messages {
+		msg := &c.Messages[index]
+		if !msg.HasAudio() {
+			continue
+		}
+		facts := *audioFacts[msg.Audio.URL]
+		facts.Transcript = transcripts[msg.Date.Year()][msg.getTranscriptName()]
+		msg.Media = &facts
+	}
+
+	return nil
+}
+
+// getAudioFacts finds the size, content type, and duration of an audio file from its URL
+func getAudioFacts(ctx context.Context, client *http.Client, audioURL string, facts *MediaFacts) error {
+	// get the size and type
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, audioURL, nil)
+	if err != nil {
+		return fmt.Errorf("cannot get audio %s: %w", audioURL, err)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("cannot get audio %s: %w", audioURL, err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unsuccessful status code getting audio %s: %d", audioURL, response.StatusCode)
+	}
+	facts.AudioSize = response.ContentLength
+	facts.AudioType = response.Header.Get("Content-Type")
+	if facts.AudioSize <= 0 {
+		return fmt.Errorf("audio %s has no size", audioURL)
+	}
+
+	// read the start of the file to find the duration. the audio starts after the ID3 tag, which
+	// can be big if it has pictures
+	header, err := readAudioRange(ctx, client, audioURL, 0)
+	if err != nil {
+		return err
+	}
+	tagSize := getID3TagSize(header)
+	if tagSize+MEDIA_FACTS_HEADER_SIZE/2 > len(header) {
+		if header, err = readAudioRange(ctx, client, audioURL, tagSize); err != nil {
+			return err
+		}
+	} else {
+		header = header[tagSize:]
+	}
+
+	seconds, err := getMP3Duration(header, facts.AudioSize-int64(tagSize))
+	if err != nil {
+		return fmt.Errorf("cannot find the duration of audio %s: %w", audioURL, err)
+	}
+	facts.AudioSeconds = int(math.Round(seconds))
+	return nil
+}
+
+// readAudioRange reads MEDIA_FACTS_HEADER_SIZE bytes of an audio file from the offset
+func readAudioRange(ctx context.Context, client *http.Client, audioURL string, offset int) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, audioURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get audio %s: %w", audioURL, err)
+	}
+	request.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+MEDIA_FACTS_HEADER_SIZE-1))
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get audio %s: %w", audioURL, err)
+	}
+	defer response.Body.Close()
+
+	body := io.Reader(response.Body)
+	switch response.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// servers that don't do ranges send everything
+		if _, err := io.CopyN(io.Discard, body, int64(offset)); err != nil {
+			return nil, fmt.Errorf("cannot read audio %s: %w", audioURL, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsuccessful status code reading audio %s: %d", audioURL, response.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, MEDIA_FACTS_HEADER_SIZE))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read audio %s: %w", audioURL, err)
+	}
+	return data, nil
+}
+
+// getTranscriptName gets the name that the transcripts of the message have, which is the name
+// of the audio file without the extension
+func (m *CatalogMessage) getTranscriptName() string {
+	audioURL, err := url.Parse(m.Audio.URL)
+	if err != nil {
+		log.Printf("WARNING: Could not parse audio URL %q: %s", m.Audio.URL, err.Error())
+		return ""
+	}
+	audioName := filepath.Base(audioURL.Path)
+	audioName = strings.TrimSuffix(audioName, filepath.Ext(audioName))
+	return strings.ReplaceAll(audioName, "+", " ")
+}
+
+// +---------------------------------------------------------------------------
+// | MP3
+// +---------------------------------------------------------------------------
+
+// bit rates of MPEG layer III frames in kbps by bit rate index, for MPEG 1 and MPEG 2/2.5
+var mp3BitRates = [2][16]int{
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+}
+
+// sample rates of MPEG 1 frames by sample rate index. MPEG 2 is half, and MPEG 2.5 a quarter
+var mp3SampleRates = [4]int{44100, 48000, 32000, 0}
+
+// mp3Frame is the header of an MPEG layer III frame
+type mp3Frame struct {
+	mpeg1      bool // MPEG 1, otherwise MPEG 2 or 2.5
+	mono       bool // one channel
+	bitRate    int  // bits per second
+	sampleRate int  // samples per second
+}
+
+// getMP3Duration finds the duration in seconds of MP3 audio from the start of the audio (after
+// any ID3 tag) and the size of the audio. The duration of a variable bit rate file comes from its
+// Xing or VBRI header, otherwise it's computed from the bit rate of the first frame
+func getMP3Duration(header []byte, size int64) (float64, error) {
+	// find the first frame
+	var frame mp3Frame
+	ok := false
+	start := 0
+	for ; start+4 <= len(header); start++ {
+		if frame, ok = parseMP3FrameHeader(header[start:]); ok {
+			break
+		}
+	}
+	if !ok {
+		return 0, fmt.Errorf("no MP3 frame found in the first %d bytes", len(header))
+	}
+
+	samplesPerFrame := 1152
+	if !frame.mpeg1 {
+		samplesPerFrame = 576
+	}
+
+	// the first frame of a variable bit rate file has the number of frames
+	sideInfoSize := 32
+	switch {
+	case frame.mpeg1 && frame.mono, !frame.mpeg1 && !frame.mono:
+		sideInfoSize = 17
+	case !frame.mpeg1 && frame.mono:
+		sideInfoSize = 9
+	}
+	frames := 0
+	if xing := header[start:]; len(xing) >= 4+sideInfoSize+12 {
+		xing = xing[4+sideInfoSize:]
+		id := string(xing[0:4])
+		if (id == "Xing" || id == "Info") && binary.BigEndian.Uint32(xing[4:8])&1 != 0 {
+			frames = int(binary.BigEndian.Uint32(xing[8:12]))
+		}
+	}
+	if vbri := header[start:]; frames == 0 && len(vbri) >= 4+32+18 && string(vbri[36:40]) == "VBRI" {
+		frames = int(binary.BigEndian.Uint32(vbri[36+14 : 36+18]))
+	}
+	if frames > 0 {
+		return float64(frames*samplesPerFrame) / float64(frame.sampleRate), nil
+	}
+
+	// constant bit rate
+	return float64(size-int64(start)) * 8 / float64(frame.bitRate), nil
+}
+
+// getID3TagSize gets the size of the ID3v2 tag at the start of a file, or 0 if there isn't one
+func getID3TagSize(header []byte) int {
+	if len(header) < 10 || string(header[0:3]) != "ID3" {
+		return 0
+	}
+	// the size doesn't include the 10 byte header, or the footer if there is one
+	size := int(header[6]&0x7f)<<21 | int(header[7]&0x7f)<<14 | int(header[8]&0x7f)<<7 | int(header[9]&0x7f)
+	size += 10
+	if header[5]&0x10 != 0 {
+		size += 10
+	}
+	return size
+}
+
+// parseMP3FrameHeader parses the header of an MPEG layer III frame. Returns false if the bytes
+// aren't the header of a layer III frame
+func parseMP3FrameHeader(b []byte) (mp3Frame, bool) {
+	var frame mp3Frame
+	if len(b) < 4 || b[0] != 0xff || b[1]&0xe0 != 0xe0 {
+		return frame, false
+	}
+
+	version := (b[1] >> 3) & 3 // 3 = MPEG 1, 2 = MPEG 2, 0 = MPEG 2.5
+	layer := (b[1] >> 1) & 3   // 1 = layer III
+	bitRateIndex := b[2] >> 4
+	sampleRateIndex := (b[2] >> 2) & 3
+	if version == 1 || layer != 1 || bitRateIndex == 0 || bitRateIndex == 15 || sampleRateIndex == 3 {
+		return frame, false
+	}
+
+	frame.mpeg1 = version == 3
+	frame.mono = b[3]>>6 == 3
+	frame.sampleRate = mp3SampleRates[sampleRateIndex]
+	if frame.mpeg1 {
+		frame.bitRate = mp3BitRates[0][bitRateIndex] * 1000
+	} else {
+		frame.bitRate = mp3BitRates[1][bitRateIndex] * 1000
+		frame.sampleRate /= 2
+		if version == 0 {
+			frame.sampleRate /= 2
+		}
+	}
+	return frame, true
+}
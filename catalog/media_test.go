@@ -0,0 +1,173 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/WordOfLifeMN/online/storage"
+	"github.com/stretchr/testify/suite"
+)
+
+// Runs the test suite as a test
+func TestMediaTestSuite(t *testing.T) {
+	suite.Run(t, new(MediaTestSuite))
+}
+
+type MediaTestSuite struct {
+	suite.Suite
+}
+
+// newMP3 creates a constant bit rate MP3 (128kbps, 44.1kHz) with the number of seconds of audio,
+// after an ID3 tag of the size
+func newMP3(seconds int, tagSize int) []byte {
+	var data []byte
+	if tagSize > 0 {
+		size := tagSize - 10
+		data = append(data, 'I', 'D', '3', 3, 0, 0,
+			byte(size>>21&0x7f), byte(size>>14&0x7f), byte(size>>7&0x7f), byte(size&0x7f))
+		data = append(data, make([]byte, size)...)
+	}
+	audio := make([]byte, seconds*128000/8)
+	copy(audio, []byte{0xff, 0xfb, 0x90, 0x00})
+	return append(data, audio...)
+}
+
+// newAudioServer serves the audio files by name
+func (t *MediaTestSuite) newAudioServer(files map[string][]byte) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[filepath.Base(r.URL.Path)]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, r.URL.Path, time.Time{}, bytes.NewReader(content))
+	}))
+	t.T().Cleanup(server.Close)
+	return server
+}
+
+func (t *MediaTestSuite) TestGetMP3Duration_ConstantBitRate() {
+	header := newMP3(60, 0)[:MEDIA_FACTS_HEADER_SIZE]
+	seconds, err := getMP3Duration(header, 960000)
+	t.NoError(err)
+	t.Equal(60.0, seconds)
+
+	// junk before the first frame isn't audio
+	header = append([]byte{0x00, 0xff, 0x00}, header...)
+	seconds, err = getMP3Duration(header, 960003)
+	t.NoError(err)
+	t.Equal(60.0, seconds)
+}
+
+func (t *MediaTestSuite) TestGetMP3Duration_Xing() {
+	header := make([]byte, 4+32+12)
+	copy(header, []byte{0xff, 0xfb, 0x90, 0x00})
+	copy(header[4+32:], []byte{'X', 'i', 'n', 'g', 0, 0, 0, 1, 0, 0, 0x03, 0xe8})
+
+	seconds, err := getMP3Duration(header, 500000)
+	t.NoError(err)
+	t.InDelta(26.12, seconds, 0.01)
+}
+
+func (t *MediaTestSuite) TestGetMP3Duration_NoFrame() {
+	_, err := getMP3Duration([]byte("not an mp3 file"), 15)
+	t.Error(err)
+
+	// layer I isn't MP3
+	_, err = getMP3Duration([]byte{0xff, 0xff, 0x90, 0x00}, 4)
+	t.Error(err)
+}
+
+func (t *MediaTestSuite) TestGetID3TagSize() {
+	t.Equal(0, getID3TagSize(nil))
+	t.Equal(0, getID3TagSize(newMP3(1, 0)))
+	t.Equal(2000, getID3TagSize(newMP3(1, 2000)))
+	t.Equal(200000, getID3TagSize(newMP3(1, 200000)))
+
+	// with a footer
+	t.Equal(30, getID3TagSize([]byte{'I', 'D', '3', 4, 0, 0x10, 0, 0, 0, 10}))
+}
+
+func (t *MediaTestSuite) TestGetAudioFacts() {
+	server := t.newAudioServer(map[string][]byte{
+		"small.mp3": newMP3(60, 2000),
+		"big.mp3":   newMP3(90, 200000),
+	})
+	ctx := context.Background()
+
+	var facts MediaFacts
+	t.NoError(getAudioFacts(ctx, server.Client(), server.URL+"/small.mp3", &facts))
+	t.Equal(MediaFacts{AudioSize: 962000, AudioType: "audio/mpeg", AudioSeconds: 60}, facts)
+
+	// the audio starts past the first read when the tag is big
+	facts = MediaFacts{}
+	t.NoError(getAudioFacts(ctx, server.Client(), server.URL+"/big.mp3", &facts))
+	t.Equal(MediaFacts{AudioSize: 1640000, AudioType: "audio/mpeg", AudioSeconds: 90}, facts)
+
+	facts = MediaFacts{}
+	t.Error(getAudioFacts(ctx, server.Client(), server.URL+"/missing.mp3", &facts))
+	t.Zero(facts.AudioSize)
+}
+
+func (t *MediaTestSuite) TestLoadMediaFacts() {
+	// given transcripts for one of the messages
+	dir := t.T().TempDir()
+	defaultStorage := openAudioStorage
+	SetAudioStorage(func() (storage.Storage, error) { return storage.Open(dir, storage.Options{}) })
+	defer SetAudioStorage(defaultStorage)
+	t.Require().NoError(os.MkdirAll(filepath.Join(dir, "2024", "xscript"), 0777))
+	t.Require().NoError(os.WriteFile(filepath.Join(dir, "2024", "xscript", "2024-01-07 Faith.text"), []byte("Faith"), 0666))
+
+	files := map[string][]byte{
+		"2024-01-07+Faith.mp3": newMP3(60, 0),
+		"2024-01-14+Hope.mp3":  newMP3(30, 0),
+	}
+	server := t.newAudioServer(files)
+	c := Catalog{
+		Messages: []CatalogMessage{
+			{Name: "FAITH", Date: MustParseDateOnly("2024-01-07"), Audio: NewResourceFromString(server.URL + "/2024-01-07+Faith.mp3")},
+			{Name: "HOPE", Date: MustParseDateOnly("2024-01-14"), Audio: NewResourceFromString(server.URL + "/2024-01-14+Hope.mp3")},
+			{Name: "LOVE", Date: MustParseDateOnly("2024-01-21"), Audio: NewResourceFromString(server.URL + "/2024-01-21+Love.mp3")},
+			{Name: "VIDEO", Date: MustParseDateOnly("2024-01-28"), Media: &MediaFacts{Transcript: true}},
+		},
+	}
+
+	// when
+	t.Require().NoError(c.LoadMediaFacts(context.Background()))
+
+	// then
+	t.Equal(&MediaFacts{Transcript: true, AudioSize: 960000, AudioType: "audio/mpeg", AudioSeconds: 60}, c.Messages[0].Media)
+	t.Equal(&MediaFacts{AudioSize: 480000, AudioType: "audio/mpeg", AudioSeconds: 30}, c.Messages[1].Media)
+	t.Equal(&MediaFacts{}, c.Messages[2].Media)
+	t.Nil(c.Messages[3].Media)
+
+	// and the messages use the facts without looking
+	SetAudioStorage(func() (storage.Storage, error) { return nil, os.ErrNotExist })
+	t.True(c.Messages[0].HasTranscript())
+	t.False(c.Messages[1].HasTranscript())
+	t.Equal(960000, c.Messages[0].GetAudioSize())
+
+	// and audio that couldn't be read stays unknown, even if it could be read now
+	files["2024-01-21+Love.mp3"] = newMP3(10, 0)
+	t.Equal(-1, c.Messages[2].GetAudioSize())
+	t.False(c.Messages[2].HasTranscript())
+}
+
+func (t *MediaTestSuite) TestLoadMediaFacts_NoStorage() {
+	defaultStorage := openAudioStorage
+	SetAudioStorage(func() (storage.Storage, error) { return nil, os.ErrNotExist })
+	defer SetAudioStorage(defaultStorage)
+
+	c := Catalog{
+		Messages: []CatalogMessage{
+			{Name: "FAITH", Date: MustParseDateOnly("2024-01-07"), Audio: NewResourceFromString("https://example.com/2024-01-07+Faith.mp3")},
+		},
+	}
+	t.Error(c.LoadMediaFacts(context.Background()))
+}
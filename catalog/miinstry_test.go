@@ -48,3 +48,137 @@ func TestMinistryString(t *testing.T) {
 	assert.Equal(t, "Faith & Freedom", FaithAndFreedom.Description())
 	assert.Equal(t, "(Unknown Ministry)", UnknownMinistry.Description())
 }
+
+func TestAllMinistries(t *testing.T) {
+	all := AllMinistries()
+	assert.Contains(t, all, WordOfLife)
+	assert.Contains(t, all, CORE_CounselingClasses)
+	assert.NotContains(t, all, UnknownMinistry)
+}
+
+func TestMinistryRegistryValues(t *testing.T) {
+	// ID prefixes
+	assert.Equal(t, "WOLS-", WordOfLife.IDPrefix())
+	assert.Equal(t, "ID-", CORE_HopeDealers.IDPrefix())
+	assert.Equal(t, "ID-", UnknownMinistry.IDPrefix())
+
+	// parents
+	assert.Equal(t, CenterOfRelationshipExperience, CORE_HopeDealers.Parent())
+	assert.Equal(t, UnknownMinistry, CenterOfRelationshipExperience.Parent())
+
+	// themes are inherited from the parent
+	assert.Equal(t, "violet", CenterOfRelationshipExperience.Theme())
+	assert.Equal(t, "violet", CORE_HealthMatters.Theme())
+	assert.Equal(t, "jade", UnknownMinistry.Theme())
+
+	// audio profiles are inherited from the parent
+	assert.Equal(t, 30.0, CORE_RecoveryClasses.AudioProfile().Trim)
+	assert.Equal(t, 9.9, FaithAndFreedom.AudioProfile().Trim)
+	assert.Equal(t, 9.8, WordOfLife.AudioProfile().Trim)
+	assert.Equal(t, 9.8, UnknownMinistry.AudioProfile().Trim)
+	assert.Equal(t, &LoudnessTarget{Integrated: -16, TruePeak: -1.5, Range: 11}, CORE_HopeDealers.AudioProfile().Loudness)
+
+	// images
+	assert.Equal(t, "static/tbo.default.thumbnail.png", TheBridgeOutreach.Thumbnail())
+	assert.Equal(t, "static/unknown.default.thumbnail.png", UnknownMinistry.Thumbnail())
+	assert.Equal(t, "static/core.logo.small.png", CORE_HopeDealers.Logo())
+	assert.Equal(t, "", UnknownMinistry.Logo())
+	assert.Equal(t, []string{"wol."}, WordOfLife.StaticFilePrefixes())
+	assert.Contains(t, AskThePastor.StaticFilePrefixes(), "wol.thumbnail.")
+
+	// blurbs
+	assert.Contains(t, string(AskThePastor.BlurbHTML()), "<em>Always be prepared")
+	assert.Contains(t, string(AskThePastor.BlurbHTML()), `<a href="mailto:info@wordoflifemn.org">`)
+	assert.Equal(t, "", string(TheBridgeOutreach.BlurbHTML()))
+}
+
+func TestMinistryRegistryFromYAML(t *testing.T) {
+	r, err := NewMinistryRegistryFromYAML([]byte(`
+theme: blue
+ministries:
+  - key: Main
+    name: Main Ministry
+    aliases: [mm]
+  - key: sub
+    name: Sub Ministry
+    parent: MM
+    audio:
+      trim: 5
+      loudness: {integrated: -19, true-peak: -2, range: 8}
+`))
+	if assert.NoError(t, err) && assert.Len(t, r.Ministries, 2) {
+		assert.Equal(t, Ministry("main"), r.Ministries[0].Key)
+		assert.Equal(t, Ministry("main"), r.Ministries[1].Parent)
+		assert.Equal(t, "blue", r.Theme)
+		assert.Nil(t, r.Audio.Loudness)
+		assert.Equal(t, &AudioProfile{Trim: 5, Loudness: &LoudnessTarget{Integrated: -19, TruePeak: -2, Range: 8}}, r.Ministries[1].Audio)
+	}
+}
+
+func TestMinistryRegistryFromYAML_Errors(t *testing.T) {
+	// duplicate alias
+	_, err := NewMinistryRegistryFromYAML([]byte(`
+ministries:
+  - key: one
+    aliases: [x]
+  - key: two
+    aliases: [X]
+`))
+	assert.Error(t, err)
+
+	// unknown parent
+	_, err = NewMinistryRegistryFromYAML([]byte(`
+ministries:
+  - key: one
+    parent: two
+`))
+	assert.Error(t, err)
+
+	// unknown also-in
+	_, err = NewMinistryRegistryFromYAML([]byte(`
+ministries:
+  - key: one
+    also-in: [two]
+`))
+	assert.Error(t, err)
+
+	// parent cycle
+	_, err = NewMinistryRegistryFromYAML([]byte(`
+ministries:
+  - key: one
+    parent: two
+  - key: two
+    parent: one
+`))
+	assert.Error(t, err)
+
+	// missing key
+	_, err = NewMinistryRegistryFromYAML([]byte(`
+ministries:
+  - name: Nameless
+`))
+	assert.Error(t, err)
+}
+
+func TestNewMinistriesFromString(t *testing.T) {
+	assert.Nil(t, NewMinistriesFromString(""))
+	assert.Equal(t, []Ministry{WordOfLife}, NewMinistriesFromString("WOL"))
+	assert.Equal(t, []Ministry{WordOfLife, AskThePastor}, NewMinistriesFromString("wol; atp;"))
+	assert.Equal(t, []Ministry{UnknownMinistry}, NewMinistriesFromString("Open Heavens"))
+}
+
+func TestMinistryIncludes(t *testing.T) {
+	// same ministry
+	assert.True(t, WordOfLife.Includes(WordOfLife))
+	assert.False(t, WordOfLife.Includes(TheBridgeOutreach))
+
+	// cross-listed ministries
+	assert.True(t, WordOfLife.Includes(AskThePastor))
+	assert.False(t, AskThePastor.Includes(WordOfLife))
+
+	// parent includes children, but not the other way around
+	assert.True(t, CenterOfRelationshipExperience.Includes(CORE_HealthMatters))
+	assert.True(t, CenterOfRelationshipExperience.Includes(CORE_CounselingClasses))
+	assert.False(t, CORE_HealthMatters.Includes(CenterOfRelationshipExperience))
+	assert.False(t, CORE_HealthMatters.Includes(CORE_HopeDealers))
+}
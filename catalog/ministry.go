@@ -1,12 +1,23 @@
 package catalog
 
 import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"os"
+	"slices"
 	"strings"
+
+	"github.com/yuin/goldmark"
+	"gopkg.in/yaml.v3"
 )
 
 // ministries
 type Ministry string
 
+// well-known ministries. the full list of ministries, and everything about them, is in the
+// ministry registry
 const (
 	UnknownMinistry                Ministry = "unknown"
 	WordOfLife                     Ministry = "wol"
@@ -20,61 +31,306 @@ const (
 	FaithAndFreedom                Ministry = "faith-freedom"
 )
 
-var AllMinistries = []Ministry{WordOfLife, TheBridgeOutreach,
-	CenterOfRelationshipExperience, CORE_HealthMatters, CORE_HopeDealers, CORE_RecoveryClasses, CORE_CounselingClasses,
-	AskThePastor,
-	FaithAndFreedom}
+// MinistryInfo describes one ministry in the ministry registry
+type MinistryInfo struct {
+	Key        Ministry      `yaml:"key"`                   // identifier used in the catalog and file names
+	Aliases    []string      `yaml:"aliases,omitempty"`     // other names for the ministry
+	Name       string        `yaml:"name"`                  // display name
+	IDPrefix   string        `yaml:"id-prefix,omitempty"`   // prefix of generated series IDs
+	Theme      string        `yaml:"theme,omitempty"`       // pico color theme of the catalog pages
+	Logo       string        `yaml:"logo,omitempty"`        // path of the logo in the catalog
+	Thumbnail  string        `yaml:"thumbnail,omitempty"`   // path of the default series thumbnail in the catalog
+	Static     []string      `yaml:"static,omitempty"`      // prefixes of the static files the pages need
+	BlurbImage string        `yaml:"blurb-image,omitempty"` // path of the image displayed with the blurb
+	Blurb      string        `yaml:"blurb,omitempty"`       // introduction to the ministry (Markdown)
+	Parent     Ministry      `yaml:"parent,omitempty"`      // ministry this one is part of
+	Audio      *AudioProfile `yaml:"audio,omitempty"`       // how audio is extracted from recordings
+
+	AlsoIn          []Ministry `yaml:"also-in,omitempty"`          // other ministries whose catalogs list this ministry's series
+	IncludeChildren bool       `yaml:"include-children,omitempty"` // catalog lists the series of all descendant ministries
+}
+
+// AudioProfile describes how the audio for a ministry is extracted from recordings
+type AudioProfile struct {
+	Trim     float64         `yaml:"trim"`               // usual seconds of intro to trim from the start of a recording
+	Bumper   string          `yaml:"bumper,omitempty"`   // path in the template directory of the audio of the intro, "" if there isn't one
+	Loudness *LoudnessTarget `yaml:"loudness,omitempty"` // loudness the audio is normalized to, nil to not normalize it
+}
+
+// LoudnessTarget is the loudness that audio is normalized to with the EBU R128 loudness filter
+type LoudnessTarget struct {
+	Integrated float64 `yaml:"integrated"` // integrated loudness in LUFS
+	TruePeak   float64 `yaml:"true-peak"`  // maximum true peak in dBTP
+	Range      float64 `yaml:"range"`      // loudness range in LU
+}
+
+// MinistryRegistry is the list of all the ministries, indexed by all the names they go by
+type MinistryRegistry struct {
+	Theme      string         `yaml:"theme"`      // theme used when a ministry doesn't have one
+	Audio      AudioProfile   `yaml:"audio"`      // audio profile used when a ministry doesn't have one
+	Ministries []MinistryInfo `yaml:"ministries"` // all the ministries, in display order
+
+	byName map[string]*MinistryInfo // ministries by lower-case key or alias
+}
+
+// default list of ministries, used if no other registry is configured
+//
+//go:embed ministries.yaml
+var defaultMinistriesYAML []byte
+
+// the registry used to look up ministries
+var ministryRegistry = mustParseMinistryRegistry(defaultMinistriesYAML)
+
+// +---------------------------------------------------------------------------
+// | Registry
+// +---------------------------------------------------------------------------
+
+// NewMinistryRegistryFromYAML parses a registry from YAML. Returns an error if two ministries
+// share a key or alias, if a parent or also-in ministry doesn't exist, or if a parent is part of
+// a cycle
+func NewMinistryRegistryFromYAML(data []byte) (*MinistryRegistry, error) {
+	r := &MinistryRegistry{}
+	if err := yaml.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("cannot parse ministry registry: %w", err)
+	}
+
+	r.byName = map[string]*MinistryInfo{}
+	for index := range r.Ministries {
+		info := &r.Ministries[index]
+		info.Key = Ministry(strings.ToLower(strings.TrimSpace(string(info.Key))))
+		if info.Key == "" {
+			return nil, fmt.Errorf("ministry %d has no key", index+1)
+		}
+		if info.Key == UnknownMinistry {
+			return nil, fmt.Errorf("ministry key '%s' is reserved", string(info.Key))
+		}
+
+		for _, name := range append([]string{string(info.Key)}, info.Aliases...) {
+			key := strings.ToLower(strings.TrimSpace(name))
+			if existing, ok := r.byName[key]; ok {
+				return nil, fmt.Errorf("ministry name '%s' is used by both %s and %s", name, existing.Key, info.Key)
+			}
+			r.byName[key] = info
+		}
+	}
+
+	for index := range r.Ministries {
+		info := &r.Ministries[index]
+		if info.Parent != "" {
+			parent, ok := r.byName[strings.ToLower(strings.TrimSpace(string(info.Parent)))]
+			if !ok {
+				return nil, fmt.Errorf("ministry %s has unknown parent '%s'", info.Key, info.Parent)
+			}
+			info.Parent = parent.Key
+		}
+
+		for i, name := range info.AlsoIn {
+			other, ok := r.byName[strings.ToLower(strings.TrimSpace(string(name)))]
+			if !ok {
+				return nil, fmt.Errorf("ministry %s is also in unknown ministry '%s'", info.Key, name)
+			}
+			info.AlsoIn[i] = other.Key
+		}
+	}
+
+	// make sure the chain of parents ends
+	for index := range r.Ministries {
+		info := &r.Ministries[index]
+		parent := info.Parent
+		for depth := 0; parent != ""; depth++ {
+			if depth >= len(r.Ministries) {
+				return nil, fmt.Errorf("ministry %s is its own ancestor", info.Key)
+			}
+			parent = r.byName[string(parent)].Parent
+		}
+	}
+
+	return r, nil
+}
+
+// NewMinistryRegistryFromFile reads a registry from a YAML file
+func NewMinistryRegistryFromFile(filePath string) (*MinistryRegistry, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read ministry registry %s: %w", filePath, err)
+	}
+	return NewMinistryRegistryFromYAML(data)
+}
+
+// mustParseMinistryRegistry parses a registry from YAML and panics if it's not valid. This is
+// only for the built-in registry
+func mustParseMinistryRegistry(data []byte) *MinistryRegistry {
+	r, err := NewMinistryRegistryFromYAML(data)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// GetMinistryRegistry gets the registry currently used to look up ministries
+func GetMinistryRegistry() *MinistryRegistry {
+	return ministryRegistry
+}
+
+// SetMinistryRegistry replaces the registry used to look up ministries. This should be done
+// before any catalog is read
+func SetMinistryRegistry(r *MinistryRegistry) {
+	ministryRegistry = r
+}
 
+// AllMinistries gets all the ministries in the registry, in display order
+func AllMinistries() []Ministry {
+	ministries := make([]Ministry, 0, len(ministryRegistry.Ministries))
+	for _, info := range ministryRegistry.Ministries {
+		ministries = append(ministries, info.Key)
+	}
+	return ministries
+}
+
+// +---------------------------------------------------------------------------
+// | Ministry
+// +---------------------------------------------------------------------------
+
+// NewMinistryFromString finds the ministry with the key or alias. Returns UnknownMinistry if no
+// ministry goes by that name
 func NewMinistryFromString(s string) Ministry {
-	s = strings.TrimSpace(s)
-	s = strings.ToLower(s)
-
-	switch s {
-	case "wol":
-		return WordOfLife
-	case "core":
-		return CenterOfRelationshipExperience
-	case "core:health", "core:healthmatters", "core: health", "core: health matters":
-		return CORE_HealthMatters
-	case "core:hope", "core:hopedealers", "core: hope", "core: hope dealers":
-		return CORE_HopeDealers
-	case "core:recovery", "core:recoveryclasses", "core: recovery", "core: recovery classes":
-		return CORE_RecoveryClasses
-	case "core:counseling", "core:counselingclasses", "core: counseling", "core: counseling classes":
-		return CORE_CounselingClasses
-	case "tbo":
-		return TheBridgeOutreach
-	case "ask pastor", "ask the pastor", "askthepastor", string(AskThePastor):
-		return AskThePastor
-	case "faith-freedom", "faithandfreedom":
-		return FaithAndFreedom
+	if info, ok := ministryRegistry.byName[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return info.Key
 	}
 
 	// log.Printf("WARNING: Encountered unknown ministry '%s'", s)
 	return UnknownMinistry
 }
 
+// NewMinistriesFromString finds the ministries in a list of keys or aliases separated by
+// semicolons. Any name that isn't known becomes UnknownMinistry. Returns nil if the list is empty
+func NewMinistriesFromString(s string) []Ministry {
+	var ministries []Ministry
+	for _, name := range strings.Split(s, ";") {
+		if strings.TrimSpace(name) != "" {
+			ministries = append(ministries, NewMinistryFromString(name))
+		}
+	}
+	return ministries
+}
+
+// Info gets the registry entry of the ministry. Returns nil if the ministry isn't in the registry
+func (ministry Ministry) Info() *MinistryInfo {
+	return ministryRegistry.byName[string(ministry)]
+}
+
 func (ministry Ministry) Description() string {
-	switch ministry {
-	case WordOfLife:
-		return "Word of Life"
-	case CenterOfRelationshipExperience:
-		return "C.O.R.E."
-	case CORE_HealthMatters:
-		return "CORE: Health Matters"
-	case CORE_HopeDealers:
-		return "CORE: Hope Dealers"
-	case CORE_RecoveryClasses:
-		return "CORE: Recovery Classes"
-	case CORE_CounselingClasses:
-		return "CORE: Counseling Classes"
-	case TheBridgeOutreach:
-		return "The Bridge Outreach"
-	case AskThePastor:
-		return "Ask the Pastor"
-	case FaithAndFreedom:
-		return "Faith & Freedom"
-	default:
-		return "(Unknown Ministry)"
+	if info := ministry.Info(); info != nil {
+		return info.Name
+	}
+	return "(Unknown Ministry)"
+}
+
+// IDPrefix gets the prefix of series IDs generated for the ministry
+func (ministry Ministry) IDPrefix() string {
+	if info := ministry.Info(); info != nil && info.IDPrefix != "" {
+		return info.IDPrefix
+	}
+	return "ID-"
+}
+
+// Parent gets the ministry this ministry is part of. Returns UnknownMinistry if it's not part of
+// another ministry
+func (ministry Ministry) Parent() Ministry {
+	if info := ministry.Info(); info != nil && info.Parent != "" {
+		return info.Parent
+	}
+	return UnknownMinistry
+}
+
+// Includes determines if the catalog of this ministry lists the series of the other ministry.
+// That is the case if they are the same ministry, if the other ministry is cross-listed in this
+// one, or if this ministry includes its children and is an ancestor of the other ministry
+func (ministry Ministry) Includes(other Ministry) bool {
+	if other == ministry {
+		return true
+	}
+	if info := other.Info(); info != nil && slices.Contains(info.AlsoIn, ministry) {
+		return true
+	}
+	if info := ministry.Info(); info != nil && info.IncludeChildren {
+		for m := other.Parent(); m != UnknownMinistry; m = m.Parent() {
+			if m == ministry {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Theme gets the pico color theme for the ministry pages, which is inherited from the parent
+// ministry if the ministry doesn't have one
+func (ministry Ministry) Theme() string {
+	for m := ministry; m != UnknownMinistry; m = m.Parent() {
+		if info := m.Info(); info != nil && info.Theme != "" {
+			return info.Theme
+		}
+	}
+	return ministryRegistry.Theme
+}
+
+// Logo gets the path of the ministry logo shown in the header of the catalog pages, which is
+// inherited from the parent ministry if the ministry doesn't have one. "" if there is no logo
+func (ministry Ministry) Logo() string {
+	for m := ministry; m != UnknownMinistry; m = m.Parent() {
+		if info := m.Info(); info != nil && info.Logo != "" {
+			return info.Logo
+		}
+	}
+	return ""
+}
+
+// Thumbnail gets the path of the default series thumbnail in the catalog
+func (ministry Ministry) Thumbnail() string {
+	if info := ministry.Info(); info != nil && info.Thumbnail != "" {
+		return info.Thumbnail
+	}
+	return "static/" + string(ministry) + ".default.thumbnail.png"
+}
+
+// StaticFilePrefixes gets the prefixes of the static files that the ministry pages need
+func (ministry Ministry) StaticFilePrefixes() []string {
+	if info := ministry.Info(); info != nil && len(info.Static) > 0 {
+		return info.Static
+	}
+	return []string{string(ministry) + "."}
+}
+
+// BlurbImage gets the path of the image displayed with the ministry blurb, or "" if there is none
+func (ministry Ministry) BlurbImage() string {
+	if info := ministry.Info(); info != nil {
+		return info.BlurbImage
+	}
+	return ""
+}
+
+// BlurbHTML gets the introduction of the ministry rendered from Markdown to HTML. Returns "" if
+// the ministry has no blurb
+func (ministry Ministry) BlurbHTML() template.HTML {
+	info := ministry.Info()
+	if info == nil || strings.TrimSpace(info.Blurb) == "" {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	if err := goldmark.Convert([]byte(info.Blurb), &buf); err != nil {
+		return template.HTML(template.HTMLEscapeString(info.Blurb))
+	}
+	return template.HTML(buf.String())
+}
+
+// AudioProfile gets how audio is extracted from the ministry recordings, which is inherited from
+// the parent ministry if the ministry doesn't have one
+func (ministry Ministry) AudioProfile() AudioProfile {
+	for m := ministry; m != UnknownMinistry; m = m.Parent() {
+		if info := m.Info(); info != nil && info.Audio != nil {
+			return *info.Audio
+		}
 	}
+	return ministryRegistry.Audio
 }
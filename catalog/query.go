@@ -0,0 +1,479 @@
+package catalog
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A Query filters the messages or series of a catalog. It is made of terms that all have to match
+// (there is no "or" between terms, but a term can list several values). A term compares a field
+// to values, like
+//
+//	speaker=Mary Peltz        any speaker is Mary Peltz
+//	ministry=wol,core         listed in the Word of Life or CORE catalog
+//	visibility!=private       isn't private
+//	name~faith                name contains "faith"
+//	date>=2019-06             on or after June 2019
+//	date=2019                 any time in 2019
+//
+// or tests whether a series or message has something, like "has:audio" or "no:transcript".
+// Text is compared without regard to case. Dates can be partial (a year or a month), and are
+// compared only as far as the value goes, so "date<=2019" includes all of 2019
+
+// what a query searches
+type QueryTarget string
+
+const (
+	QueryMessages QueryTarget = "messages" // query the messages of the catalog
+	QuerySeries   QueryTarget = "series"   // query the series of the catalog
+)
+
+// QueryTerm is one condition of a query
+type QueryTerm struct {
+	Field    string   // name of the field or property, like "speaker" or "audio"
+	Operator string   // how the field is compared: =, !=, ~, <, <=, >, >=, has, or no
+	Values   []string // values the field is compared to, any of which can match
+}
+
+// Query is a list of terms that a message or series must all match
+type Query struct {
+	Target QueryTarget // what the query searches
+	Terms  []QueryTerm // conditions that have to match
+}
+
+// queryField is a field that a query can compare. A field can have more than one value (like the
+// speakers of a message)
+type queryField struct {
+	dated     bool                           // values are dates (yyyy-mm-dd), which can be ordered
+	normalize func(string) (string, error)   // converts a value in a query to the form of the field, nil to use as is
+	equal     func(value, field string) bool // determines if a value in a query equals a value of the field, nil to compare text
+	message   func(*CatalogMessage) []string // values of a message, nil if messages don't have the field
+	seri      func(*CatalogSeri) []string    // values of a series, nil if series don't have the field
+}
+
+// queryProperty is something that a series or message has or doesn't have, like audio
+type queryProperty struct {
+	message func(*CatalogMessage) bool // whether a message has it, nil if it doesn't apply to messages
+	seri    func(*CatalogSeri) bool    // whether a series has it, nil if it doesn't apply to series
+}
+
+// fields that queries can compare
+var queryFields = map[string]queryField{
+	"id": {
+		seri: func(s *CatalogSeri) []string { return []string{s.ID} },
+	},
+	"name": {
+		message: func(m *CatalogMessage) []string { return []string{m.Name} },
+		seri:    func(s *CatalogSeri) []string { return []string{s.Name} },
+	},
+	"description": {
+		message: func(m *CatalogMessage) []string { return []string{m.Description} },
+		seri:    func(s *CatalogSeri) []string { return []string{s.Description} },
+	},
+	"date": {
+		dated:   true,
+		message: func(m *CatalogMessage) []string { return getQueryDates(m.Date) },
+		seri:    func(s *CatalogSeri) []string { return getQueryDates(s.StartDate) },
+	},
+	"start-date": {
+		dated: true,
+		seri:  func(s *CatalogSeri) []string { return getQueryDates(s.StartDate) },
+	},
+	"end-date": {
+		dated: true,
+		seri:  func(s *CatalogSeri) []string { return getQueryDates(s.StopDate) },
+	},
+	"speaker": {
+		equal:   isSameSpeaker,
+		message: func(m *CatalogMessage) []string { return m.Speakers },
+		seri:    func(s *CatalogSeri) []string { return s.Speakers },
+	},
+	"tag": {
+		message: func(m *CatalogMessage) []string { return m.Tags },
+		seri:    func(s *CatalogSeri) []string { return s.Tags },
+	},
+	"ministry": {
+		normalize: func(value string) (string, error) {
+			if ministry := NewMinistryFromString(value); ministry != UnknownMinistry {
+				return string(ministry), nil
+			}
+			return "", fmt.Errorf("unknown ministry '%s'", value)
+		},
+		equal: func(value, field string) bool { return Ministry(value).Includes(Ministry(field)) },
+		message: func(m *CatalogMessage) []string {
+			return getQueryMinistries(append([]Ministry{m.Ministry}, m.AlsoIn...))
+		},
+		seri: func(s *CatalogSeri) []string {
+			ministries := append([]Ministry{s.GetMinistry()}, s.AlsoIn...)
+			for _, msg := range s.Messages {
+				ministries = append(ministries, msg.AlsoIn...)
+			}
+			return getQueryMinistries(ministries)
+		},
+	},
+	"type": {
+		normalize: func(value string) (string, error) {
+			if messageType := NewMessageTypeFromString(value); messageType != UnknownType {
+				return string(messageType), nil
+			}
+			return "", fmt.Errorf("unknown message type '%s'", value)
+		},
+		message: func(m *CatalogMessage) []string { return []string{string(m.Type)} },
+	},
+	"visibility": {
+		normalize: func(value string) (string, error) {
+			if view := NewViewFromString(value); value != "" && view != UnknownView {
+				return string(view), nil
+			}
+			return "", fmt.Errorf("unknown visibility '%s'", value)
+		},
+		message: func(m *CatalogMessage) []string { return []string{string(m.Visibility)} },
+		seri:    func(s *CatalogSeri) []string { return []string{string(s.Visibility)} },
+	},
+	"series": {
+		message: func(m *CatalogMessage) []string {
+			var names []string
+			for _, ref := range m.Series {
+				names = append(names, ref.Name)
+			}
+			return names
+		},
+	},
+}
+
+// properties that queries can test for
+var queryProperties = map[string]queryProperty{
+	"audio": {
+		message: (*CatalogMessage).HasAudio,
+		seri:    (*CatalogSeri).HasAudio,
+	},
+	"video": {
+		message: (*CatalogMessage).HasVideo,
+		seri: func(s *CatalogSeri) bool {
+			return slices.ContainsFunc(s.Messages, func(m CatalogMessage) bool { return m.HasVideo() })
+		},
+	},
+	"transcript": {
+		message: (*CatalogMessage).HasTranscript,
+	},
+	"thumbnail": {
+		message: func(m *CatalogMessage) bool { return m.Thumb != nil && m.Thumb.URL != "" },
+		seri:    func(s *CatalogSeri) bool { return s.Thumbnail != "" },
+	},
+	"description": {
+		message: func(m *CatalogMessage) bool { return strings.TrimSpace(m.Description) != "" },
+		seri:    func(s *CatalogSeri) bool { return strings.TrimSpace(s.Description) != "" },
+	},
+	"speaker": {
+		message: func(m *CatalogMessage) bool { return len(m.Speakers) > 0 },
+		seri:    func(s *CatalogSeri) bool { return len(s.Speakers) > 0 },
+	},
+	"tags": {
+		message: func(m *CatalogMessage) bool { return len(m.Tags) > 0 },
+		seri:    func(s *CatalogSeri) bool { return len(s.Tags) > 0 },
+	},
+	"series": {
+		message: func(m *CatalogMessage) bool { return len(m.Series) > 0 },
+	},
+	"resources": {
+		message: func(m *CatalogMessage) bool { return len(m.Resources) > 0 },
+		seri:    func(s *CatalogSeri) bool { return len(s.Resources) > 0 },
+	},
+	"booklets": {
+		seri: func(s *CatalogSeri) bool { return len(s.Booklets) > 0 },
+	},
+	"jacket": {
+		seri: func(s *CatalogSeri) bool { return s.Jacket != "" },
+	},
+	"scriptures": {
+		message: func(m *CatalogMessage) bool { return len(m.Scriptures) > 0 },
+	},
+}
+
+// syntax of the terms of a query
+var (
+	queryPropertyPattern = regexp.MustCompile(`^(has|no):([a-z-]+)$`)
+	queryFieldPattern    = regexp.MustCompile(`^([a-z-]+)(!=|<=|>=|=|~|<|>)(.*)$`)
+)
+
+// +---------------------------------------------------------------------------
+// | Constructors
+// +---------------------------------------------------------------------------
+
+// ParseQuery parses the terms of a query of messages or series. Each expression is one term,
+// like "speaker=Mary Peltz" or "no:transcript"
+func ParseQuery(target QueryTarget, expressions []string) (*Query, error) {
+	if target != QueryMessages && target != QuerySeries {
+		return nil, fmt.Errorf("cannot query '%s'. query messages or series", target)
+	}
+
+	query := &Query{Target: target}
+	for _, expression := range expressions {
+		term, err := parseQueryTerm(target, strings.TrimSpace(expression))
+		if err != nil {
+			return nil, err
+		}
+		query.Terms = append(query.Terms, term)
+	}
+	return query, nil
+}
+
+// parseQueryTerm parses one term of a query
+func parseQueryTerm(target QueryTarget, expression string) (QueryTerm, error) {
+	if match := queryPropertyPattern.FindStringSubmatch(expression); match != nil {
+		term := QueryTerm{Field: match[2], Operator: match[1]}
+		property, ok := queryProperties[term.Field]
+		if !ok || (target == QueryMessages && property.message == nil) || (target == QuerySeries && property.seri == nil) {
+			return term, fmt.Errorf("%s don't have '%s'. use one of: %s", target, term.Field,
+				strings.Join(GetQueryProperties(target), ", "))
+		}
+		return term, nil
+	}
+
+	match := queryFieldPattern.FindStringSubmatch(expression)
+	if match == nil {
+		return QueryTerm{}, fmt.Errorf("cannot understand '%s'. use field=value, has:property, or no:property", expression)
+	}
+
+	term := QueryTerm{Field: match[1], Operator: match[2]}
+	field, ok := queryFields[term.Field]
+	if !ok || (target == QueryMessages && field.message == nil) || (target == QuerySeries && field.seri == nil) {
+		return term, fmt.Errorf("%s don't have the field '%s'. use one of: %s", target, term.Field,
+			strings.Join(GetQueryFields(target), ", "))
+	}
+
+	for _, value := range strings.Split(match[3], ",") {
+		value = strings.TrimSpace(value)
+		if field.normalize != nil && term.Operator != "~" {
+			var err error
+			if value, err = field.normalize(value); err != nil {
+				return term, err
+			}
+		}
+		term.Values = append(term.Values, value)
+	}
+
+	switch term.Operator {
+	case "<", "<=", ">", ">=":
+		if !field.dated {
+			return term, fmt.Errorf("'%s' can only be compared with =, !=, or ~", term.Field)
+		}
+		if len(term.Values) != 1 {
+			return term, fmt.Errorf("'%s' can only be compared to one date", expression)
+		}
+	}
+	if field.dated && term.Operator != "~" {
+		for _, value := range term.Values {
+			if !isQueryDate(value) {
+				return term, fmt.Errorf("'%s' is not a date like 2019, 2019-06, or 2019-06-02", value)
+			}
+		}
+	}
+
+	return term, nil
+}
+
+// +---------------------------------------------------------------------------
+// | Accessors
+// +---------------------------------------------------------------------------
+
+// GetQueryFields gets the names of the fields that queries of the target can compare
+func GetQueryFields(target QueryTarget) []string {
+	var names []string
+	for name, field := range queryFields {
+		if (target == QueryMessages && field.message != nil) || (target == QuerySeries && field.seri != nil) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetQueryProperties gets the names of the properties that queries of the target can test for
+func GetQueryProperties(target QueryTarget) []string {
+	var names []string
+	for name, property := range queryProperties {
+		if (target == QueryMessages && property.message != nil) || (target == QuerySeries && property.seri != nil) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetMessageField gets the values of a field of a message, or nil if messages don't have the
+// field
+func GetMessageField(msg *CatalogMessage, name string) []string {
+	if field, ok := queryFields[name]; ok && field.message != nil {
+		return field.message(msg)
+	}
+	return nil
+}
+
+// GetSeriField gets the values of a field of a series, or nil if series don't have the field
+func GetSeriField(seri *CatalogSeri, name string) []string {
+	if field, ok := queryFields[name]; ok && field.seri != nil {
+		return field.seri(seri)
+	}
+	return nil
+}
+
+// +---------------------------------------------------------------------------
+// | Matching
+// +---------------------------------------------------------------------------
+
+// MatchesMessage determines if a message matches all the terms of the query
+func (q *Query) MatchesMessage(msg *CatalogMessage) bool {
+	for _, term := range q.Terms {
+		if property, ok := queryProperties[term.Field]; ok && (term.Operator == "has" || term.Operator == "no") {
+			if property.message(msg) != (term.Operator == "has") {
+				return false
+			}
+			continue
+		}
+		if !term.matches(queryFields[term.Field], queryFields[term.Field].message(msg)) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesSeri determines if a series matches all the terms of the query
+func (q *Query) MatchesSeri(seri *CatalogSeri) bool {
+	for _, term := range q.Terms {
+		if property, ok := queryProperties[term.Field]; ok && (term.Operator == "has" || term.Operator == "no") {
+			if property.seri(seri) != (term.Operator == "has") {
+				return false
+			}
+			continue
+		}
+		if !term.matches(queryFields[term.Field], queryFields[term.Field].seri(seri)) {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterMessages finds the messages that match the query
+func (q *Query) FilterMessages(messages []CatalogMessage) []CatalogMessage {
+	result := []CatalogMessage{}
+	for index := range messages {
+		if q.MatchesMessage(&messages[index]) {
+			result = append(result, messages[index])
+		}
+	}
+	return result
+}
+
+// FilterSeries finds the series that match the query
+func (q *Query) FilterSeries(series []CatalogSeri) []CatalogSeri {
+	result := []CatalogSeri{}
+	for index := range series {
+		if q.MatchesSeri(&series[index]) {
+			result = append(result, series[index])
+		}
+	}
+	return result
+}
+
+// matches determines if the values of a field match the term. A field with several values
+// matches if any of them match, except for != which matches only if none of them are equal
+func (t QueryTerm) matches(field queryField, fieldValues []string) bool {
+	if t.Operator == "!=" {
+		return !t.equalsAny(field, fieldValues)
+	}
+	if t.Operator == "=" {
+		return t.equalsAny(field, fieldValues)
+	}
+
+	for _, fieldValue := range fieldValues {
+		for _, value := range t.Values {
+			if t.Operator == "~" {
+				if strings.Contains(strings.ToLower(fieldValue), strings.ToLower(value)) {
+					return true
+				}
+				continue
+			}
+
+			// dates are compared only as far as the value goes
+			fieldValue := fieldValue[:min(len(fieldValue), len(value))]
+			if (t.Operator == "<" && fieldValue < value) ||
+				(t.Operator == "<=" && fieldValue <= value) ||
+				(t.Operator == ">" && fieldValue > value) ||
+				(t.Operator == ">=" && fieldValue >= value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// equalsAny determines if any value of a field equals any of the values of the term
+func (t QueryTerm) equalsAny(field queryField, fieldValues []string) bool {
+	for _, fieldValue := range fieldValues {
+		for _, value := range t.Values {
+			switch {
+			case field.equal != nil:
+				if field.equal(value, fieldValue) {
+					return true
+				}
+			case field.dated:
+				if strings.HasPrefix(fieldValue, value) {
+					return true
+				}
+			case strings.EqualFold(fieldValue, value):
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// +---------------------------------------------------------------------------
+// | Helpers
+// +---------------------------------------------------------------------------
+
+// getQueryDates gets the value of a date field, which has no values if the date isn't set
+func getQueryDates(date DateOnly) []string {
+	if date.IsZero() {
+		return nil
+	}
+	return []string{date.String()}
+}
+
+// getQueryMinistries gets the values of a ministry field
+func getQueryMinistries(ministries []Ministry) []string {
+	var values []string
+	for _, ministry := range ministries {
+		if ministry != "" && ministry != UnknownMinistry && !slices.Contains(values, string(ministry)) {
+			values = append(values, string(ministry))
+		}
+	}
+	return values
+}
+
+// isQueryDate determines if a value is a full or partial date, like 2019, 2019-06, or 2019-06-02
+func isQueryDate(value string) bool {
+	for _, layout := range []string{"2006", "2006-01", "2006-01-02"} {
+		if len(value) == len(layout) {
+			if _, err := time.Parse(layout, value); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isSameSpeaker determines if two names are the same speaker, either because they are the same
+// name or they are names of the same well-known speaker
+func isSameSpeaker(name1, name2 string) bool {
+	if strings.EqualFold(strings.TrimSpace(name1), strings.TrimSpace(name2)) {
+		return true
+	}
+	speaker := GetSpeakerRegistry().Find(name1)
+	return speaker != nil && speaker == GetSpeakerRegistry().Find(name2)
+}
@@ -0,0 +1,153 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// Runs the test suite as a test
+func TestQueryTestSuite(t *testing.T) {
+	suite.Run(t, new(QueryTestSuite))
+}
+
+type QueryTestSuite struct {
+	suite.Suite
+}
+
+// newQueryCatalog creates a catalog with a few messages in two ministries
+func (t *QueryTestSuite) newQueryCatalog() *Catalog {
+	cat := &Catalog{
+		Series: []CatalogSeri{
+			{ID: "S1", Name: "Faith", Visibility: Public, Thumbnail: "faith.jpg"},
+			{ID: "S2", Name: "Hope", Visibility: Partner},
+		},
+		Messages: []CatalogMessage{
+			{
+				Name:       "Faith 1",
+				Date:       MustParseDateOnly("2019-01-06"),
+				Speakers:   []string{"Vern Peltz"},
+				Ministry:   WordOfLife,
+				Type:       Message,
+				Visibility: Public,
+				Series:     []SeriesReference{{Name: "Faith", Index: 1}},
+				Audio:      &OnlineResource{URL: "https://example.com/faith1.mp3"},
+			},
+			{
+				Name:       "Faith 2",
+				Date:       MustParseDateOnly("2019-12-29"),
+				Speakers:   []string{"Mary Peltz"},
+				Ministry:   WordOfLife,
+				Type:       Message,
+				Visibility: Public,
+				Series:     []SeriesReference{{Name: "Faith", Index: 2}},
+			},
+			{
+				Name:       "Hope 1",
+				Date:       MustParseDateOnly("2020-03-01"),
+				Speakers:   []string{"Mary Peltz", "Vern Peltz"},
+				Ministry:   TheBridgeOutreach,
+				Type:       Training,
+				Visibility: Partner,
+				Series:     []SeriesReference{{Name: "Hope", Index: 1}},
+				Video:      &OnlineResource{URL: "https://example.com/hope1.mp4"},
+			},
+		},
+	}
+	t.Require().NoError(cat.Initialize())
+	return cat
+}
+
+// queryMessageNames gets the names of the messages that match a query
+func (t *QueryTestSuite) queryMessageNames(cat *Catalog, expressions ...string) []string {
+	query, err := ParseQuery(QueryMessages, expressions)
+	t.Require().NoError(err)
+
+	names := []string{}
+	for _, msg := range query.FilterMessages(cat.Messages) {
+		names = append(names, msg.Name)
+	}
+	return names
+}
+
+func (t *QueryTestSuite) TestParseQuery() {
+	query, err := ParseQuery(QueryMessages, []string{"speaker=Mary Peltz, Vern", "visibility!=protected", "no:audio"})
+	t.NoError(err)
+	t.Equal([]QueryTerm{
+		{Field: "speaker", Operator: "=", Values: []string{"Mary Peltz", "Vern"}},
+		{Field: "visibility", Operator: "!=", Values: []string{"partner"}},
+		{Field: "audio", Operator: "no"},
+	}, query.Terms)
+
+	for _, expression := range []string{
+		"speaker",             // no operator
+		"color=red",           // unknown field
+		"id=S1",               // series field
+		"has:booklets",        // series property
+		"name>A",              // only dates can be ordered
+		"date=last year",      // not a date
+		"date>=2019,2020",     // can only be ordered against one date
+		"ministry=nowhere",    // unknown ministry
+		"visibility=everyone", // unknown visibility
+	} {
+		_, err := ParseQuery(QueryMessages, []string{expression})
+		t.Error(err, expression)
+	}
+
+	_, err = ParseQuery("speakers", nil)
+	t.Error(err)
+}
+
+func (t *QueryTestSuite) TestMatchText() {
+	cat := t.newQueryCatalog()
+
+	t.Equal([]string{"Faith 2", "Hope 1"}, t.queryMessageNames(cat, "speaker=mary peltz"))
+	t.Equal([]string{"Faith 1", "Hope 1"}, t.queryMessageNames(cat, "speaker=vern"))
+	t.Equal([]string{"Faith 2"}, t.queryMessageNames(cat, "speaker!=Vern Peltz"))
+	t.Empty(t.queryMessageNames(cat, "speaker!=Vern Peltz,Mary Peltz"))
+	t.Equal([]string{"Faith 1", "Faith 2"}, t.queryMessageNames(cat, "name~FAITH"))
+	t.Equal([]string{"Faith 1", "Faith 2"}, t.queryMessageNames(cat, "series=faith"))
+	t.Equal([]string{"Hope 1"}, t.queryMessageNames(cat, "type=training"))
+	t.Equal([]string{"Hope 1"}, t.queryMessageNames(cat, "visibility!=public"))
+	t.Equal([]string{"Hope 1"}, t.queryMessageNames(cat, "ministry=tbo"))
+	t.Equal([]string{"Faith 1", "Faith 2", "Hope 1"}, t.queryMessageNames(cat, "ministry=wol,tbo"))
+}
+
+func (t *QueryTestSuite) TestMatchDates() {
+	cat := t.newQueryCatalog()
+
+	t.Equal([]string{"Faith 1", "Faith 2"}, t.queryMessageNames(cat, "date=2019"))
+	t.Equal([]string{"Faith 2"}, t.queryMessageNames(cat, "date=2019-12"))
+	t.Equal([]string{"Faith 1", "Faith 2"}, t.queryMessageNames(cat, "date<=2019"))
+	t.Equal([]string{"Faith 1", "Faith 2"}, t.queryMessageNames(cat, "date<2020-01-01"))
+	t.Equal([]string{"Hope 1"}, t.queryMessageNames(cat, "date>2019"))
+	t.Equal([]string{"Faith 2", "Hope 1"}, t.queryMessageNames(cat, "date>=2019-02", "date<2021"))
+}
+
+func (t *QueryTestSuite) TestMatchProperties() {
+	cat := t.newQueryCatalog()
+
+	t.Equal([]string{"Faith 1"}, t.queryMessageNames(cat, "has:audio"))
+	t.Equal([]string{"Faith 2", "Hope 1"}, t.queryMessageNames(cat, "no:audio"))
+	t.Equal([]string{"Hope 1"}, t.queryMessageNames(cat, "has:video", "speaker=Mary Peltz"))
+	t.Empty(t.queryMessageNames(cat, "has:video", "no:speaker"))
+}
+
+func (t *QueryTestSuite) TestMatchSeries() {
+	cat := t.newQueryCatalog()
+
+	query, err := ParseQuery(QuerySeries, []string{"no:thumbnail"})
+	t.Require().NoError(err)
+	series := query.FilterSeries(cat.Series[:2])
+	if t.Len(series, 1) {
+		t.Equal("Hope", series[0].Name)
+	}
+
+	query, err = ParseQuery(QuerySeries, []string{"start-date=2019", "has:audio", "speaker=Mary Peltz"})
+	t.Require().NoError(err)
+	series = query.FilterSeries(cat.Series[:2])
+	if t.Len(series, 1) {
+		t.Equal("Faith", series[0].Name)
+		t.Equal([]string{"2019-12-29"}, GetSeriField(&series[0], "end-date"))
+	}
+}
@@ -0,0 +1,442 @@
+package catalog
+
+import (
+	"fmt"
+	"html/template"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/WordOfLifeMN/online/util"
+)
+
+// ScriptureReference is a reference to a passage of the Bible, like "John 3:16-18" or
+// "1 Corinthians 13". The book is always the full name of the book
+type ScriptureReference struct {
+	Book       string `json:"book"`                  // full name of the book
+	Chapter    int    `json:"chapter"`               // first chapter of the passage
+	Verse      int    `json:"verse,omitempty"`       // first verse of the passage, 0 for the whole chapter
+	EndChapter int    `json:"end-chapter,omitempty"` // last chapter of the passage, 0 if it's the first chapter
+	EndVerse   int    `json:"end-verse,omitempty"`   // last verse of the passage, 0 if it's the first verse
+}
+
+// scriptureBook describes one book of the Bible
+type scriptureBook struct {
+	name     string   // full name
+	chapters int      // number of chapters
+	verses   int      // number of verses in the longest chapter
+	aliases  []string // abbreviations and other names, without any number prefix
+}
+
+// books of the Bible in canonical order. books that start with a number (1 Samuel, etc) are
+// listed with the number, and the aliases are generated for all the ways to write the number
+var scriptureBooks = []scriptureBook{
+	{"Genesis", 50, 67, []string{"Gen", "Gn"}},
+	{"Exodus", 40, 51, []string{"Exod", "Exo"}},
+	{"Leviticus", 27, 59, []string{"Lev", "Lv"}},
+	{"Numbers", 36, 89, []string{"Num", "Nm"}},
+	{"Deuteronomy", 34, 68, []string{"Deut", "Dt"}},
+	{"Joshua", 24, 63, []string{"Josh", "Jos"}},
+	{"Judges", 21, 57, []string{"Judg", "Jdg"}},
+	{"Ruth", 4, 23, []string{"Rth"}},
+	{"1 Samuel", 31, 58, []string{"Samuel", "Sam", "Sm"}},
+	{"2 Samuel", 24, 43, []string{"Samuel", "Sam", "Sm"}},
+	{"1 Kings", 22, 66, []string{"Kings", "Kgs", "Kin"}},
+	{"2 Kings", 25, 37, []string{"Kings", "Kgs", "Kin"}},
+	{"1 Chronicles", 29, 81, []string{"Chronicles", "Chron", "Chr"}},
+	{"2 Chronicles", 36, 42, []string{"Chronicles", "Chron", "Chr"}},
+	{"Ezra", 10, 70, []string{"Ezr"}},
+	{"Nehemiah", 13, 73, []string{"Neh"}},
+	{"Esther", 10, 32, []string{"Esth", "Est"}},
+	{"Job", 42, 41, []string{}},
+	{"Psalms", 150, 176, []string{"Psalm", "Psa", "Pss", "Ps"}},
+	{"Proverbs", 31, 36, []string{"Prov", "Prv", "Pro"}},
+	{"Ecclesiastes", 12, 29, []string{"Eccles", "Eccl", "Ecc", "Qoheleth"}},
+	{"Song of Solomon", 8, 17, []string{"Song of Songs", "Song of Sol", "Song", "SoS"}},
+	{"Isaiah", 66, 38, []string{"Isa"}},
+	{"Jeremiah", 52, 64, []string{"Jer"}},
+	{"Lamentations", 5, 66, []string{"Lam"}},
+	{"Ezekiel", 48, 63, []string{"Ezek", "Eze"}},
+	{"Daniel", 12, 49, []string{"Dan", "Dn"}},
+	{"Hosea", 14, 23, []string{"Hos"}},
+	{"Joel", 3, 32, []string{}},
+	{"Amos", 9, 27, []string{}},
+	{"Obadiah", 1, 21, []string{"Obad", "Oba"}},
+	{"Jonah", 4, 17, []string{"Jon"}},
+	{"Micah", 7, 20, []string{"Mic"}},
+	{"Nahum", 3, 19, []string{"Nah"}},
+	{"Habakkuk", 3, 20, []string{"Hab"}},
+	{"Zephaniah", 3, 20, []string{"Zeph", "Zep"}},
+	{"Haggai", 2, 23, []string{"Hag"}},
+	{"Zechariah", 14, 23, []string{"Zech", "Zec"}},
+	{"Malachi", 4, 18, []string{"Mal"}},
+	{"Matthew", 28, 75, []string{"Matt", "Mat", "Mt"}},
+	{"Mark", 16, 72, []string{"Mrk", "Mk"}},
+	{"Luke", 24, 80, []string{"Luk", "Lk"}},
+	{"John", 21, 71, []string{"Jhn", "Jn"}},
+	{"Acts", 28, 60, []string{"Act"}},
+	{"Romans", 16, 39, []string{"Rom", "Rm"}},
+	{"1 Corinthians", 16, 58, []string{"Corinthians", "Cor"}},
+	{"2 Corinthians", 13, 33, []string{"Corinthians", "Cor"}},
+	{"Galatians", 6, 31, []string{"Gal"}},
+	{"Ephesians", 6, 33, []string{"Eph"}},
+	{"Philippians", 4, 30, []string{"Phil", "Php"}},
+	{"Colossians", 4, 29, []string{"Col"}},
+	{"1 Thessalonians", 5, 28, []string{"Thessalonians", "Thess", "Th"}},
+	{"2 Thessalonians", 3, 17, []string{"Thessalonians", "Thess", "Th"}},
+	{"1 Timothy", 6, 25, []string{"Timothy", "Tim", "Tm"}},
+	{"2 Timothy", 4, 26, []string{"Timothy", "Tim", "Tm"}},
+	{"Titus", 3, 16, []string{"Tit"}},
+	{"Philemon", 1, 25, []string{"Philem", "Phm"}},
+	{"Hebrews", 13, 40, []string{"Heb"}},
+	{"James", 5, 27, []string{"Jas", "Jm"}},
+	{"1 Peter", 5, 25, []string{"Peter", "Pet", "Pt"}},
+	{"2 Peter", 3, 22, []string{"Peter", "Pet", "Pt"}},
+	{"1 John", 5, 29, []string{"John", "Jhn", "Jn"}},
+	{"2 John", 1, 13, []string{"John", "Jhn", "Jn"}},
+	{"3 John", 1, 15, []string{"John", "Jhn", "Jn"}},
+	{"Jude", 1, 25, []string{}},
+	{"Revelation", 22, 29, []string{"Revelations", "Rev", "Rv"}},
+}
+
+// all the ways a book number can be written
+var scriptureBookNumbers = map[string][]string{
+	"1": {"1", "I", "1st", "First"},
+	"2": {"2", "II", "2nd", "Second"},
+	"3": {"3", "III", "3rd", "Third"},
+}
+
+// scriptureBooksByName is the index of a book by all the names it goes by. the key is the
+// lower-case name with all the spaces removed
+var scriptureBooksByName map[string]int
+
+// scriptureReferenceRE finds scripture references in text. the groups are the book, chapter,
+// verse, and then the chapter/verse of the end of the passage
+var scriptureReferenceRE *regexp.Regexp
+
+func init() {
+	scriptureBooksByName = map[string]int{}
+	var names []string
+	for index, book := range scriptureBooks {
+		number, name, numbered := strings.Cut(book.name, " ")
+		if !numbered || scriptureBookNumbers[number] == nil {
+			for _, n := range append([]string{book.name}, book.aliases...) {
+				scriptureBooksByName[normalizeBookName(n)] = index
+				names = append(names, n)
+			}
+			continue
+		}
+
+		for _, n := range append([]string{name}, book.aliases...) {
+			for _, prefix := range scriptureBookNumbers[number] {
+				scriptureBooksByName[normalizeBookName(prefix+n)] = index
+				names = append(names, prefix+" "+n)
+				if unicode.IsDigit(rune(prefix[0])) {
+					names = append(names, prefix+n)
+				}
+			}
+		}
+	}
+
+	// longest names first so "1 John" and "Song of Songs" are preferred over "John" and "Song"
+	sort.SliceStable(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	alternatives := make([]string, 0, len(names))
+	for _, name := range names {
+		alternatives = append(alternatives, strings.ReplaceAll(regexp.QuoteMeta(name), " ", `\s+`))
+	}
+
+	scriptureReferenceRE = regexp.MustCompile(`(?i)\b(` + strings.Join(alternatives, "|") + `)` +
+		`(?:\.\s*|\s+)(\d{1,3})(?:\s*:\s*(\d{1,3}))?(?:\s*[-–—]\s*(\d{1,3})(?:\s*:\s*(\d{1,3}))?)?`)
+}
+
+// normalizeBookName converts the name of a book into the key of scriptureBooksByName
+func normalizeBookName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), ""))
+}
+
+// +---------------------------------------------------------------------------
+// | Parsing
+// +---------------------------------------------------------------------------
+
+// ParseScriptureReferences finds all the scripture references in some text, like "John 3:16-18"
+// or "1 Cor 13". Book abbreviations are expanded to the full name of the book. References to
+// chapters that don't exist, or to verses past the end of the longest chapter of the book, are
+// ignored, as are references where the book isn't capitalized (so "mark 3 times" isn't a
+// reference). Duplicate references are only returned once
+func ParseScriptureReferences(text string) []ScriptureReference {
+	var refs []ScriptureReference
+	for _, match := range findScriptureReferences(text) {
+		if !slices.Contains(refs, match.ref) {
+			refs = append(refs, match.ref)
+		}
+	}
+	return refs
+}
+
+// NewScriptureReferenceFromString parses a single scripture reference, like "John 3:16". Returns
+// an error if the string isn't a reference
+func NewScriptureReferenceFromString(s string) (ScriptureReference, error) {
+	matches := findScriptureReferences(s)
+	if len(matches) != 1 || strings.TrimSpace(s[matches[0].start:matches[0].end]) != strings.TrimSpace(s) {
+		return ScriptureReference{}, fmt.Errorf("'%s' is not a scripture reference", s)
+	}
+	return matches[0].ref, nil
+}
+
+// scriptureMatch is a reference found in text, and where it was found
+type scriptureMatch struct {
+	ref        ScriptureReference
+	start, end int
+}
+
+// findScriptureReferences finds the location of all the valid scripture references in text
+func findScriptureReferences(text string) []scriptureMatch {
+	var matches []scriptureMatch
+	for _, loc := range scriptureReferenceRE.FindAllStringSubmatchIndex(text, -1) {
+		group := func(n int) string {
+			if loc[2*n] < 0 {
+				return ""
+			}
+			return text[loc[2*n]:loc[2*n+1]]
+		}
+		number := func(n int) int {
+			value, _ := strconv.Atoi(group(n))
+			return value
+		}
+
+		// the book must be capitalized, and the numbers can't run into other digits
+		if first := []rune(group(1))[0]; unicode.IsLower(first) {
+			continue
+		}
+		if loc[1] < len(text) && unicode.IsDigit(rune(text[loc[1]])) {
+			continue
+		}
+
+		index := scriptureBooksByName[normalizeBookName(group(1))]
+		ref := ScriptureReference{
+			Book:    scriptureBooks[index].name,
+			Chapter: number(2),
+			Verse:   number(3),
+		}
+		switch {
+		case group(4) != "" && group(5) != "":
+			// John 3:16-4:2 or John 3-4:2
+			ref.EndChapter, ref.EndVerse = number(4), number(5)
+		case group(4) != "" && ref.Verse != 0:
+			// John 3:16-18
+			ref.EndVerse = number(4)
+		case group(4) != "":
+			// John 3-4
+			ref.EndChapter = number(4)
+		}
+
+		if !ref.isValid() {
+			continue
+		}
+		matches = append(matches, scriptureMatch{ref: ref, start: loc[0], end: loc[1]})
+	}
+	return matches
+}
+
+// isValid checks that the chapters exist, the verses are in the longest chapter of the book, and
+// the end of the passage isn't before the start. The verses aren't checked against the number of
+// verses in their own chapter
+func (r ScriptureReference) isValid() bool {
+	index := r.BookIndex()
+	if index < 0 || r.Chapter < 1 || r.Chapter > scriptureBooks[index].chapters {
+		return false
+	}
+	if r.Verse > scriptureBooks[index].verses || r.EndVerse > scriptureBooks[index].verses {
+		return false
+	}
+	if r.EndChapter != 0 && (r.EndChapter < r.Chapter || r.EndChapter > scriptureBooks[index].chapters) {
+		return false
+	}
+	if r.EndChapter == 0 && r.EndVerse != 0 && r.EndVerse < r.Verse {
+		return false
+	}
+	return true
+}
+
+// +---------------------------------------------------------------------------
+// | Accessors
+// +---------------------------------------------------------------------------
+
+// String gets the reference in the standard form, like "John 3:16-18", "Genesis 1:1-2:3",
+// "1 Corinthians 13", or "Psalms 23-24"
+func (r ScriptureReference) String() string {
+	s := r.Book + " " + strconv.Itoa(r.Chapter)
+	if r.Verse != 0 {
+		s += ":" + strconv.Itoa(r.Verse)
+	}
+	switch {
+	case r.EndChapter != 0 && r.EndVerse != 0:
+		s += "-" + strconv.Itoa(r.EndChapter) + ":" + strconv.Itoa(r.EndVerse)
+	case r.EndChapter != 0:
+		s += "-" + strconv.Itoa(r.EndChapter)
+	case r.EndVerse != 0:
+		s += "-" + strconv.Itoa(r.EndVerse)
+	}
+	return s
+}
+
+// BookIndex gets the position of the book in the Bible (0 for Genesis). Returns -1 if the book
+// isn't known
+func (r ScriptureReference) BookIndex() int {
+	for index, book := range scriptureBooks {
+		if book.name == r.Book {
+			return index
+		}
+	}
+	return -1
+}
+
+// Anchor gets the ID of the chapter of the reference in the scripture index page, like
+// "1-corinthians-13"
+func (r ScriptureReference) Anchor() string {
+	return GetScriptureAnchor(r.Book, r.Chapter)
+}
+
+// GetScriptureAnchor gets the ID of a book, or a chapter of a book if the chapter isn't 0, in the
+// scripture index page
+func GetScriptureAnchor(book string, chapter int) string {
+	anchor := strings.ToLower(strings.Join(strings.Fields(book), "-"))
+	if chapter != 0 {
+		anchor += "-" + strconv.Itoa(chapter)
+	}
+	return anchor
+}
+
+// SortScriptureReferences sorts references in the order of the Bible
+func SortScriptureReferences(refs []ScriptureReference) {
+	sort.SliceStable(refs, func(i, j int) bool {
+		a, b := refs[i], refs[j]
+		if a.BookIndex() != b.BookIndex() {
+			return a.BookIndex() < b.BookIndex()
+		}
+		if a.Chapter != b.Chapter {
+			return a.Chapter < b.Chapter
+		}
+		return a.Verse < b.Verse
+	})
+}
+
+// GetCatalogFileNameForScriptures generates the name of the HTML file of the scripture index for
+// a ministry and view. The name has a hash to make the page name harder to guess
+func GetCatalogFileNameForScriptures(ministry Ministry, view View) string {
+	nameBase := string(ministry) + "-" + string(view)
+	return "scripture." + nameBase + "-" + util.ComputeHash(nameBase+"-scripture") + ".html"
+}
+
+// linkScriptureReferences converts text to HTML where each scripture reference links to its
+// chapter in the scripture index of the ministry and view. If the view doesn't have scripture
+// index pages (raw or unknown), then the text is just escaped
+func linkScriptureReferences(text string, ministry Ministry, view View) template.HTML {
+	if view != Public && view != Partner && view != Private {
+		return template.HTML(template.HTMLEscapeString(text))
+	}
+
+	indexFileName := GetCatalogFileNameForScriptures(ministry, view)
+	var sb strings.Builder
+	last := 0
+	for _, match := range findScriptureReferences(text) {
+		sb.WriteString(template.HTMLEscapeString(text[last:match.start]))
+		fmt.Fprintf(&sb, `<a href="%s#%s" title="%s">%s</a>`,
+			indexFileName, match.ref.Anchor(),
+			template.HTMLEscapeString(match.ref.String()),
+			template.HTMLEscapeString(text[match.start:match.end]))
+		last = match.end
+	}
+	sb.WriteString(template.HTMLEscapeString(text[last:]))
+	return template.HTML(sb.String())
+}
+
+// +---------------------------------------------------------------------------
+// | Index
+// +---------------------------------------------------------------------------
+
+// ScriptureIndexBook is one book in a scripture index, with all the chapters that are referenced
+type ScriptureIndexBook struct {
+	Book     string                  // full name of the book
+	Chapters []ScriptureIndexChapter // referenced chapters, in order
+}
+
+// ScriptureIndexChapter is one chapter in a scripture index, with all the messages that
+// reference it
+type ScriptureIndexChapter struct {
+	Book    string                // full name of the book
+	Chapter int                   // chapter number
+	Entries []ScriptureIndexEntry // messages that reference the chapter, in verse order
+}
+
+// ScriptureIndexEntry is one reference from a message in a scripture index
+type ScriptureIndexEntry struct {
+	Reference ScriptureReference // the reference in the message
+	Message   CatalogMessage     // the message with the reference
+	Seri      CatalogSeri        // the series the message is displayed in
+}
+
+// Anchor gets the ID of the book in the scripture index page
+func (b ScriptureIndexBook) Anchor() string {
+	return GetScriptureAnchor(b.Book, 0)
+}
+
+// Anchor gets the ID of the chapter in the scripture index page
+func (c ScriptureIndexChapter) Anchor() string {
+	return GetScriptureAnchor(c.Book, c.Chapter)
+}
+
+// NewScriptureIndex builds an index of all the scripture references in the messages of the
+// series, organized by book, then chapter. A passage that spans chapters is only listed under
+// the chapter it starts in. A message that is in more than one of the series is only listed
+// once, with the first series it is in
+func NewScriptureIndex(series []CatalogSeri) []ScriptureIndexBook {
+	var entries []ScriptureIndexEntry
+	seen := map[string]bool{}
+	for _, seri := range series {
+		for _, msg := range seri.Messages {
+			key := msg.Date.String() + "|" + msg.Name
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			for _, ref := range msg.Scriptures {
+				entries = append(entries, ScriptureIndexEntry{Reference: ref, Message: msg, Seri: seri})
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].Reference, entries[j].Reference
+		if a.BookIndex() != b.BookIndex() {
+			return a.BookIndex() < b.BookIndex()
+		}
+		if a.Chapter != b.Chapter {
+			return a.Chapter < b.Chapter
+		}
+		if a.Verse != b.Verse {
+			return a.Verse < b.Verse
+		}
+		return entries[i].Message.Date.Before(entries[j].Message.Date.Time)
+	})
+
+	var index []ScriptureIndexBook
+	for _, entry := range entries {
+		ref := entry.Reference
+		if len(index) == 0 || index[len(index)-1].Book != ref.Book {
+			index = append(index, ScriptureIndexBook{Book: ref.Book})
+		}
+		book := &index[len(index)-1]
+		if len(book.Chapters) == 0 || book.Chapters[len(book.Chapters)-1].Chapter != ref.Chapter {
+			book.Chapters = append(book.Chapters, ScriptureIndexChapter{Book: ref.Book, Chapter: ref.Chapter})
+		}
+		chapter := &book.Chapters[len(book.Chapters)-1]
+		chapter.Entries = append(chapter.Entries, entry)
+	}
+
+	return index
+}
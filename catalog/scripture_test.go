@@ -0,0 +1,173 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// Runs the test suite as a test
+func TestScriptureTestSuite(t *testing.T) {
+	suite.Run(t, new(ScriptureTestSuite))
+}
+
+type ScriptureTestSuite struct {
+	suite.Suite
+}
+
+// +---------------------------------------------------------------------------
+// | Parsing
+// +---------------------------------------------------------------------------
+
+func (t *ScriptureTestSuite) TestParse_Forms() {
+	for text, expected := range map[string]ScriptureReference{
+		"John 3:16":        {Book: "John", Chapter: 3, Verse: 16},
+		"John 3:16-18":     {Book: "John", Chapter: 3, Verse: 16, EndVerse: 18},
+		"Jn. 3:16 – 18":    {Book: "John", Chapter: 3, Verse: 16, EndVerse: 18},
+		"1 Cor 13":         {Book: "1 Corinthians", Chapter: 13},
+		"1Cor. 13:4":       {Book: "1 Corinthians", Chapter: 13, Verse: 4},
+		"II Timothy 2:15":  {Book: "2 Timothy", Chapter: 2, Verse: 15},
+		"First John 4:8":   {Book: "1 John", Chapter: 4, Verse: 8},
+		"Gen 1:1-2:3":      {Book: "Genesis", Chapter: 1, Verse: 1, EndChapter: 2, EndVerse: 3},
+		"Psalm 23-24":      {Book: "Psalms", Chapter: 23, EndChapter: 24},
+		"Song of Songs 2":  {Book: "Song of Solomon", Chapter: 2},
+		"Rev 22:21":        {Book: "Revelation", Chapter: 22, Verse: 21},
+		"PROVERBS 3:5-6":   {Book: "Proverbs", Chapter: 3, Verse: 5, EndVerse: 6},
+		"Romans 8 : 28":    {Book: "Romans", Chapter: 8, Verse: 28},
+		"3 John 1:2":       {Book: "3 John", Chapter: 1, Verse: 2},
+		"Philemon 1":       {Book: "Philemon", Chapter: 1},
+		"Phil 4:13":        {Book: "Philippians", Chapter: 4, Verse: 13},
+		"Heb 11:1":         {Book: "Hebrews", Chapter: 11, Verse: 1},
+		"Matthew 5:3-12":   {Book: "Matthew", Chapter: 5, Verse: 3, EndVerse: 12},
+		"Ecclesiastes 3:1": {Book: "Ecclesiastes", Chapter: 3, Verse: 1},
+		"Psalm 119:176":    {Book: "Psalms", Chapter: 119, Verse: 176},
+		"John 6:70-71":     {Book: "John", Chapter: 6, Verse: 70, EndVerse: 71},
+	} {
+		ref, err := NewScriptureReferenceFromString(text)
+		if t.NoError(err, text) {
+			t.Equal(expected, ref, text)
+		}
+	}
+}
+
+func (t *ScriptureTestSuite) TestParse_NotReferences() {
+	for _, text := range []string{
+		"",
+		"John",
+		"mark 3 times",
+		"John 22:1",
+		"John 3:18-16",
+		"Psalms 151",
+		"Genesis 1234",
+		"John 3:999",
+		"John 3:16-72",
+		"Jude 1:26",
+		"Open Heavens 3:16",
+	} {
+		_, err := NewScriptureReferenceFromString(text)
+		t.Error(err, text)
+	}
+}
+
+func (t *ScriptureTestSuite) TestParse_Text() {
+	refs := ParseScriptureReferences(
+		"Love is patient (1 Cor 13:4). For God so loved the world (John 3:16), and again in " +
+			"John 3:16 we see 3 times that faith is a gift. See also Eph. 2:8-9 and mark 3 times.")
+	t.Equal([]ScriptureReference{
+		{Book: "1 Corinthians", Chapter: 13, Verse: 4},
+		{Book: "John", Chapter: 3, Verse: 16},
+		{Book: "Ephesians", Chapter: 2, Verse: 8, EndVerse: 9},
+	}, refs)
+
+	t.Nil(ParseScriptureReferences("Nothing to see here"))
+}
+
+// +---------------------------------------------------------------------------
+// | Accessors
+// +---------------------------------------------------------------------------
+
+func (t *ScriptureTestSuite) TestString() {
+	for _, text := range []string{
+		"John 3:16",
+		"John 3:16-18",
+		"Genesis 1:1-2:3",
+		"1 Corinthians 13",
+		"Psalms 23-24",
+	} {
+		ref, err := NewScriptureReferenceFromString(text)
+		t.NoError(err)
+		t.Equal(text, ref.String())
+	}
+}
+
+func (t *ScriptureTestSuite) TestAnchor() {
+	t.Equal("john-3", ScriptureReference{Book: "John", Chapter: 3}.Anchor())
+	t.Equal("1-corinthians-13", ScriptureReference{Book: "1 Corinthians", Chapter: 13}.Anchor())
+	t.Equal("song-of-solomon", GetScriptureAnchor("Song of Solomon", 0))
+}
+
+func (t *ScriptureTestSuite) TestSort() {
+	refs := []ScriptureReference{
+		{Book: "John", Chapter: 3, Verse: 16},
+		{Book: "Genesis", Chapter: 1},
+		{Book: "John", Chapter: 1, Verse: 1},
+		{Book: "John", Chapter: 3, Verse: 1},
+	}
+	SortScriptureReferences(refs)
+	t.Equal("Genesis 1", refs[0].String())
+	t.Equal("John 1:1", refs[1].String())
+	t.Equal("John 3:1", refs[2].String())
+	t.Equal("John 3:16", refs[3].String())
+}
+
+func (t *ScriptureTestSuite) TestLinkScriptureReferences() {
+	index := GetCatalogFileNameForScriptures(WordOfLife, Public)
+
+	html := string(linkScriptureReferences("Faith & works <James 2:14-26>", WordOfLife, Public))
+	t.Equal(`Faith &amp; works &lt;<a href="`+index+`#james-2" title="James 2:14-26">James 2:14-26</a>&gt;`, html)
+
+	// raw views aren't linked
+	html = string(linkScriptureReferences("Faith & works <James 2:14-26>", WordOfLife, Raw))
+	t.Equal(`Faith &amp; works &lt;James 2:14-26&gt;`, html)
+}
+
+// +---------------------------------------------------------------------------
+// | Index
+// +---------------------------------------------------------------------------
+
+func (t *ScriptureTestSuite) TestIndex() {
+	msgA := CatalogMessage{
+		Name: "MSG-A", Date: MustParseDateOnly("2021-01-01"),
+		Scriptures: []ScriptureReference{{Book: "John", Chapter: 3, Verse: 16}, {Book: "Genesis", Chapter: 1}},
+	}
+	msgB := CatalogMessage{
+		Name: "MSG-B", Date: MustParseDateOnly("2021-01-08"),
+		Scriptures: []ScriptureReference{{Book: "John", Chapter: 3, Verse: 1}, {Book: "John", Chapter: 4}},
+	}
+	series := []CatalogSeri{
+		{Name: "SERIES-1", Messages: []CatalogMessage{msgA, msgB}},
+		{Name: "SERIES-2", Messages: []CatalogMessage{msgB}},
+	}
+
+	index := NewScriptureIndex(series)
+
+	if t.Len(index, 2) {
+		t.Equal("Genesis", index[0].Book)
+		t.Equal("genesis", index[0].Anchor())
+		t.Len(index[0].Chapters, 1)
+
+		t.Equal("John", index[1].Book)
+		if t.Len(index[1].Chapters, 2) {
+			chapter := index[1].Chapters[0]
+			t.Equal("john-3", chapter.Anchor())
+			if t.Len(chapter.Entries, 2) {
+				t.Equal("MSG-B", chapter.Entries[0].Message.Name)
+				t.Equal("SERIES-1", chapter.Entries[0].Seri.Name)
+				t.Equal("MSG-A", chapter.Entries[1].Message.Name)
+			}
+			t.Equal(4, index[1].Chapters[1].Chapter)
+		}
+	}
+
+	t.Nil(NewScriptureIndex(nil))
+}
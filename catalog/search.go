@@ -0,0 +1,166 @@
+package catalog
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+
+	"github.com/WordOfLifeMN/online/util"
+)
+
+// The transcript search index is a set of static JSON files that a search page queries in the
+// browser. The index is split into files so the browser only downloads the parts it needs:
+//
+//   - meta.json has the messages, the stop words, and the number of files of each kind
+//   - index-NN.json maps each word to the passages it is in. A word is in the file numbered
+//     by the FNV-1a hash of the word modulo the number of index files. The list of passages is
+//     delta-encoded (each number is the difference from the one before it)
+//   - passages-NNN.json has the passages, SEARCH_PASSAGES_PER_FILE per file. Each passage is
+//     [message number, start time in seconds (-1 if unknown), text]
+
+// number of files the words of the index are split into
+const SEARCH_INDEX_SHARDS = 32
+
+// number of passages in each passage file
+const SEARCH_PASSAGES_PER_FILE = 200
+
+// words that are too common to be worth indexing
+var searchStopWords = []string{
+	"a", "an", "and", "are", "as", "at", "be", "but", "by", "for", "from", "have", "he", "her",
+	"his", "i", "if", "in", "is", "it", "its", "me", "my", "of", "on", "or", "our", "she", "so",
+	"that", "the", "their", "them", "they", "this", "to", "us", "was", "we", "were", "what",
+	"with", "you", "your",
+}
+
+// SearchMessage describes a message in the search index
+type SearchMessage struct {
+	Name     string `json:"name"`            // name of the message
+	Speakers string `json:"speakers"`        // speakers as a display string
+	Date     string `json:"date"`            // date as a display string
+	Page     string `json:"page"`            // file name of the series page with the message
+	Audio    string `json:"audio,omitempty"` // URL of the audio
+}
+
+// searchPassage is a passage of a transcript in the search index
+type searchPassage struct {
+	message int     // index of the message in the messages
+	start   float64 // seconds from the start of the recording, -1 if not known
+	text    string  // what was said
+}
+
+// SearchIndex is an inverted index of transcript passages
+type SearchIndex struct {
+	Messages []SearchMessage  // all the messages with passages in the index
+	Words    map[string][]int // word to the passages that contain it, in order
+	passages []searchPassage
+}
+
+// NewSearchIndex creates an empty search index
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{Words: map[string][]int{}}
+}
+
+// TokenizeSearchText splits text into the words that are indexed. Words are lower-case without
+// punctuation or apostrophes, and single letters and stop words are removed. The search page
+// must do exactly the same thing to the query
+func TokenizeSearchText(text string) []string {
+	var tokens []string
+	for _, word := range splitWords(text) {
+		if len([]rune(word)) < 2 || slices.Contains(searchStopWords, word) {
+			continue
+		}
+		tokens = append(tokens, word)
+	}
+	return tokens
+}
+
+// GetSearchShard gets the number of the index file that has the word
+func GetSearchShard(word string) int {
+	h := fnv.New32a()
+	h.Write([]byte(word))
+	return int(h.Sum32() % SEARCH_INDEX_SHARDS)
+}
+
+// PassageCount gets the number of passages in the index
+func (idx *SearchIndex) PassageCount() int {
+	return len(idx.passages)
+}
+
+// AddMessage adds the passages of a message's transcript to the index
+func (idx *SearchIndex) AddMessage(msg SearchMessage, passages []TranscriptPassage) {
+	msgNumber := len(idx.Messages)
+	idx.Messages = append(idx.Messages, msg)
+
+	for _, passage := range passages {
+		passageNumber := len(idx.passages)
+		idx.passages = append(idx.passages, searchPassage{msgNumber, passage.Start, passage.Text})
+
+		for _, word := range TokenizeSearchText(passage.Text) {
+			list := idx.Words[word]
+			if len(list) == 0 || list[len(list)-1] != passageNumber {
+				idx.Words[word] = append(list, passageNumber)
+			}
+		}
+	}
+}
+
+// GetMetaFile gets the contents of meta.json
+func (idx *SearchIndex) GetMetaFile() any {
+	return map[string]any{
+		"messages":          idx.Messages,
+		"stop-words":        searchStopWords,
+		"index-files":       SEARCH_INDEX_SHARDS,
+		"passages-per-file": SEARCH_PASSAGES_PER_FILE,
+	}
+}
+
+// GetIndexFiles gets the contents of all the index-NN.json files, by file name
+func (idx *SearchIndex) GetIndexFiles() map[string]any {
+	shards := make([]map[string][]int, SEARCH_INDEX_SHARDS)
+	for index := range shards {
+		shards[index] = map[string][]int{}
+	}
+
+	for word, list := range idx.Words {
+		deltas := make([]int, len(list))
+		for index, passage := range list {
+			deltas[index] = passage
+			if index > 0 {
+				deltas[index] = passage - list[index-1]
+			}
+		}
+		shards[GetSearchShard(word)][word] = deltas
+	}
+
+	files := map[string]any{}
+	for index, shard := range shards {
+		files[fmt.Sprintf("index-%02d.json", index)] = shard
+	}
+	return files
+}
+
+// GetPassageFiles gets the contents of all the passages-NNN.json files, by file name
+func (idx *SearchIndex) GetPassageFiles() map[string]any {
+	files := map[string]any{}
+	for start := 0; start < len(idx.passages); start += SEARCH_PASSAGES_PER_FILE {
+		var passages [][]any
+		for _, p := range idx.passages[start:min(start+SEARCH_PASSAGES_PER_FILE, len(idx.passages))] {
+			passages = append(passages, []any{p.message, p.start, p.text})
+		}
+		files[fmt.Sprintf("passages-%03d.json", start/SEARCH_PASSAGES_PER_FILE)] = passages
+	}
+	return files
+}
+
+// GetCatalogFileNameForSearch generates the name of the HTML file of the transcript search page
+// for a ministry and view. The name has a hash to make the page name harder to guess
+func GetCatalogFileNameForSearch(ministry Ministry, view View) string {
+	return GetCatalogDirNameForSearch(ministry, view) + ".html"
+}
+
+// GetCatalogDirNameForSearch generates the name of the directory with the transcript search
+// index files for a ministry and view
+func GetCatalogDirNameForSearch(ministry Ministry, view View) string {
+	nameBase := string(ministry) + "-" + string(view)
+	return "search." + nameBase + "-" + util.ComputeHash(nameBase+"-search")
+}
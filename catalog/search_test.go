@@ -0,0 +1,78 @@
+package catalog
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// Runs the test suite as a test
+func TestSearchTestSuite(t *testing.T) {
+	suite.Run(t, new(SearchTestSuite))
+}
+
+type SearchTestSuite struct {
+	suite.Suite
+}
+
+func (t *SearchTestSuite) TestTokenize() {
+	t.Equal([]string{"dont", "worry", "happy", "12"}, TokenizeSearchText("Don't worry, be HAPPY — 12 a"))
+	t.Nil(TokenizeSearchText("the and of"))
+}
+
+func (t *SearchTestSuite) TestShard() {
+	t.Equal(23, GetSearchShard("faith"))
+	t.Equal(GetSearchShard("faith"), GetSearchShard("faith"))
+}
+
+func (t *SearchTestSuite) TestIndex() {
+	// given
+	sut := NewSearchIndex()
+
+	// when
+	sut.AddMessage(SearchMessage{Name: "MESSAGE-A"}, []TranscriptPassage{
+		{Start: 0, Text: "Faith comes by hearing"},
+		{Start: 30, Text: "and hearing by the word"},
+	})
+	sut.AddMessage(SearchMessage{Name: "MESSAGE-B"}, []TranscriptPassage{
+		{Start: -1, Text: "Faith, faith, faith"},
+	})
+
+	// then
+	t.Len(sut.Messages, 2)
+	t.Equal(3, sut.PassageCount())
+	t.Equal([]int{0, 2}, sut.Words["faith"])
+	t.Equal([]int{0, 1}, sut.Words["hearing"])
+	t.NotContains(sut.Words, "the")
+
+	files := sut.GetIndexFiles()
+	t.Len(files, SEARCH_INDEX_SHARDS)
+	t.Equal([]int{0, 2}, files["index-23.json"].(map[string][]int)["faith"])
+	t.Equal([]int{0, 1}, files[indexFileName("hearing")].(map[string][]int)["hearing"])
+
+	passages := sut.GetPassageFiles()
+	t.Len(passages, 1)
+	t.Equal([]any{1, float64(-1), "Faith, faith, faith"}, passages["passages-000.json"].([][]any)[2])
+}
+
+func (t *SearchTestSuite) TestIndex_DeltaEncoding() {
+	sut := NewSearchIndex()
+	for _, text := range []string{"grace", "other", "other", "grace", "grace"} {
+		sut.AddMessage(SearchMessage{}, []TranscriptPassage{{Text: text}})
+	}
+
+	files := sut.GetIndexFiles()
+	t.Equal([]int{0, 3, 1}, files[indexFileName("grace")].(map[string][]int)["grace"])
+}
+
+func (t *SearchTestSuite) TestFileNames() {
+	t.Regexp(`^search\.wol-public-[^.]+$`, GetCatalogDirNameForSearch(WordOfLife, Public))
+	t.Equal(GetCatalogDirNameForSearch(WordOfLife, Public)+".html", GetCatalogFileNameForSearch(WordOfLife, Public))
+	t.NotEqual(GetCatalogDirNameForSearch(WordOfLife, Public), GetCatalogDirNameForSearch(WordOfLife, Partner))
+}
+
+// indexFileName gets the name of the index file with the word
+func indexFileName(word string) string {
+	return fmt.Sprintf("index-%02d.json", GetSearchShard(word))
+}
@@ -0,0 +1,204 @@
+package catalog
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+
+	"github.com/WordOfLifeMN/online/util"
+	"gopkg.in/yaml.v3"
+)
+
+// Speaker describes one person who speaks in messages. Speakers are kept in a registry so that
+// the different ways a speaker's name is entered (in the spreadsheet, or as initials in the
+// names of recorded files) all resolve to the same display name
+type Speaker struct {
+	Name       string              `yaml:"name"`                 // canonical name, without title
+	Title      string              `yaml:"title,omitempty"`      // title displayed before the name, like "Pastor"
+	Ministries map[Ministry]string `yaml:"ministries,omitempty"` // display name to use for specific ministries
+	Aliases    []string            `yaml:"aliases,omitempty"`    // other names and abbreviations for the speaker
+	Initials   string              `yaml:"initials,omitempty"`   // letter that identifies the speaker in file names
+	Bio        string              `yaml:"bio,omitempty"`        // short biography
+	Photo      string              `yaml:"photo,omitempty"`      // URL of a photo of the speaker
+}
+
+// SpeakerRegistry is the list of well-known speakers, indexed by all the names they go by
+type SpeakerRegistry struct {
+	Speakers []Speaker `yaml:"speakers"`
+
+	byName     map[string]*Speaker // speakers by lower-case name, display name, or alias
+	byInitials map[string]*Speaker // speakers by upper-case initials
+}
+
+// default list of speakers, used if no other registry is configured
+//
+//go:embed speakers.yaml
+var defaultSpeakersYAML []byte
+
+// the registry used to resolve speaker names
+var speakerRegistry = mustParseSpeakerRegistry(defaultSpeakersYAML)
+
+// +---------------------------------------------------------------------------
+// | Constructors
+// +---------------------------------------------------------------------------
+
+// NewSpeakerRegistry creates a registry from a list of speakers. Returns an error if two
+// speakers share a name, alias, or initials
+func NewSpeakerRegistry(speakers []Speaker) (*SpeakerRegistry, error) {
+	r := &SpeakerRegistry{
+		Speakers:   speakers,
+		byName:     map[string]*Speaker{},
+		byInitials: map[string]*Speaker{},
+	}
+
+	for index := range r.Speakers {
+		speaker := &r.Speakers[index]
+		if strings.TrimSpace(speaker.Name) == "" {
+			return nil, fmt.Errorf("speaker %d has no name", index+1)
+		}
+
+		// index all the names the speaker goes by
+		names := []string{speaker.Name, speaker.DisplayName(UnknownMinistry)}
+		names = append(names, speaker.Aliases...)
+		for _, name := range speaker.Ministries {
+			names = append(names, name)
+		}
+		for _, name := range names {
+			key := strings.ToLower(strings.TrimSpace(name))
+			if existing, ok := r.byName[key]; ok && existing != speaker {
+				return nil, fmt.Errorf("speaker name '%s' is used by both %s and %s", name, existing.Name, speaker.Name)
+			}
+			r.byName[key] = speaker
+		}
+
+		// index the initials
+		if speaker.Initials != "" {
+			key := strings.ToUpper(strings.TrimSpace(speaker.Initials))
+			if existing, ok := r.byInitials[key]; ok {
+				return nil, fmt.Errorf("speaker initials '%s' are used by both %s and %s", key, existing.Name, speaker.Name)
+			}
+			r.byInitials[key] = speaker
+		}
+	}
+
+	return r, nil
+}
+
+// NewSpeakerRegistryFromYAML parses a registry from YAML. The YAML has a list of "speakers"
+func NewSpeakerRegistryFromYAML(data []byte) (*SpeakerRegistry, error) {
+	var parsed SpeakerRegistry
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("cannot parse speaker registry: %w", err)
+	}
+	return NewSpeakerRegistry(parsed.Speakers)
+}
+
+// NewSpeakerRegistryFromFile reads a registry from a YAML file
+func NewSpeakerRegistryFromFile(filePath string) (*SpeakerRegistry, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read speaker registry %s: %w", filePath, err)
+	}
+	return NewSpeakerRegistryFromYAML(data)
+}
+
+// mustParseSpeakerRegistry parses a registry from YAML and panics if it's not valid. This is only
+// for the built-in registry
+func mustParseSpeakerRegistry(data []byte) *SpeakerRegistry {
+	r, err := NewSpeakerRegistryFromYAML(data)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// GetSpeakerRegistry gets the registry currently used to resolve speaker names
+func GetSpeakerRegistry() *SpeakerRegistry {
+	return speakerRegistry
+}
+
+// SetSpeakerRegistry replaces the registry used to resolve speaker names. This should be done
+// before any catalog is initialized
+func SetSpeakerRegistry(r *SpeakerRegistry) {
+	speakerRegistry = r
+}
+
+// +---------------------------------------------------------------------------
+// | Accessors
+// +---------------------------------------------------------------------------
+
+// DisplayName gets the name of the speaker as it should be displayed for a ministry. This is
+// the title and name unless the ministry has an override
+func (s *Speaker) DisplayName(ministry Ministry) string {
+	if name, ok := s.Ministries[ministry]; ok {
+		return name
+	}
+	if s.Title == "" {
+		return s.Name
+	}
+	return s.Title + " " + s.Name
+}
+
+// Find looks up a speaker by any of the names or aliases the speaker goes by. Returns nil if
+// the name doesn't belong to a well-known speaker
+func (r *SpeakerRegistry) Find(name string) *Speaker {
+	return r.byName[strings.ToLower(strings.TrimSpace(name))]
+}
+
+// FindByInitials looks up a speaker by the initials used in file names. Returns nil if no
+// speaker has the initials
+func (r *SpeakerRegistry) FindByInitials(initials string) *Speaker {
+	return r.byInitials[strings.ToUpper(strings.TrimSpace(initials))]
+}
+
+// GetAllInitials gets the initials of all the speakers that have them, in registry order
+func (r *SpeakerRegistry) GetAllInitials() []string {
+	var initials []string
+	for index := range r.Speakers {
+		if r.Speakers[index].Initials != "" {
+			initials = append(initials, strings.ToUpper(r.Speakers[index].Initials))
+		}
+	}
+	return initials
+}
+
+// NormalizeName converts any name a speaker goes by to the display name for the ministry. If
+// the name is not a well-known speaker, then it is returned trimmed but otherwise unchanged
+func (r *SpeakerRegistry) NormalizeName(name string, ministry Ministry) string {
+	name = strings.TrimSpace(name)
+	if speaker := r.Find(name); speaker != nil {
+		return speaker.DisplayName(ministry)
+	}
+	return name
+}
+
+// +---------------------------------------------------------------------------
+// | Speaker pages
+// +---------------------------------------------------------------------------
+
+// GetCatalogFileNameForSpeaker generates the name of the HTML file for the page that lists all
+// the messages of a speaker in a ministry and view. The name includes a hash to make the page
+// name harder to guess
+func GetCatalogFileNameForSpeaker(ministry Ministry, view View, speaker string) string {
+	nameBase := string(ministry) + "-" + string(view)
+	return "speaker." + nameBase + "-" + util.ComputeHash(nameBase+"-"+strings.ToLower(speaker)) + ".html"
+}
+
+// getSpeakerLinks generates HTML for a list of speakers where each speaker links to their page
+// for the ministry and view. If the view doesn't have speaker pages (raw or unknown), then the
+// names are not linked
+func getSpeakerLinks(speakers []string, ministry Ministry, view View) template.HTML {
+	linked := view == Public || view == Partner || view == Private
+
+	links := make([]string, 0, len(speakers))
+	for _, speaker := range speakers {
+		name := template.HTMLEscapeString(speaker)
+		if linked {
+			name = fmt.Sprintf(`<a href="%s">%s</a>`, GetCatalogFileNameForSpeaker(ministry, view, speaker), name)
+		}
+		links = append(links, name)
+	}
+	return template.HTML(strings.Join(links, ", "))
+}
@@ -0,0 +1,166 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// Runs the test suite as a test
+func TestSpeakerTestSuite(t *testing.T) {
+	suite.Run(t, new(SpeakerTestSuite))
+}
+
+type SpeakerTestSuite struct {
+	suite.Suite
+}
+
+// +---------------------------------------------------------------------------
+// | Constructors
+// +---------------------------------------------------------------------------
+
+func (t *SpeakerTestSuite) TestDefaultRegistry() {
+	sut := GetSpeakerRegistry()
+	t.NotEmpty(sut.Speakers)
+	t.Equal([]string{"V", "M", "J", "I", "T", "A"}, sut.GetAllInitials())
+}
+
+func (t *SpeakerTestSuite) TestRegistryFromYAML() {
+	// given
+	data := []byte(`
+speakers:
+  - name: Sven Olson
+    title: Elder
+    aliases: [sven, so]
+    initials: s
+    bio: Likes fishing
+    photo: https://example.com/sven.jpg
+`)
+
+	// when
+	sut, err := NewSpeakerRegistryFromYAML(data)
+
+	// then
+	t.Require().NoError(err)
+	t.Require().Len(sut.Speakers, 1)
+	t.Equal("Likes fishing", sut.Speakers[0].Bio)
+	t.Equal("https://example.com/sven.jpg", sut.Speakers[0].Photo)
+	t.Equal("Elder Sven Olson", sut.NormalizeName("SO", WordOfLife))
+	t.Equal("Elder Sven Olson", sut.FindByInitials("S").DisplayName(WordOfLife))
+	t.Equal("Vern", sut.NormalizeName(" Vern ", WordOfLife))
+}
+
+func (t *SpeakerTestSuite) TestRegistryFromFile() {
+	// given
+	filePath := filepath.Join(t.T().TempDir(), "speakers.yaml")
+	t.Require().NoError(os.WriteFile(filePath, []byte("speakers:\n  - name: Sven Olson\n"), 0644))
+
+	// when
+	sut, err := NewSpeakerRegistryFromFile(filePath)
+
+	// then
+	t.Require().NoError(err)
+	t.NotNil(sut.Find("sven olson"))
+
+	// when file doesn't exist
+	_, err = NewSpeakerRegistryFromFile(filePath + ".missing")
+	t.Error(err)
+}
+
+func (t *SpeakerTestSuite) TestRegistry_DuplicateAlias() {
+	_, err := NewSpeakerRegistry([]Speaker{
+		{Name: "Sven Olson", Aliases: []string{"so"}},
+		{Name: "Sally Ortiz", Aliases: []string{"SO"}},
+	})
+	t.Error(err)
+}
+
+func (t *SpeakerTestSuite) TestRegistry_DuplicateInitials() {
+	_, err := NewSpeakerRegistry([]Speaker{
+		{Name: "Sven Olson", Initials: "S"},
+		{Name: "Sally Ortiz", Initials: "s"},
+	})
+	t.Error(err)
+}
+
+func (t *SpeakerTestSuite) TestRegistry_NoName() {
+	_, err := NewSpeakerRegistry([]Speaker{{Title: "Pastor"}})
+	t.Error(err)
+}
+
+// +---------------------------------------------------------------------------
+// | Accessors
+// +---------------------------------------------------------------------------
+
+func (t *SpeakerTestSuite) TestDisplayName() {
+	sut := Speaker{
+		Name:       "Mary Peltz",
+		Title:      "Pastor",
+		Ministries: map[Ministry]string{CenterOfRelationshipExperience: "Mary Peltz"},
+	}
+	t.Equal("Pastor Mary Peltz", sut.DisplayName(WordOfLife))
+	t.Equal("Mary Peltz", sut.DisplayName(CenterOfRelationshipExperience))
+
+	sut = Speaker{Name: "Anthony Leong"}
+	t.Equal("Anthony Leong", sut.DisplayName(WordOfLife))
+}
+
+func (t *SpeakerTestSuite) TestFind() {
+	sut := GetSpeakerRegistry()
+
+	// all names resolve to the same speaker
+	vern := sut.Find("vp")
+	t.Require().NotNil(vern)
+	t.Same(vern, sut.Find("Vern Peltz"))
+	t.Same(vern, sut.Find("Pastor Vern Peltz"))
+	t.Same(vern, sut.Find(" PASTOR VERN "))
+	t.Same(vern, sut.FindByInitials("v"))
+
+	// unknown names
+	t.Nil(sut.Find("Sven"))
+	t.Nil(sut.Find(""))
+	t.Nil(sut.FindByInitials("X"))
+}
+
+// +---------------------------------------------------------------------------
+// | Speaker pages
+// +---------------------------------------------------------------------------
+
+func (t *SpeakerTestSuite) TestSpeakerFileName() {
+	public := GetCatalogFileNameForSpeaker(WordOfLife, Public, "Pastor Vern Peltz")
+	t.Regexp(`^speaker\.wol-public-.+\.html$`, public)
+	t.Equal(public, GetCatalogFileNameForSpeaker(WordOfLife, Public, "PASTOR VERN PELTZ"))
+	t.NotEqual(public, GetCatalogFileNameForSpeaker(WordOfLife, Partner, "Pastor Vern Peltz"))
+	t.NotEqual(public, GetCatalogFileNameForSpeaker(CenterOfRelationshipExperience, Public, "Pastor Vern Peltz"))
+	t.NotEqual(public, GetCatalogFileNameForSpeaker(WordOfLife, Public, "Pastor Mary Peltz"))
+}
+
+func (t *SpeakerTestSuite) TestSpeakerLinks() {
+	// given
+	msg := CatalogMessage{
+		Ministry:   WordOfLife,
+		Visibility: Public,
+		Speakers:   []string{"Pastor Vern Peltz", "Sven & Ollie"},
+	}
+
+	// when
+	links := string(msg.SpeakerLinks(WordOfLife, Public))
+
+	// then
+	t.Contains(links, `<a href="`+GetCatalogFileNameForSpeaker(WordOfLife, Public, "Pastor Vern Peltz")+`">Pastor Vern Peltz</a>, `)
+	t.Contains(links, `>Sven &amp; Ollie</a>`)
+
+	// when the message is listed on a page of another ministry and view
+	links = string(msg.SpeakerLinks(CenterOfRelationshipExperience, Partner))
+
+	// then the links are for that page
+	t.Contains(links, `<a href="`+GetCatalogFileNameForSpeaker(CenterOfRelationshipExperience, Partner, "Pastor Vern Peltz")+`">`)
+
+	// when the page isn't in a view with speaker pages
+	links = string(msg.SpeakerLinks(WordOfLife, Raw))
+
+	// then
+	t.Equal("Pastor Vern Peltz, Sven &amp; Ollie", links)
+}
@@ -0,0 +1,72 @@
+package catalog
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/WordOfLifeMN/online/util"
+)
+
+// Tags are topics (like "healing" or "marriage") that messages are about. Tags are compared
+// without regard to case, so "Healing" and "healing" are the same tag
+
+// NewTagsFromString parses a list of tags separated by semicolons. Returns nil if there are no
+// tags
+func NewTagsFromString(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ";") {
+		tags = addTag(tags, tag)
+	}
+	return tags
+}
+
+// addTag adds a tag to a list of tags if it isn't blank or already in the list
+func addTag(tags []string, tag string) []string {
+	tag = strings.Join(strings.Fields(tag), " ")
+	if tag == "" || hasTag(tags, tag) {
+		return tags
+	}
+	return append(tags, tag)
+}
+
+// hasTag determines if the tag is in the list of tags
+func hasTag(tags []string, tag string) bool {
+	for _, existing := range tags {
+		if strings.EqualFold(existing, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCatalogFileNameForTag generates the name of the HTML file for the page of a tag in the
+// catalog of a ministry and view. The name has a hash to make the page name harder to guess
+func GetCatalogFileNameForTag(ministry Ministry, view View, tag string) string {
+	nameBase := string(ministry) + "-" + string(view)
+	return "tag." + nameBase + "-" + util.ComputeHash(nameBase+"-"+strings.ToLower(tag)) + ".html"
+}
+
+// GetCatalogFileNameForTags generates the name of the HTML file for the page with all the tags
+// in the catalog of a ministry and view (the tag cloud)
+func GetCatalogFileNameForTags(ministry Ministry, view View) string {
+	nameBase := string(ministry) + "-" + string(view)
+	return "tags." + nameBase + "-" + util.ComputeHash(nameBase+"-tags") + ".html"
+}
+
+// getTagLinks generates HTML for a list of tags where each tag links to its page for the
+// ministry and view. If the view doesn't have tag pages (raw or unknown), then the tags are not
+// linked
+func getTagLinks(tags []string, ministry Ministry, view View) template.HTML {
+	linked := view == Public || view == Partner || view == Private
+
+	links := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		name := template.HTMLEscapeString(tag)
+		if linked {
+			name = fmt.Sprintf(`<a href="%s">%s</a>`, GetCatalogFileNameForTag(ministry, view, tag), name)
+		}
+		links = append(links, name)
+	}
+	return template.HTML(strings.Join(links, ", "))
+}
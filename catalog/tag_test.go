@@ -0,0 +1,55 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// Runs the test suite as a test
+func TestTagTestSuite(t *testing.T) {
+	suite.Run(t, new(TagTestSuite))
+}
+
+type TagTestSuite struct {
+	suite.Suite
+}
+
+func (t *TagTestSuite) TestNewTagsFromString() {
+	t.Nil(NewTagsFromString(""))
+	t.Nil(NewTagsFromString(" ; ;"))
+	t.Equal([]string{"healing"}, NewTagsFromString("healing"))
+	t.Equal([]string{"healing", "Marriage", "spiritual warfare"},
+		NewTagsFromString("healing; Marriage;spiritual   warfare; Healing;"))
+}
+
+func (t *TagTestSuite) TestFileNames() {
+	t.Equal(
+		GetCatalogFileNameForTag(WordOfLife, Public, "Healing"),
+		GetCatalogFileNameForTag(WordOfLife, Public, "healing"))
+	t.NotEqual(
+		GetCatalogFileNameForTag(WordOfLife, Public, "healing"),
+		GetCatalogFileNameForTag(WordOfLife, Partner, "healing"))
+	t.Regexp(`^tag\.wol-public-.+\.html$`, GetCatalogFileNameForTag(WordOfLife, Public, "healing"))
+	t.Regexp(`^tags\.wol-public-.+\.html$`, GetCatalogFileNameForTags(WordOfLife, Public))
+}
+
+func (t *TagTestSuite) TestTagLinks() {
+	msg := CatalogMessage{Ministry: WordOfLife, Visibility: Public, Tags: []string{"healing", "faith & works"}}
+	t.Equal(
+		`<a href="`+GetCatalogFileNameForTag(WordOfLife, Public, "healing")+`">healing</a>, `+
+			`<a href="`+GetCatalogFileNameForTag(WordOfLife, Public, "faith & works")+`">faith &amp; works</a>`,
+		string(msg.TagLinks(WordOfLife, Public)))
+
+	// the links are for the page the message is listed on, not the message
+	t.Contains(string(msg.TagLinks(CenterOfRelationshipExperience, Partner)),
+		`<a href="`+GetCatalogFileNameForTag(CenterOfRelationshipExperience, Partner, "healing")+`">healing</a>`)
+
+	t.Equal("healing, faith &amp; works", string(msg.TagLinks(WordOfLife, Raw)))
+}
+
+func (t *TagTestSuite) TestHasTag() {
+	msg := CatalogMessage{Tags: []string{"Healing"}}
+	t.True(msg.HasTag("healing"))
+	t.False(msg.HasTag("marriage"))
+}
@@ -0,0 +1,310 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/WordOfLifeMN/online/storage"
+)
+
+// Transcripts are stored next to the audio, in an "xscript" directory. The plain text transcript
+// has the extension .text, and the captions (with timestamps) have the extension .vtt. They can
+// be downloaded, or read from a local copy of the audio bucket
+
+// directory with a local copy of the audio bucket, "" to download the transcripts
+var transcriptDir string
+
+// where the audio files and their transcripts are stored, which is opened the first time it is
+// needed. the keys of the transcripts are <year>/xscript/<file name>
+var openAudioStorage = func() (storage.Storage, error) {
+	return storage.Open("s3://wordoflife.mn.audio", storage.Options{})
+}
+var audioStorage storage.Storage
+var audioStorageErr error
+var audioStorageOnce sync.Once
+
+// transcripts that were already read, by URL
+var transcriptTextCache = map[string]string{}
+var transcriptTextCacheMu sync.Mutex
+
+// SetTranscriptDir sets a directory to read transcripts from instead of downloading them. The
+// directory is a local copy of the audio bucket, so transcripts are in <year>/xscript/. Use ""
+// to download the transcripts
+func SetTranscriptDir(dir string) {
+	transcriptDir = dir
+}
+
+// SetAudioStorage sets how to open the storage with the audio files and their transcripts, which
+// is used to find which messages have transcripts. The storage isn't opened until it is needed,
+// so nothing needs credentials for it unless it is used
+func SetAudioStorage(open func() (storage.Storage, error)) {
+	openAudioStorage = open
+	audioStorageOnce = sync.Once{}
+}
+
+// getAudioStorage gets the storage with the audio files and their transcripts
+func getAudioStorage() (storage.Storage, error) {
+	audioStorageOnce.Do(func() {
+		audioStorage, audioStorageErr = openAudioStorage()
+	})
+	return audioStorage, audioStorageErr
+}
+
+// listTranscriptNames gets the names of all the plain text transcripts of a year in the audio
+// storage, without the extension
+func listTranscriptNames(ctx context.Context, year int) ([]string, error) {
+	store, err := getAudioStorage()
+	if err != nil {
+		return nil, err
+	}
+	prefix := fmt.Sprintf("%d/xscript/", year)
+	objects, err := store.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, object := range objects {
+		name := strings.TrimPrefix(object.Key, prefix)
+		if strings.Contains(name, "/") || !strings.HasSuffix(name, ".text") {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(name, ".text"))
+	}
+	return names, nil
+}
+
+// getTranscriptFilePath gets the path of a transcript in the transcript directory from its URL,
+// which is the last three parts of the URL path (year, "xscript", and file name)
+func getTranscriptFilePath(xscriptURL string) string {
+	u, err := url.Parse(xscriptURL)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(strings.ReplaceAll(u.Path, "+", " "), "/")
+	if len(parts) < 3 {
+		return ""
+	}
+	return filepath.Join(append([]string{transcriptDir}, parts[len(parts)-3:]...)...)
+}
+
+// GetTranscript gets the contents of a transcript of the message. The extension is ".text" for
+// the plain text transcript or ".vtt" for the captions. The transcript is read from the
+// transcript directory if there is one, otherwise it is downloaded. Transcripts are cached, so
+// asking for the same one again doesn't read it again
+func (m *CatalogMessage) GetTranscript(ext string) (string, error) {
+	xscriptURL := m.GetTranscriptURL(ext)
+	if xscriptURL == "" {
+		return "", fmt.Errorf("message '%s' has no transcript", m.Name)
+	}
+
+	transcriptTextCacheMu.Lock()
+	text, ok := transcriptTextCache[xscriptURL]
+	transcriptTextCacheMu.Unlock()
+	if ok {
+		return text, nil
+	}
+
+	var data []byte
+	var err error
+	if transcriptDir != "" {
+		data, err = os.ReadFile(getTranscriptFilePath(xscriptURL))
+		if err != nil {
+			return "", fmt.Errorf("cannot read transcript of '%s': %w", m.Name, err)
+		}
+	} else {
+		data, err = downloadTranscript(xscriptURL)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	transcriptTextCacheMu.Lock()
+	transcriptTextCache[xscriptURL] = string(data)
+	transcriptTextCacheMu.Unlock()
+	return string(data), nil
+}
+
+// downloadTranscript gets the contents of a transcript from its URL
+func downloadTranscript(xscriptURL string) ([]byte, error) {
+	resp, err := http.Get(xscriptURL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get transcript %s: %w", xscriptURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unsuccessful status code getting transcript %s: %d", xscriptURL, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read transcript %s: %w", xscriptURL, err)
+	}
+	return data, nil
+}
+
+// +---------------------------------------------------------------------------
+// | Captions
+// +---------------------------------------------------------------------------
+
+// TranscriptCue is one caption from a .vtt transcript
+type TranscriptCue struct {
+	Start float64 // seconds from the start of the recording
+	Text  string  // what was said
+}
+
+// ParseVTT reads the cues from a WebVTT file. Cue settings, notes, and styles are ignored, and
+// any tags in the text (like <v Speaker>) are removed
+func ParseVTT(vtt string) []TranscriptCue {
+	var cues []TranscriptCue
+
+	blocks := strings.Split(strings.ReplaceAll(vtt, "\r\n", "\n"), "\n\n")
+	for _, block := range blocks {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+
+		// find the timing line, which might follow a cue identifier
+		timing := -1
+		for index, line := range lines {
+			if strings.Contains(line, "-->") {
+				timing = index
+				break
+			}
+		}
+		if timing < 0 {
+			continue
+		}
+
+		start, ok := parseVTTTimestamp(strings.TrimSpace(strings.Split(lines[timing], "-->")[0]))
+		if !ok {
+			continue
+		}
+		text := stripVTTTags(strings.Join(lines[timing+1:], " "))
+		if text == "" {
+			continue
+		}
+		cues = append(cues, TranscriptCue{Start: start, Text: text})
+	}
+
+	return cues
+}
+
+// parseVTTTimestamp converts a timestamp like "01:02:03.456" or "02:03.456" into seconds
+func parseVTTTimestamp(s string) (float64, bool) {
+	seconds := 0.0
+	for _, part := range strings.Split(s, ":") {
+		value, err := strconv.ParseFloat(strings.ReplaceAll(part, ",", "."), 64)
+		if err != nil {
+			return 0, false
+		}
+		seconds = seconds*60 + value
+	}
+	return seconds, true
+}
+
+// stripVTTTags removes tags like <v Speaker> and <i> from caption text
+func stripVTTTags(s string) string {
+	var sb strings.Builder
+	inTag := false
+	for _, r := range s {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>':
+			inTag = false
+		case !inTag:
+			sb.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// +---------------------------------------------------------------------------
+// | Passages
+// +---------------------------------------------------------------------------
+
+// TranscriptPassage is a short section of a transcript, which is what the search finds
+type TranscriptPassage struct {
+	Start float64 // seconds from the start of the recording, -1 if not known
+	Text  string  // what was said
+}
+
+// number of words in a transcript passage
+const TRANSCRIPT_PASSAGE_WORDS = 60
+
+// SplitTranscript splits a plain text transcript into passages of about
+// TRANSCRIPT_PASSAGE_WORDS words, breaking at the end of sentences where it can. If there are
+// captions, each passage gets the time of the caption where it starts. Otherwise the time is -1
+func SplitTranscript(text string, cues []TranscriptCue) []TranscriptPassage {
+	var passages []TranscriptPassage
+
+	// split the text into passages
+	words := strings.Fields(text)
+	for len(words) > 0 {
+		end := min(len(words), TRANSCRIPT_PASSAGE_WORDS)
+		for index := end; index < len(words) && index < end+TRANSCRIPT_PASSAGE_WORDS/2; index++ {
+			if strings.ContainsAny(words[index-1][len(words[index-1])-1:], ".?!") {
+				end = index
+				break
+			}
+		}
+		passages = append(passages, TranscriptPassage{Start: -1, Text: strings.Join(words[:end], " ")})
+		words = words[end:]
+	}
+
+	// find when each passage starts by finding its first words in the captions. the search for
+	// the next passage starts a few words before the end of this one, in case the captions and
+	// the transcript don't have exactly the same words
+	type timedToken struct {
+		token string
+		start float64
+	}
+	var captionTokens []timedToken
+	for _, cue := range cues {
+		for _, token := range splitWords(cue.Text) {
+			captionTokens = append(captionTokens, timedToken{token, cue.Start})
+		}
+	}
+	cursor := 0
+	for index := range passages {
+		allTokens := splitWords(passages[index].Text)
+		if len(allTokens) == 0 {
+			continue
+		}
+		tokens := allTokens[:min(len(allTokens), 6)]
+
+	search:
+		for position := cursor; position+len(tokens) <= len(captionTokens); position++ {
+			for offset, token := range tokens {
+				if captionTokens[position+offset].token != token {
+					continue search
+				}
+			}
+			passages[index].Start = captionTokens[position].start
+			cursor = position + max(len(tokens), len(allTokens)-len(tokens))
+			break
+		}
+		if passages[index].Start < 0 && index > 0 {
+			passages[index].Start = passages[index-1].Start
+		}
+	}
+
+	return passages
+}
+
+// splitWords splits text into lower-case words without punctuation. Apostrophes are removed, so
+// "Don't" is "dont"
+func splitWords(text string) []string {
+	text = strings.NewReplacer("'", "", "’", "").Replace(strings.ToLower(text))
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
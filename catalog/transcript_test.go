@@ -0,0 +1,130 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/WordOfLifeMN/online/storage"
+	"github.com/stretchr/testify/suite"
+)
+
+// Runs the test suite as a test
+func TestTranscriptTestSuite(t *testing.T) {
+	suite.Run(t, new(TranscriptTestSuite))
+}
+
+type TranscriptTestSuite struct {
+	suite.Suite
+}
+
+func (t *TranscriptTestSuite) TestGetTranscript_FromDir() {
+	// given
+	dir := t.T().TempDir()
+	SetTranscriptDir(dir)
+	defer SetTranscriptDir("")
+
+	t.NoError(os.MkdirAll(filepath.Join(dir, "2020", "xscript"), 0777))
+	t.NoError(os.WriteFile(filepath.Join(dir, "2020", "xscript", "2020-10-11 Fear, Part 1.text"), []byte("TRANSCRIPT"), 0666))
+
+	msg := CatalogMessage{
+		Name:  "MESSAGE",
+		Audio: NewResourceFromString("https://s3-us-west-2.amazonaws.com/wordoflife.mn.audio/2020/2020-10-11+Fear%2C+Part+1.mp3"),
+	}
+
+	// then
+	t.True(msg.HasTranscript())
+	text, err := msg.GetTranscript(".text")
+	t.NoError(err)
+	t.Equal("TRANSCRIPT", text)
+
+	_, err = msg.GetTranscript(".vtt")
+	t.Error(err)
+}
+
+func (t *TranscriptTestSuite) TestLoadTranscriptCacheForYear() {
+	// given
+	dir := t.T().TempDir()
+	defaultStorage := openAudioStorage
+	SetAudioStorage(func() (storage.Storage, error) { return storage.Open(dir, storage.Options{}) })
+	defer SetAudioStorage(defaultStorage)
+
+	for _, name := range []string{"2020-10-11 Fear, Part 1.text", "2020-10-11 Fear, Part 1.vtt", "2020-10-18 Faith.text", "old/2020-01-05 Hope.text"} {
+		filePath := filepath.Join(dir, "2020", "xscript", filepath.FromSlash(name))
+		t.NoError(os.MkdirAll(filepath.Dir(filePath), 0777))
+		t.NoError(os.WriteFile(filePath, []byte("TRANSCRIPT"), 0666))
+	}
+	t.NoError(os.WriteFile(filepath.Join(dir, "2020", "2020-10-11 Fear, Part 1.mp3"), []byte("MP3"), 0666))
+
+	// then
+	msg := CatalogMessage{}
+	t.Equal([]string{"2020-10-11 Fear, Part 1", "2020-10-18 Faith"}, msg.LoadTranscriptCacheForYear(2020))
+	t.Empty(msg.LoadTranscriptCacheForYear(2021))
+}
+
+func (t *TranscriptTestSuite) TestGetTranscript_NoAudio() {
+	msg := CatalogMessage{Name: "MESSAGE"}
+	_, err := msg.GetTranscript(".text")
+	t.Error(err)
+}
+
+func (t *TranscriptTestSuite) TestParseVTT() {
+	cues := ParseVTT("WEBVTT\r\n\r\nNOTE a comment\r\n\r\n1\r\n00:00:01.500 --> 00:00:04.000\r\n<v Vern>Good morning,</v>\r\neveryone\r\n\r\n" +
+		"01:02:03.000 --> 01:02:05.000 align:start\r\nAmen\r\n\r\n00:05.000 --> 00:06.000\r\n\r\n")
+
+	t.Equal([]TranscriptCue{
+		{Start: 1.5, Text: "Good morning, everyone"},
+		{Start: 3723, Text: "Amen"},
+	}, cues)
+
+	t.Nil(ParseVTT(""))
+}
+
+func (t *TranscriptTestSuite) TestSplitTranscript_NoCaptions() {
+	text := ""
+	for i := 0; i < 100; i++ {
+		text += "word "
+	}
+	text += "end."
+
+	passages := SplitTranscript(text, nil)
+	if t.Len(passages, 2) {
+		t.Equal(float64(-1), passages[0].Start)
+		t.Equal(TRANSCRIPT_PASSAGE_WORDS, len(splitWords(passages[0].Text)))
+		t.Equal(float64(-1), passages[1].Start)
+	}
+
+	t.Nil(SplitTranscript("", nil))
+}
+
+func (t *TranscriptTestSuite) TestSplitTranscript_BreaksAtSentence() {
+	text := ""
+	for i := 0; i < TRANSCRIPT_PASSAGE_WORDS+5; i++ {
+		text += "word "
+	}
+	text += "end. Next sentence."
+
+	passages := SplitTranscript(text, nil)
+	if t.Len(passages, 2) {
+		t.Equal("Next sentence.", passages[1].Text)
+	}
+}
+
+func (t *TranscriptTestSuite) TestSplitTranscript_Captions() {
+	sentence := "Welcome everyone, today we talk about faith. "
+	text := ""
+	for i := 0; i < 20; i++ {
+		text += sentence
+	}
+	cues := []TranscriptCue{}
+	for i := 0; i < 20; i++ {
+		cues = append(cues, TranscriptCue{Start: float64(10 * i), Text: sentence})
+	}
+
+	passages := SplitTranscript(text, cues)
+	if t.Len(passages, 3) {
+		t.Equal(float64(0), passages[0].Start)
+		t.Equal(float64(90), passages[1].Start)
+		t.Equal(float64(180), passages[2].Start)
+	}
+}
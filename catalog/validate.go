@@ -4,6 +4,7 @@ package catalog
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 
@@ -47,6 +48,9 @@ var possibleAudioVideoStates = []string{
 //  - Series names are unique
 //  - Message names are unique
 //  - Message names not in a series are unique wrt Series names
+//
+// Speakers that aren't in the speaker registry are reported, but don't make the catalog invalid
+// because guest speakers are allowed
 func (c *Catalog) IsValid(reportLoud bool) bool {
 	var report *util.IndentingReport
 	if reportLoud {
@@ -74,6 +78,9 @@ func (c *Catalog) IsValid(reportLoud bool) bool {
 	// valid = c.IsMessageNamesValid(report) && valid
 	// valid = c.IsSeriesAndMessageNamesValid(report) && valid
 
+	// warnings
+	c.ReportUnknownSpeakers(report)
+
 	return valid
 }
 
@@ -171,6 +178,36 @@ func (c *Catalog) IsSeriesNamesValid(report *util.IndentingReport) bool {
 	return valid
 }
 
+// ReportUnknownSpeakers reports all the speaker names used in messages that don't resolve to a
+// speaker in the speaker registry. Returns the unknown names, sorted
+func (c *Catalog) ReportUnknownSpeakers(report *util.IndentingReport) []string {
+	report.StartSection("Speaker Checks")
+	defer report.StopSection()
+
+	// count the messages for each unknown speaker
+	counts := map[string]int{}
+	for _, msg := range c.Messages {
+		for _, speaker := range msg.Speakers {
+			speaker = strings.TrimSpace(speaker)
+			if speaker == "" || speakerRegistry.Find(speaker) != nil {
+				continue
+			}
+			counts[speaker]++
+		}
+	}
+
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		report.Printf("Speaker '%s' is not in the speaker registry (%d messages)", name, counts[name])
+	}
+	return names
+}
+
 // // Verifies that all message names are unique within a ministry
 // func (c *Catalog) IsMessageNamesValid(report *ValidationReport) bool {
 // report.StartSection("Message Name Checks")
@@ -249,6 +286,16 @@ func (s *CatalogSeri) IsValid(report *util.IndentingReport) bool {
 		valid = false
 	}
 
+	// ministries
+	if s.Ministry == UnknownMinistry {
+		report.Printf("Unknown ministry")
+		valid = false
+	}
+	if slices.Contains(s.AlsoIn, UnknownMinistry) {
+		report.Printf("Also in an unknown ministry")
+		valid = false
+	}
+
 	// booklets
 	for _, booklet := range s.Booklets {
 		valid = booklet.IsValid(report) && valid
@@ -289,6 +336,10 @@ func (m *CatalogMessage) IsValid(report *util.IndentingReport) bool {
 		report.Printf("Unknown ministry '%s'", string(m.Ministry))
 		valid = false
 	}
+	if slices.Contains(m.AlsoIn, UnknownMinistry) {
+		report.Printf("Also in an unknown ministry")
+		valid = false
+	}
 
 	// visibility
 	if m.Visibility == "" {
@@ -215,6 +215,20 @@ func (t *ValidateTestSuite) TestSeriBooklet() {
 // | Catalog
 // +---------------------------------------------------------------------------
 
+func (t *ValidateTestSuite) TestReportUnknownSpeakers() {
+	// given
+	sut := Catalog{
+		Messages: []CatalogMessage{
+			{Name: "MESSAGE-A", Speakers: []string{"vp", "Guest Speaker"}},
+			{Name: "MESSAGE-B", Speakers: []string{"Pastor Mary Peltz", " guest speaker "}},
+			{Name: "MESSAGE-C", Speakers: []string{"Another Guest", "Guest Speaker"}},
+		},
+	}
+
+	// then
+	t.Equal([]string{"Another Guest", "Guest Speaker", "guest speaker"}, sut.ReportUnknownSpeakers(t.Report))
+}
+
 func (t *ValidateTestSuite) TestValidateMessageSeries() {
 	// given - empty message list
 	sut := Catalog{
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -9,6 +10,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/WordOfLifeMN/online/storage"
 	"github.com/WordOfLifeMN/online/util"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -39,10 +42,12 @@ var audioCmd = &cobra.Command{
 	Long: `Process audio from a service message.
 
 Given one or more video files, will do the following for each file:
-1. Extract the audio and save it as *.mp3
-2. Upload the audio to s3://wordoflife.mn.audio/year
-3. Transcribe the audio with Whisper to xscript/*.txt
-4. Send the transcript to ChatGPT to get a suggested title and summary`,
+1. Extract the audio without the intro, check its quality, save it as *.mp3,
+   and tag it. With --normalize, its loudness is normalized first
+2. Upload the audio to s3://wordoflife.mn.audio/year (or --audio-storage)
+3. Transcribe the audio with Whisper to xscript/*.txt and upload it
+4. Send the transcript to ChatGPT to get a suggested title and summary, and add
+   the summary to the tags of the audio`,
 	RunE: audio,
 }
 
@@ -51,6 +56,13 @@ func init() {
 
 	rootCmd.PersistentFlags().String("speaker", "", "Name of the speaker")
 	viper.BindPFlag("speaker", rootCmd.PersistentFlags().Lookup("speaker"))
+
+	audioCmd.PersistentFlags().Float64("trim", -1, "Seconds of intro to trim from the start of recordings, or -1 to find the end of the intro")
+	viper.BindPFlag("audio-trim", audioCmd.PersistentFlags().Lookup("trim"))
+	audioCmd.PersistentFlags().Bool("normalize", false, "Normalize the loudness of extracted audio to the level of the ministry, which takes an extra pass over the recording")
+	viper.BindPFlag("audio-normalize", audioCmd.PersistentFlags().Lookup("normalize"))
+	audioCmd.PersistentFlags().Bool("check", true, "Check extracted audio for clipping and silence before uploading it")
+	viper.BindPFlag("audio-check", audioCmd.PersistentFlags().Lookup("check"))
 }
 
 func audio(cmd *cobra.Command, args []string) error {
@@ -58,6 +70,11 @@ func audio(cmd *cobra.Command, args []string) error {
 
 	var infos []*MessageInfo
 
+	store, err := getAudioStorage()
+	if err != nil {
+		return err
+	}
+
 	if len(args) == 0 {
 		// prompt user for video files until there are no more
 		for {
@@ -74,7 +91,7 @@ func audio(cmd *cobra.Command, args []string) error {
 
 			info := MessageInfo{
 				VideoPath:   videoPath,
-				SpeakerName: viper.GetString("speaker"),
+				SpeakerName: resolveSpeakerName(viper.GetString("speaker")),
 			}
 			if info.SpeakerName == "" {
 				info.SpeakerName = getSpeakerFromFileName(videoPath)
@@ -89,7 +106,7 @@ func audio(cmd *cobra.Command, args []string) error {
 
 			info := MessageInfo{
 				VideoPath:   arg,
-				SpeakerName: viper.GetString("speaker"),
+				SpeakerName: resolveSpeakerName(viper.GetString("speaker")),
 			}
 			if info.SpeakerName == "" {
 				info.SpeakerName = getSpeakerFromFileName(arg)
@@ -99,8 +116,8 @@ func audio(cmd *cobra.Command, args []string) error {
 	}
 
 	// process all the video files
-	//	err := processAllVideosSequentially(infos)
-	err := processAllVideosInEditingPriority(infos)
+	//	err = processAllVideosSequentially(store, infos)
+	err = processAllVideosInEditingPriority(store, infos)
 
 	// output the results of all processing
 	for index, info := range infos {
@@ -112,10 +129,10 @@ func audio(cmd *cobra.Command, args []string) error {
 
 // processAllVideosSequentially processes each video sequentially,
 // updating the message information records as it goes
-func processAllVideosSequentially(infos []*MessageInfo) error {
+func processAllVideosSequentially(store storage.Storage, infos []*MessageInfo) error {
 	var errs []error
 	for _, info := range infos {
-		if err := processOneAudio(info); err != nil {
+		if err := processOneAudio(store, info); err != nil {
 			errs = append(errs, err)
 		}
 	}
@@ -129,7 +146,7 @@ func processAllVideosSequentially(infos []*MessageInfo) error {
 // If the transcript doesn't exist, will transcribe the audio.
 // Will send the audio transcript to ChatGPT.
 // All information will be recorded in the passed in info record.
-func processOneAudio(info *MessageInfo) error {
+func processOneAudio(store storage.Storage, info *MessageInfo) error {
 	var err error
 
 	if info.VideoPath == "" {
@@ -140,15 +157,15 @@ func processOneAudio(info *MessageInfo) error {
 	info.AudioPath = getAudioPathFromVideoPath(info.VideoPath)
 	if !util.IsFile(info.AudioPath) {
 		info.ExtractTime = util.NewStopWatch()
-		info.AudioPath, err = extractAudioFromVideo(info.VideoPath)
+		info.AudioPath, err = extractAudioFromVideo(info.VideoPath, info.SpeakerName)
 		info.ExtractTime.Stop()
 		if err != nil {
 			return err
 		}
 
-		// upload the audio to S3
+		// upload the audio to storage
 		info.UploadTime = util.NewStopWatch()
-		info.AudioURL, err = uploadAudioToS3(info.AudioPath)
+		info.AudioURL, err = uploadAudio(store, info.AudioPath)
 		info.UploadTime.Stop()
 		if err != nil {
 			return err
@@ -179,6 +196,10 @@ func processOneAudio(info *MessageInfo) error {
 	}
 	info.SummaryTime.Stop()
 
+	if err := tagAudioWithSummary(store, info); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -186,7 +207,7 @@ func processOneAudio(info *MessageInfo) error {
 // use of editing time. It first does all the extraction and uploading, outputting the
 // relevant links, then does the transcoding and summarization later since that takes
 // the most time
-func processAllVideosInEditingPriority(infos []*MessageInfo) error {
+func processAllVideosInEditingPriority(store storage.Storage, infos []*MessageInfo) error {
 	var err error
 
 	// do all the audio extraction
@@ -200,15 +221,15 @@ func processAllVideosInEditingPriority(infos []*MessageInfo) error {
 		info.AudioURL = getAudioHTTPURL(info.AudioPath)
 		if !util.IsFile(info.AudioPath) {
 			info.ExtractTime = util.NewStopWatch()
-			info.AudioPath, err = extractAudioFromVideo(info.VideoPath)
+			info.AudioPath, err = extractAudioFromVideo(info.VideoPath, info.SpeakerName)
 			info.ExtractTime.Stop()
 			if err != nil {
 				return err
 			}
 
-			// upload the audio to S3
+			// upload the audio to storage
 			info.UploadTime = util.NewStopWatch()
-			info.AudioURL, err = uploadAudioToS3(info.AudioPath)
+			info.AudioURL, err = uploadAudio(store, info.AudioPath)
 			info.UploadTime.Stop()
 			if err != nil {
 				return err
@@ -239,7 +260,7 @@ func processAllVideosInEditingPriority(infos []*MessageInfo) error {
 
 			// upload the transcriptions
 			info.UploadTranscriptTime = util.NewStopWatch()
-			info.TranscriptURLs, err = uploadTranscriptionsToS3(xscripts)
+			info.TranscriptURLs, err = uploadTranscriptions(store, xscripts)
 			info.UploadTranscriptTime.Stop()
 			if err != nil {
 				return err
@@ -257,11 +278,30 @@ func processAllVideosInEditingPriority(infos []*MessageInfo) error {
 			return err
 		}
 		info.SummaryTime.Stop()
+
+		if err := tagAudioWithSummary(store, info); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// tagAudioWithSummary tags audio that was extracted from a recording again to add its summary, and
+// uploads it again. Audio that was already there isn't changed, since it may have been tagged from
+// the catalog
+func tagAudioWithSummary(store storage.Storage, info *MessageInfo) error {
+	if info.ExtractTime.StartTime.IsZero() || info.Summary == "" {
+		return nil
+	}
+
+	tag := newRecordingID3Tag(info.AudioPath, info.SpeakerName, info.Summary)
+	if err := tagAudio(info.AudioPath, &tag); err != nil {
+		return err
+	}
+	return uploadFileToStorage(store, info.AudioPath, getAudioKey(info.AudioPath), "audio/mpeg")
+}
+
 func printMessageInfo(index int, info *MessageInfo) {
 	fmt.Printf("Message #%d\n", index+1)
 	fmt.Printf("Video file   : %s\n", filepath.Base(info.VideoPath))
@@ -381,6 +421,28 @@ func getTranscribePathFromAudioPath(audioPath string, textExt string) string {
 		filepath.Dir(audioPath), audioName[:len(audioName)-4]+textExt)
 }
 
+// uploadFileToStorage uploads a file to the storage with the key. The file isn't uploaded again if
+// the storage already has the same contents
+func uploadFileToStorage(store storage.Storage, filePath string, key string, contentType string) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("cannot read %s: %w", filePath, err)
+	}
+
+	ctx := context.Background()
+	existing, err := store.Head(ctx, key)
+	if err == nil && existing.ETag == storage.ComputeETag(content) {
+		fmt.Printf("Already uploaded: %s\n", key)
+		return nil
+	}
+	if err != nil && !errors.Is(err, storage.ErrNotExist) {
+		return err
+	}
+
+	fmt.Printf("Uploading: %s\n", key)
+	return store.Put(ctx, key, content, storage.PutOptions{ContentType: contentType})
+}
+
 // deleteExistingFile deletes an existing file if it exists.
 // If prompt is true, then the user will be asked whether
 // they want to overwrite it before deleting it
@@ -422,12 +484,15 @@ func PromptUserForSpeaker(defaultSpeaker string) string {
 	if name == "" {
 		name = defaultSpeaker
 	}
-	switch strings.ToUpper(name) {
-	case "V", "VERN":
-		return "Pastor Vern Peltz"
-	case "M", "MARY":
-		return "Pastor Mary Peltz"
-	default:
-		return name
+	return resolveSpeakerName(name)
+}
+
+// resolveSpeakerName converts a name, alias, or initials of a speaker into the display name from
+// the speaker registry. Names of speakers that aren't in the registry are returned as-is
+func resolveSpeakerName(name string) string {
+	speakers := catalog.GetSpeakerRegistry()
+	if speaker := speakers.FindByInitials(name); speaker != nil {
+		return speaker.DisplayName(catalog.UnknownMinistry)
 	}
+	return speakers.NormalizeName(name, catalog.UnknownMinistry)
 }
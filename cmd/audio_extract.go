@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -8,8 +9,11 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/WordOfLifeMN/online/storage"
 	"github.com/WordOfLifeMN/online/util"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // audioExtractCmd represents the command to extract audio from video
@@ -22,10 +26,28 @@ The input file must be .mp4 and the output will be generated as an .mp3 file
 in the same directory as the input. If the output file already exsits, you will
 be prompted to overwrite it.
 
-After extraction, the audio file will be uploaded to AWS S3's wordoflife.mn.audio
-bucket as s3://wordoflife.mn.audio/{year}/{mp3-file-name} .
+The intro at the start of the recording is trimmed. The end of the intro is
+found by matching the intro bumper of the ministry if it has one, or from the
+silence after the intro near where it usually ends. The usual trim of the
+ministry is used if it can't be found, and --trim sets it. Check the trim that
+is printed.
 
-Requires 'ffmpeg' and 'aws' be installed and accessible on the path.`,
+The audio is tagged with its title, year, and ministry from the file name, the
+speaker (--speaker, or the initials in the file name), and the default
+thumbnail of the ministry as cover art. Use 'audio tag' to tag it from the
+catalog once the message is in it.
+
+With --normalize, the audio is normalized to the loudness of the ministry (EBU
+R128, measured in an extra first pass over the recording). It is not normalized
+by default. Then it is checked for clipping, long silences, and being nearly
+silent. If there are problems, it is renamed to
+*.rejected.mp3 and isn't uploaded unless --check=false.
+
+After extraction, the audio file will be uploaded to the audio storage
+(--audio-storage, the AWS S3 wordoflife.mn.audio bucket by default) as
+s3://wordoflife.mn.audio/{year}/{mp3-file-name} .
+
+Requires 'ffmpeg' be installed and accessible on the path.`,
 	RunE: audioExtract,
 }
 
@@ -51,44 +73,63 @@ func audioExtract(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	audioPath, err := extractAudioFromVideo(videoPath)
+	store, err := getAudioStorage()
 	if err != nil {
 		return err
 	}
 
-	if _, err = uploadAudioToS3(audioPath); err != nil {
+	speakerName := resolveSpeakerName(viper.GetString("speaker"))
+	if speakerName == "" {
+		speakerName = getSpeakerFromFileName(videoPath)
+	}
+	audioPath, err := extractAudioFromVideo(videoPath, speakerName)
+	if err != nil {
+		return err
+	}
+
+	if _, err = uploadAudio(store, audioPath); err != nil {
 		return err
 	}
 	return nil
 }
 
-func extractAudioFromVideo(videoPath string) (string, error) {
+// extractAudioFromVideo extracts the audio of a recording into an mp3 file next to it, and tags it
+// from the file name
+func extractAudioFromVideo(videoPath string, speakerName string) (string, error) {
 	audioPath := getAudioPathFromVideoPath(videoPath)
 	if err := deleteExistingFile(audioPath, true); err != nil {
 		return "", err
 	}
 
-	// compute trim length based on file name
-	trimLen := 9.8
-	if strings.Contains(audioPath, " FF ") {
-		trimLen = 9.9
-	} else if strings.Contains(audioPath, " CORE ") {
-		trimLen = 30.0
+	// find the end of the intro and the loudness based on the ministry in the file name
+	profile := getMinistryFromFileName(audioPath).AudioProfile()
+	trimLen, trimSource := getTrimLength(videoPath, profile)
+	args := []string{
+		"-hide_banner",
+		"-loglevel", "warning",
+		"-stats",
+		"-i", videoPath,
+		"-ss", fmt.Sprintf("%f", trimLen),
+	}
+	if profile.Loudness != nil && viper.GetBool("audio-normalize") {
+		filter, err := getLoudnessFilter(videoPath, trimLen, profile.Loudness)
+		if err != nil {
+			return "", err
+		}
+		// the loudness filter works at a higher sample rate, so set it back
+		args = append(args, "-af", filter, "-ar", "44100")
 	}
+	args = append(args, audioPath)
 
 	// output status
 	fmt.Printf("Extracting: %s\n", filepath.Base(audioPath))
 	fmt.Printf("      from: %s\n", filepath.Base(videoPath))
-	fmt.Printf("  trimming: %0.1fs\n", trimLen)
+	fmt.Printf("  trimming: %0.1fs (%s)\n", trimLen, trimSource)
+	if profile.Loudness != nil && viper.GetBool("audio-normalize") {
+		fmt.Printf("normalizing: %g LUFS\n", profile.Loudness.Integrated)
+	}
 
-	cmd := exec.Command("ffmpeg",
-		"-hide_banner",
-		"-loglevel", "warning",
-		"-stats",
-		"-i", videoPath,
-		"-ss", fmt.Sprintf("%f", trimLen),
-		audioPath,
-	)
+	cmd := exec.Command("ffmpeg", args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	log.Print(cmd.String())
@@ -97,42 +138,84 @@ func extractAudioFromVideo(videoPath string) (string, error) {
 		return "", err
 	}
 
+	// don't let a bad recording get uploaded. the audio is moved out of the way, so it is extracted
+	// and checked again the next time instead of being taken as done
+	if viper.GetBool("audio-check") {
+		if err := checkAudioQuality(audioPath); err != nil {
+			rejectedPath, rejectErr := rejectAudio(audioPath)
+			if rejectErr != nil {
+				return "", errors.Join(err, rejectErr)
+			}
+			return "", fmt.Errorf("%w, so it was not uploaded. listen to %s, and use 'audio extract --check=false' to upload it anyway",
+				err, filepath.Base(rejectedPath))
+		}
+	}
+
+	tag := newRecordingID3Tag(audioPath, speakerName, "")
+	if err := tagAudio(audioPath, &tag); err != nil {
+		fmt.Printf("Unable to tag audio: %s\n", err)
+		return "", err
+	}
+
 	return audioPath, nil
 }
 
-// uploadAudioToS3 uploads the provided audio to the S3 bucket
+// rejectAudio renames audio that didn't pass the quality checks to *.rejected.mp3, replacing any
+// audio that was rejected before, and returns its new path
+func rejectAudio(audioPath string) (string, error) {
+	rejectedPath := strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + ".rejected" + filepath.Ext(audioPath)
+	if err := os.Rename(audioPath, rejectedPath); err != nil {
+		return "", fmt.Errorf("unable to move the rejected audio %s: %w", audioPath, err)
+	}
+	return rejectedPath, nil
+}
+
+// getMinistryFromFileName finds the ministry of a recording from its file name. The ministry is
+// identified by an upper-case word in the name that is a ministry key or alias, like
+// "2025-03-09 CORE Title.mp4". Returns UnknownMinistry if the file name has no ministry
+func getMinistryFromFileName(filePath string) catalog.Ministry {
+	for _, word := range strings.Fields(filepath.Base(filePath)) {
+		if word != strings.ToUpper(word) {
+			continue
+		}
+		if ministry := catalog.NewMinistryFromString(word); ministry != catalog.UnknownMinistry {
+			return ministry
+		}
+	}
+	return catalog.UnknownMinistry
+}
+
+// uploadAudio uploads the provided audio to the audio storage
 // and returns the HTTP URL for the uploaded file.
-func uploadAudioToS3(audioPath string) (string, error) {
+func uploadAudio(store storage.Storage, audioPath string) (string, error) {
 	if !util.DoesPathExist(audioPath) {
 		return "", fmt.Errorf("cannot find file %s", audioPath)
 	}
 
 	// compute all the file references
-	s3URL := getAudioS3URL(audioPath)
+	key := getAudioKey(audioPath)
 	url := getAudioHTTPURL(audioPath)
 
 	// fmt.Printf("Uploading: %s\n", audioPath)
-	// fmt.Printf("       to: %s\n", s3URL)
+	// fmt.Printf("       to: %s\n", key)
 	fmt.Printf("╭───────────────────────────────────────────────────────────────────────────────────┄┄\n")
 	fmt.Printf("│ Public HTML reference for audio file\n")
 	fmt.Printf("%s\n", url)
 	fmt.Printf("╰───────────────────────────────────────────────────────────────────────────────────┄┄\n")
 
-	cmd := exec.Command("aws", "s3", "cp", audioPath, s3URL)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		fmt.Printf("Unable to upload audio to S3: %s\n", err)
+	if err := uploadFileToStorage(store, audioPath, key, "audio/mpeg"); err != nil {
+		fmt.Printf("Unable to upload audio: %s\n", err)
 		return "", err
 	}
 
 	return url, nil
 }
 
-func getAudioS3URL(audioPath string) string {
+// getAudioKey gets the key of an audio file in the audio storage, which is in the directory for
+// the year at the start of its name
+func getAudioKey(audioPath string) string {
 	audioName := filepath.Base(audioPath)
-	s3Bucket := "wordoflife.mn.audio"
-	return fmt.Sprintf("s3://%s/%s/%s", s3Bucket, audioName[0:4], audioName)
+	return fmt.Sprintf("%s/%s", audioName[0:4], audioName)
 }
 
 func getAudioHTTPURL(audioPath string) string {
@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/spf13/viper"
+)
+
+// Recordings start with an intro bumper that isn't part of the message, and it changes now and
+// then. The end of the intro is found by matching the start of the recording against a recording
+// of the bumper if the ministry has one, or by finding the silence between the intro and the
+// message near where the intro of the ministry usually ends, if there is only one. If neither
+// finds it, the usual trim of the ministry is used
+
+// seconds at the start of a recording that are searched for the end of the intro
+const INTRO_SEARCH_SECONDS = 90
+
+// level in dB below which audio is the silence after the intro, and the shortest silence
+const INTRO_SILENCE_NOISE = -35
+const INTRO_SILENCE_SECONDS = 0.3
+
+// seconds from the usual trim of the ministry that the silence after the intro can end, and the
+// fraction of the usual trim that must be over before it ends, so a gap in the bumper or a pause
+// in the message isn't taken as the end of the intro
+const INTRO_SILENCE_WINDOW = 3.0
+const INTRO_SILENCE_MIN_FRACTION = 0.5
+
+// seconds of the silence after the intro that are kept, so the first word isn't cut off
+const INTRO_LEAD_SECONDS = 0.2
+
+// sample rate the audio is decoded at to match the bumper, and the number of samples in each
+// frame of the loudness envelope that is matched (20ms)
+const INTRO_SAMPLE_RATE = 8000
+const INTRO_FRAME_SIZE = 160
+
+// lowest correlation between the bumper and the recording for the bumper to be found
+const INTRO_MATCH_SCORE = 0.7
+
+// getTrimLength finds the seconds of intro to trim from the start of a recording, and describes
+// how it was found
+func getTrimLength(videoPath string, profile catalog.AudioProfile) (float64, string) {
+	if trim := viper.GetFloat64("audio-trim"); trim >= 0 {
+		return trim, "from --trim"
+	}
+
+	if profile.Bumper != "" {
+		end, found, err := findIntroEndFromBumper(videoPath, profile.Bumper)
+		if err != nil {
+			log.Printf("WARNING: %s", err.Error())
+		} else if found {
+			return end, "matched the intro " + filepath.Base(profile.Bumper)
+		}
+	}
+
+	end, found, err := findIntroEndFromSilence(videoPath, profile.Trim)
+	if err != nil {
+		log.Printf("WARNING: %s", err.Error())
+	} else if found {
+		return end, fmt.Sprintf("silence after the intro, usually %0.1fs", profile.Trim)
+	}
+
+	return profile.Trim, "usual for the ministry, the end of the intro wasn't found"
+}
+
+// +---------------------------------------------------------------------------
+// | Silence
+// +---------------------------------------------------------------------------
+
+// findIntroEndFromSilence finds the end of the intro of a recording from the silence between
+// the intro and the message. Returns false if there isn't a silence near the usual trim
+func findIntroEndFromSilence(videoPath string, usualTrim float64) (float64, bool, error) {
+	cmd := exec.Command("ffmpeg",
+		"-hide_banner",
+		"-nostats",
+		"-t", fmt.Sprintf("%d", INTRO_SEARCH_SECONDS),
+		"-i", videoPath,
+		"-vn",
+		"-af", fmt.Sprintf("silencedetect=noise=%ddB:d=%g", INTRO_SILENCE_NOISE, INTRO_SILENCE_SECONDS),
+		"-f", "null",
+		"-",
+	)
+	log.Print(cmd.String())
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return 0, false, fmt.Errorf("unable to find the silences in %s: %w", videoPath, err)
+	}
+
+	silences, err := parseSilences(string(output))
+	if err != nil {
+		return 0, false, fmt.Errorf("unable to find the silences in %s: %w", videoPath, err)
+	}
+	end, found := chooseIntroSilence(silences, usualTrim)
+	return end, found, nil
+}
+
+// chooseIntroSilence chooses the silence after the intro, which is the only one that ends near the
+// usual trim, and returns where the message starts after it. Returns false if no silence ends
+// within INTRO_SILENCE_WINDOW of the usual trim, or if more than one does since then it can't be
+// told which one is the end of the intro
+func chooseIntroSilence(silences []audioSilence, usualTrim float64) (float64, bool) {
+	var candidates []audioSilence
+	for _, silence := range silences {
+		// a silence that doesn't end is the end of the search, not the start of the message
+		if silence.Duration == 0 {
+			continue
+		}
+		end := silence.Start + silence.Duration
+		if end < usualTrim*INTRO_SILENCE_MIN_FRACTION || math.Abs(end-usualTrim) > INTRO_SILENCE_WINDOW {
+			continue
+		}
+		candidates = append(candidates, silence)
+	}
+	if len(candidates) != 1 {
+		if len(candidates) > 1 {
+			log.Printf("%d silences end near the usual trim of %0.1fs, so the end of the intro is unknown", len(candidates), usualTrim)
+		}
+		return 0, false
+	}
+
+	silence := candidates[0]
+	return math.Max(silence.Start+silence.Duration-INTRO_LEAD_SECONDS, silence.Start), true
+}
+
+// +---------------------------------------------------------------------------
+// | Bumper
+// +---------------------------------------------------------------------------
+
+// findIntroEndFromBumper finds the end of the intro of a recording by finding where a recording
+// of the intro bumper matches it. Returns false if the bumper isn't in the recording
+func findIntroEndFromBumper(videoPath string, bumper string) (float64, bool, error) {
+	templateDir, err := getTemplateDir()
+	if err != nil {
+		return 0, false, err
+	}
+	reference, err := decodeAudioEnvelope(filepath.Join(templateDir, filepath.FromSlash(bumper)))
+	if err != nil {
+		return 0, false, err
+	}
+	recording, err := decodeAudioEnvelope(videoPath)
+	if err != nil {
+		return 0, false, err
+	}
+
+	offset, score := findBumperOffset(recording, reference)
+	log.Printf("Intro %s matches %s at %0.2fs with a score of %0.2f", bumper, filepath.Base(videoPath),
+		float64(offset*INTRO_FRAME_SIZE)/INTRO_SAMPLE_RATE, score)
+	if score < INTRO_MATCH_SCORE {
+		return 0, false, nil
+	}
+	return float64((offset+len(reference))*INTRO_FRAME_SIZE) / INTRO_SAMPLE_RATE, true, nil
+}
+
+// decodeAudioEnvelope decodes the start of the audio of a file and gets its loudness envelope
+func decodeAudioEnvelope(filePath string) ([]float64, error) {
+	cmd := exec.Command("ffmpeg",
+		"-hide_banner",
+		"-loglevel", "error",
+		"-t", fmt.Sprintf("%d", INTRO_SEARCH_SECONDS),
+		"-i", filePath,
+		"-vn",
+		"-ac", "1",
+		"-ar", fmt.Sprintf("%d", INTRO_SAMPLE_RATE),
+		"-f", "s16le",
+		"-",
+	)
+	log.Print(cmd.String())
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode the audio of %s: %w", filePath, err)
+	}
+
+	samples := make([]int16, len(output)/2)
+	for index := range samples {
+		samples[index] = int16(binary.LittleEndian.Uint16(output[index*2:]))
+	}
+	return getAudioEnvelope(samples), nil
+}
+
+// getAudioEnvelope gets the loudness envelope of audio, which is the RMS level of each frame of
+// INTRO_FRAME_SIZE samples
+func getAudioEnvelope(samples []int16) []float64 {
+	envelope := make([]float64, 0, len(samples)/INTRO_FRAME_SIZE)
+	for start := 0; start+INTRO_FRAME_SIZE <= len(samples); start += INTRO_FRAME_SIZE {
+		sum := 0.0
+		for _, sample := range samples[start : start+INTRO_FRAME_SIZE] {
+			sum += float64(sample) * float64(sample)
+		}
+		envelope = append(envelope, math.Sqrt(sum/INTRO_FRAME_SIZE))
+	}
+	return envelope
+}
+
+// findBumperOffset finds the frame of a recording where the envelope of the bumper matches it
+// best, and how well it matches, from -1 to 1
+func findBumperOffset(recording []float64, bumper []float64) (int, float64) {
+	bestOffset, bestScore := 0, -1.0
+	for offset := 0; offset+len(bumper) <= len(recording); offset++ {
+		score := getCorrelation(recording[offset:offset+len(bumper)], bumper)
+		if score > bestScore {
+			bestOffset, bestScore = offset, score
+		}
+	}
+	return bestOffset, bestScore
+}
+
+// getCorrelation gets the correlation of two envelopes of the same length, from -1 to 1. It is 0
+// if either is flat
+func getCorrelation(a []float64, b []float64) float64 {
+	if len(a) == 0 {
+		return 0
+	}
+	meanA, meanB := 0.0, 0.0
+	for index := range a {
+		meanA += a[index]
+		meanB += b[index]
+	}
+	meanA /= float64(len(a))
+	meanB /= float64(len(b))
+
+	covariance, varianceA, varianceB := 0.0, 0.0, 0.0
+	for index := range a {
+		covariance += (a[index] - meanA) * (b[index] - meanB)
+		varianceA += (a[index] - meanA) * (a[index] - meanA)
+		varianceB += (b[index] - meanB) * (b[index] - meanB)
+	}
+	if varianceA == 0 || varianceB == 0 {
+		return 0
+	}
+	return covariance / math.Sqrt(varianceA*varianceB)
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"math"
+	"testing"
+
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestAudioIntroTestSuite(t *testing.T) {
+	suite.Run(t, new(AudioIntroTestSuite))
+}
+
+type AudioIntroTestSuite struct {
+	suite.Suite
+}
+
+func (t *AudioIntroTestSuite) TestGetTrimLength_Flag() {
+	previous := viper.Get("audio-trim")
+	viper.Set("audio-trim", 12.5)
+	t.T().Cleanup(func() { viper.Set("audio-trim", previous) })
+
+	trim, source := getTrimLength("2025-03-09 Faith.mp4", catalog.AudioProfile{Trim: 30, Bumper: "intro/core.mp3"})
+	t.Equal(12.5, trim)
+	t.Equal("from --trim", source)
+}
+
+func (t *AudioIntroTestSuite) TestParseSilences() {
+	output := `Input #0, mov,mp4,m4a,3gp,3g2,mj2, from '2025-03-09 Faith.mp4':
+[silencedetect @ 0x6000012d4000] silence_start: 4.1
+[silencedetect @ 0x6000012d4000] silence_end: 4.6 | silence_duration: 0.5
+[silencedetect @ 0x6000012d4000] silence_start: 27.82
+[silencedetect @ 0x6000012d4000] silence_end: 29.4 | silence_duration: 1.58
+[silencedetect @ 0x6000012d4000] silence_start: 88.5
+`
+	silences, err := parseSilences(output)
+	t.Require().NoError(err)
+	t.Equal([]audioSilence{{Start: 4.1, Duration: 0.5}, {Start: 27.82, Duration: 1.58}, {Start: 88.5}}, silences)
+}
+
+func (t *AudioIntroTestSuite) TestChooseIntroSilence() {
+	silences := []audioSilence{{Start: 4.1, Duration: 0.5}, {Start: 27.8, Duration: 1.6}, {Start: 40, Duration: 0.4}, {Start: 88.5}}
+
+	// near the usual trim, keeping a little of the silence
+	end, found := chooseIntroSilence(silences, 30)
+	t.True(found)
+	t.InDelta(29.2, end, 0.001)
+
+	end, found = chooseIntroSilence(silences, 38)
+	t.True(found)
+	t.InDelta(40.2, end, 0.001)
+
+	// a short silence keeps all of it
+	end, found = chooseIntroSilence([]audioSilence{{Start: 10, Duration: 0.1}}, 10)
+	t.True(found)
+	t.InDelta(10, end, 0.001)
+
+	// the silence at the end of the search isn't the end of the intro
+	_, found = chooseIntroSilence(silences, 85)
+	t.False(found)
+
+	_, found = chooseIntroSilence(nil, 30)
+	t.False(found)
+}
+
+func (t *AudioIntroTestSuite) TestChooseIntroSilence_NotTheIntro() {
+	// a gap in the bumper keeps the rest of the jingle
+	_, found := chooseIntroSilence([]audioSilence{{Start: 4.1, Duration: 0.5}}, 9.8)
+	t.False(found)
+
+	// a pause in the message cuts off the first sentences
+	_, found = chooseIntroSilence([]audioSilence{{Start: 19.6, Duration: 0.8}}, 9.8)
+	t.False(found)
+
+	// a silence near a short usual trim that ends too early
+	_, found = chooseIntroSilence([]audioSilence{{Start: 1, Duration: 0.5}}, 4)
+	t.False(found)
+
+	// with more than one silence near the usual trim, which one ends the intro is unknown
+	_, found = chooseIntroSilence([]audioSilence{{Start: 7.5, Duration: 0.5}, {Start: 11, Duration: 0.4}}, 9.8)
+	t.False(found)
+
+	// the gap in the bumper doesn't hide the end of the intro
+	end, found := chooseIntroSilence([]audioSilence{{Start: 4.1, Duration: 0.5}, {Start: 9.5, Duration: 0.6}}, 9.8)
+	t.True(found)
+	t.InDelta(9.9, end, 0.001)
+}
+
+func (t *AudioIntroTestSuite) TestGetAudioEnvelope() {
+	samples := make([]int16, INTRO_FRAME_SIZE*2+10)
+	for index := range INTRO_FRAME_SIZE {
+		samples[index] = 100
+		samples[INTRO_FRAME_SIZE+index] = -300
+	}
+
+	// the partial frame at the end is dropped
+	envelope := getAudioEnvelope(samples)
+	t.Require().Len(envelope, 2)
+	t.InDelta(100, envelope[0], 0.001)
+	t.InDelta(300, envelope[1], 0.001)
+}
+
+func (t *AudioIntroTestSuite) TestFindBumperOffset() {
+	bumper := make([]float64, 50)
+	for index := range bumper {
+		bumper[index] = 1000 + 800*math.Sin(float64(index)/3)
+	}
+	recording := make([]float64, 300)
+	for index := range recording {
+		recording[index] = 200 + 50*math.Cos(float64(index)/7)
+	}
+	// the bumper is quieter in the recording, which doesn't matter
+	for index, level := range bumper {
+		recording[120+index] = level / 2
+	}
+
+	offset, score := findBumperOffset(recording, bumper)
+	t.Equal(120, offset)
+	t.InDelta(1, score, 0.001)
+
+	// a recording shorter than the bumper can't have it
+	_, score = findBumperOffset(recording[:40], bumper)
+	t.Less(score, INTRO_MATCH_SCORE)
+}
+
+func (t *AudioIntroTestSuite) TestGetCorrelation() {
+	t.InDelta(1, getCorrelation([]float64{1, 2, 3}, []float64{2, 4, 6}), 0.001)
+	t.InDelta(-1, getCorrelation([]float64{1, 2, 3}, []float64{3, 2, 1}), 0.001)
+	t.Equal(0.0, getCorrelation([]float64{5, 5, 5}, []float64{1, 2, 3}))
+	t.Equal(0.0, getCorrelation(nil, nil))
+}
@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/WordOfLifeMN/online/catalog"
+)
+
+// With --normalize, audio is normalized to the loudness of its ministry with the two pass EBU
+// R128 "loudnorm" filter of ffmpeg: the first pass measures the loudness of the recording, and the
+// second uses the measurements to normalize it linearly. After it's extracted, the audio is
+// analyzed for clipping, long silences, and being nearly silent, so a bad file is caught before
+// it's uploaded
+
+// peak level in dBFS at or above which the audio is clipped, since it's at full scale
+const AUDIO_CLIP_LEVEL = -0.1
+
+// number of samples at the peak level at or above which the audio is clipped, since the peaks
+// are flattened
+const AUDIO_CLIP_COUNT = 100
+
+// RMS level in dBFS below which the audio is nearly silent
+const AUDIO_SILENT_LEVEL = -50.0
+
+// level in dB below which audio is silence, and the seconds of silence that are reported
+const AUDIO_SILENCE_NOISE = -50
+const AUDIO_SILENCE_SECONDS = 30
+
+// loudnessMeasurement is what the first pass of the loudnorm filter measures
+type loudnessMeasurement struct {
+	Integrated string `json:"input_i"`       // integrated loudness in LUFS
+	TruePeak   string `json:"input_tp"`      // true peak in dBTP
+	Range      string `json:"input_lra"`     // loudness range in LU
+	Threshold  string `json:"input_thresh"`  // threshold in LUFS
+	Offset     string `json:"target_offset"` // offset gain in LU
+}
+
+// audioAnalysis is the levels and silences of audio
+type audioAnalysis struct {
+	PeakLevel float64        // peak level in dBFS
+	PeakCount int            // number of samples at the peak level
+	RMSLevel  float64        // RMS level in dBFS
+	Silences  []audioSilence // silences longer than AUDIO_SILENCE_SECONDS
+}
+
+// audioSilence is a silence in audio
+type audioSilence struct {
+	Start    float64 // seconds from the start of the audio
+	Duration float64 // seconds of silence, or 0 if it lasts to the end of the audio
+}
+
+// +---------------------------------------------------------------------------
+// | Loudness normalization
+// +---------------------------------------------------------------------------
+
+// getLoudnessFilter gets the ffmpeg audio filter that normalizes the loudness of a recording to
+// the target. This is the first pass, which measures the recording
+func getLoudnessFilter(videoPath string, trimLen float64, target *catalog.LoudnessTarget) (string, error) {
+	fmt.Printf("Measuring: %s\n", filepath.Base(videoPath))
+
+	cmd := exec.Command("ffmpeg",
+		"-hide_banner",
+		"-nostats",
+		"-i", videoPath,
+		"-ss", fmt.Sprintf("%f", trimLen),
+		"-vn",
+		"-af", fmt.Sprintf("loudnorm=I=%g:TP=%g:LRA=%g:print_format=json", target.Integrated, target.TruePeak, target.Range),
+		"-f", "null",
+		"-",
+	)
+	log.Print(cmd.String())
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("unable to measure the loudness of %s: %w", videoPath, err)
+	}
+
+	measurement, err := parseLoudnessMeasurement(string(output))
+	if err != nil {
+		return "", fmt.Errorf("unable to measure the loudness of %s: %w", videoPath, err)
+	}
+	fmt.Printf(" loudness: %s LUFS, peak %s dBTP, range %s LU\n",
+		measurement.Integrated, measurement.TruePeak, measurement.Range)
+
+	return fmt.Sprintf("loudnorm=I=%g:TP=%g:LRA=%g:measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s:linear=true",
+		target.Integrated, target.TruePeak, target.Range,
+		measurement.Integrated, measurement.TruePeak, measurement.Range, measurement.Threshold, measurement.Offset), nil
+}
+
+// parseLoudnessMeasurement parses the measurement from the output of the first pass of the
+// loudnorm filter, which is a JSON object at the end of the output
+func parseLoudnessMeasurement(output string) (*loudnessMeasurement, error) {
+	start := strings.LastIndex(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no loudness measurement in the output of ffmpeg")
+	}
+
+	var measurement loudnessMeasurement
+	if err := json.Unmarshal([]byte(output[start:end+1]), &measurement); err != nil {
+		return nil, fmt.Errorf("cannot parse the loudness measurement: %w", err)
+	}
+
+	// silence can't be normalized
+	for _, value := range []string{measurement.Integrated, measurement.TruePeak, measurement.Range, measurement.Threshold, measurement.Offset} {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsInf(number, 0) || math.IsNaN(number) {
+			return nil, fmt.Errorf("the loudness measurement %q is not a number, the audio may be silent", value)
+		}
+	}
+	return &measurement, nil
+}
+
+// +---------------------------------------------------------------------------
+// | Quality checks
+// +---------------------------------------------------------------------------
+
+// checkAudioQuality analyzes audio and returns an error if it is clipped, has long silences, or
+// is nearly silent
+func checkAudioQuality(audioPath string) error {
+	fmt.Printf("Analyzing: %s\n", filepath.Base(audioPath))
+
+	cmd := exec.Command("ffmpeg",
+		"-hide_banner",
+		"-nostats",
+		"-i", audioPath,
+		"-af", fmt.Sprintf("astats=measure_perchannel=none,silencedetect=noise=%ddB:d=%d", AUDIO_SILENCE_NOISE, AUDIO_SILENCE_SECONDS),
+		"-f", "null",
+		"-",
+	)
+	log.Print(cmd.String())
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("unable to analyze %s: %w", audioPath, err)
+	}
+
+	analysis, err := parseAudioAnalysis(string(output))
+	if err != nil {
+		return fmt.Errorf("unable to analyze %s: %w", audioPath, err)
+	}
+	problems := getAudioProblems(analysis)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	fmt.Printf("Problems with %s:\n", filepath.Base(audioPath))
+	for _, problem := range problems {
+		fmt.Printf("  - %s\n", problem)
+	}
+	return fmt.Errorf("%s has problems", filepath.Base(audioPath))
+}
+
+// matches the start of the lines of the output of the ffmpeg filters, like the
+// "[Parsed_astats_0 @ 0x7f8] " of "[Parsed_astats_0 @ 0x7f8] Peak level dB: -0.512"
+var audioFilterLineRegexp = regexp.MustCompile(`^\[\S+ @ \S+\] `)
+
+// parseAudioAnalysis parses the output of the astats and silencedetect filters
+func parseAudioAnalysis(output string) (*audioAnalysis, error) {
+	analysis := &audioAnalysis{}
+	found := map[string]bool{}
+	err := parseAudioFilterOutput(output, func(key string, value string) error {
+		var err error
+		switch key {
+		case "Peak level dB":
+			analysis.PeakLevel, err = strconv.ParseFloat(value, 64)
+		case "Peak count":
+			var count float64
+			count, err = strconv.ParseFloat(value, 64)
+			analysis.PeakCount = int(count)
+		case "RMS level dB":
+			analysis.RMSLevel, err = strconv.ParseFloat(value, 64)
+		default:
+			return nil
+		}
+		found[key] = true
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found["Peak level dB"] || !found["RMS level dB"] {
+		return nil, fmt.Errorf("no audio levels in the output of ffmpeg")
+	}
+
+	if analysis.Silences, err = parseSilences(output); err != nil {
+		return nil, err
+	}
+	return analysis, nil
+}
+
+// parseSilences parses the silences from the output of the silencedetect filter
+func parseSilences(output string) ([]audioSilence, error) {
+	var silences []audioSilence
+	err := parseAudioFilterOutput(output, func(key string, value string) error {
+		switch key {
+		case "silence_start":
+			start, err := strconv.ParseFloat(value, 64)
+			silences = append(silences, audioSilence{Start: start})
+			return err
+		case "silence_duration":
+			if len(silences) == 0 {
+				return nil
+			}
+			var err error
+			silences[len(silences)-1].Duration, err = strconv.ParseFloat(value, 64)
+			return err
+		}
+		return nil
+	})
+	return silences, err
+}
+
+// parseAudioFilterOutput calls parse with the key and value of each "key: value" that the ffmpeg
+// filters output
+func parseAudioFilterOutput(output string, parse func(key string, value string) error) error {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		prefix := audioFilterLineRegexp.FindString(line)
+		if prefix == "" {
+			continue
+		}
+
+		// silencedetect puts the end and duration on one line
+		for _, part := range strings.Split(line[len(prefix):], " | ") {
+			key, value, ok := strings.Cut(part, ": ")
+			if !ok {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			if err := parse(key, value); err != nil {
+				return fmt.Errorf("cannot parse %s %q: %w", key, value, err)
+			}
+		}
+	}
+	return nil
+}
+
+// getAudioProblems gets descriptions of the problems found by analyzing audio
+func getAudioProblems(analysis *audioAnalysis) []string {
+	var problems []string
+	if analysis.RMSLevel < AUDIO_SILENT_LEVEL {
+		problems = append(problems, fmt.Sprintf("nearly silent: the level is %.1f dB", analysis.RMSLevel))
+	}
+	if analysis.PeakLevel >= AUDIO_CLIP_LEVEL {
+		problems = append(problems, fmt.Sprintf("clipped: the peak level is %.1f dB", analysis.PeakLevel))
+	} else if analysis.PeakCount >= AUDIO_CLIP_COUNT {
+		problems = append(problems, fmt.Sprintf("clipped: %d samples are at the peak level", analysis.PeakCount))
+	}
+	for _, silence := range analysis.Silences {
+		if silence.Duration == 0 {
+			problems = append(problems, fmt.Sprintf("silent from %s to the end", formatPodcastDuration(int(silence.Start))))
+		} else {
+			problems = append(problems, fmt.Sprintf("silent for %.0f seconds at %s", silence.Duration, formatPodcastDuration(int(silence.Start))))
+		}
+	}
+	return problems
+}
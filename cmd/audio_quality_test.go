@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestAudioQualityTestSuite(t *testing.T) {
+	suite.Run(t, new(AudioQualityTestSuite))
+}
+
+type AudioQualityTestSuite struct {
+	suite.Suite
+}
+
+func (t *AudioQualityTestSuite) TestParseLoudnessMeasurement() {
+	output := `Input #0, mov,mp4,m4a,3gp,3g2,mj2, from '2025-03-09 Faith.mp4':
+  Duration: 01:02:03.04, start: 0.000000, bitrate: 2500 kb/s
+[Parsed_loudnorm_0 @ 0x600000e2c000]
+{
+	"input_i" : "-27.61",
+	"input_tp" : "-4.47",
+	"input_lra" : "18.06",
+	"input_thresh" : "-39.20",
+	"output_i" : "-16.58",
+	"output_tp" : "-1.50",
+	"output_lra" : "14.78",
+	"output_thresh" : "-27.71",
+	"normalization_type" : "dynamic",
+	"target_offset" : "0.58"
+}
+`
+	measurement, err := parseLoudnessMeasurement(output)
+	t.Require().NoError(err)
+	t.Equal(&loudnessMeasurement{
+		Integrated: "-27.61",
+		TruePeak:   "-4.47",
+		Range:      "18.06",
+		Threshold:  "-39.20",
+		Offset:     "0.58",
+	}, measurement)
+}
+
+func (t *AudioQualityTestSuite) TestParseLoudnessMeasurement_Errors() {
+	_, err := parseLoudnessMeasurement("Conversion failed!")
+	t.Error(err)
+
+	// silence can't be measured
+	_, err = parseLoudnessMeasurement(`{"input_i" : "-inf", "input_tp" : "-inf", "input_lra" : "0.00", "input_thresh" : "-70.00", "target_offset" : "inf"}`)
+	if t.Error(err) {
+		t.Contains(err.Error(), "silent")
+	}
+}
+
+func (t *AudioQualityTestSuite) TestParseAudioAnalysis() {
+	output := `Input #0, mp3, from '2025-03-09 Faith.mp3':
+  Duration: 01:01:53.18, start: 0.025057, bitrate: 128 kb/s
+[silencedetect @ 0x6000012d4000] silence_start: 1801.52
+[silencedetect @ 0x6000012d4000] silence_end: 1845.2 | silence_duration: 43.68
+[silencedetect @ 0x6000012d4000] silence_start: 3700
+[Parsed_astats_0 @ 0x6000012d0000] Overall
+[Parsed_astats_0 @ 0x6000012d0000] DC offset: 0.000012
+[Parsed_astats_0 @ 0x6000012d0000] Peak level dB: -1.512
+[Parsed_astats_0 @ 0x6000012d0000] RMS level dB: -21.338
+[Parsed_astats_0 @ 0x6000012d0000] Peak count: 2.000000
+[Parsed_astats_0 @ 0x6000012d0000] Number of samples: 163721216
+size=N/A time=01:01:53.15 bitrate=N/A speed= 412x
+`
+	analysis, err := parseAudioAnalysis(output)
+	t.Require().NoError(err)
+	t.Equal(&audioAnalysis{
+		PeakLevel: -1.512,
+		PeakCount: 2,
+		RMSLevel:  -21.338,
+		Silences: []audioSilence{
+			{Start: 1801.52, Duration: 43.68},
+			{Start: 3700},
+		},
+	}, analysis)
+
+	_, err = parseAudioAnalysis("Conversion failed!")
+	t.Error(err)
+}
+
+func (t *AudioQualityTestSuite) TestGetAudioProblems() {
+	t.Empty(getAudioProblems(&audioAnalysis{PeakLevel: -1.5, PeakCount: 2, RMSLevel: -21}))
+
+	t.Equal([]string{
+		"nearly silent: the level is -63.0 dB",
+		"silent for 44 seconds at 0:30:01",
+		"silent from 1:01:40 to the end",
+	}, getAudioProblems(&audioAnalysis{
+		PeakLevel: -30,
+		PeakCount: 1,
+		RMSLevel:  -63,
+		Silences: []audioSilence{
+			{Start: 1801.52, Duration: 43.68},
+			{Start: 3700},
+		},
+	}))
+
+	t.Equal([]string{"clipped: the peak level is 0.0 dB"}, getAudioProblems(&audioAnalysis{PeakLevel: 0, PeakCount: 5000, RMSLevel: -12}))
+	t.Equal([]string{"clipped: 5000 samples are at the peak level"}, getAudioProblems(&audioAnalysis{PeakLevel: -3, PeakCount: 5000, RMSLevel: -15}))
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"unicode"
 
+	"github.com/WordOfLifeMN/online/catalog"
 	"github.com/WordOfLifeMN/online/util"
 	"github.com/sashabaranov/go-openai"
 	"github.com/spf13/cobra"
@@ -275,25 +276,21 @@ func findStartOfWord(str string, initPos int) int {
 
 // getSpeakerFromFileName attempts to infer the speaker name from the file name,
 // and prompts the user if necessary
-//   - supported initials are V (Vern Peltz), M (Mary Peltz), J (Jim Isakson), I
-//     (Igor Kondratyuk), A (Anthony Leong), T (Tania Kondratyuk)
+//   - supported initials are the initials of the speakers in the speaker registry,
+//     like V (Vern Peltz), M (Mary Peltz), J (Jim Isakson), etc
 //   - 2025-03-04 Message Title-[VMJIA].mp4
 //   - 2025-03-04-[vmjia] Message Title.mp4
 //   - 2025-03-04-p[vmjia] Message Title.mp4
 //   - 2025-03-04p-[vmjia] Message Title.mp4
 func getSpeakerFromFileName(filePath string) string {
-	names := map[string]string{
-		"V": "Pastor Vern Peltz",
-		"M": "Pastor Mary Peltz",
-		"J": "Jim Isakson",
-		"I": "Pastor Igor Kondratyuk",
-		"T": "Pastor Tania Kondratyuk",
-		"A": "Anthony Leong",
-	}
+	speakers := catalog.GetSpeakerRegistry()
 
 	// test for each possible location of the initials
 	ucFilePath := strings.ToUpper(filePath)
-	for initials, n := range names {
+	for _, initials := range speakers.GetAllInitials() {
+		n := speakers.FindByInitials(initials).DisplayName(catalog.UnknownMinistry)
+		initials = regexp.QuoteMeta(initials)
+
 		// initial at end of file name: "2025-03-09 Title-v.mp4"
 		re := fmt.Sprintf("-%s\\....$", initials)
 		if match, err := regexp.MatchString(re, ucFilePath); err == nil && match {
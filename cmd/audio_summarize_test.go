@@ -120,7 +120,7 @@ func (t *AudioSummarizeCmdTestSuite) TestGetSpeakerFromFileName() {
 		{"2025-03-09-i Msg.mp4", "Pastor Igor Kondratyuk"},
 		{"2025-03-09-t Msg.mp4", "Pastor Tania Kondratyuk"},
 		{"2025-03-09-a Msg.mp4", "Anthony Leong"},
-		{"2025-03-09-j Msg.mp4", "Jim Isakson"},
+		{"2025-03-09-j Msg.mp4", "Pastor Jim Isakson"},
 
 		{"2025-03-09-pt Msg.mp4", "Pastor Tania Kondratyuk"},
 	} {
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/spf13/cobra"
+)
+
+// audioTagCmd represents the command to write the tags of audio files from the catalog
+var audioTagCmd = &cobra.Command{
+	Use:   "tag [--upload] audio-file...",
+	Short: "Write the ID3 tags of audio files from the catalog",
+	Long: `Writes the ID3 tags of audio files that are already in the catalog, so
+podcast apps and phones show the message instead of just the file name.
+
+The message of each file is found in the catalog (--input or --sheet-id) by the
+name of its audio file. The tags have the title, speakers (artist), series
+(album), track number, year, ministry (publisher), description (comment), and
+the series thumbnail or the ministry default thumbnail as cover art.
+
+Audio extracted with 'audio extract' is already tagged from its file name. Use
+--upload to upload the tagged files to the audio storage again.`,
+	Example: `audio tag --input ~/.wolm/online.cache.json --upload "2025-03-09 Faith.mp3"`,
+	RunE:    audioTag,
+}
+
+func init() {
+	audioCmd.AddCommand(audioTagCmd)
+
+	audioTagCmd.Flags().Bool("upload", false, "Upload the tagged files to the audio storage")
+}
+
+func audioTag(cmd *cobra.Command, args []string) error {
+	initLogging()
+
+	upload, _ := cmd.Flags().GetBool("upload")
+
+	// get the input audio files
+	if len(args) == 0 {
+		audioPath := getInputAudio("")
+		if audioPath == "" {
+			fmt.Printf("Aborting")
+			return nil
+		}
+		args = []string{audioPath}
+	}
+
+	cat, err := readOnlineContentFromInput(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	for _, audioPath := range args {
+		msg := findMessageByAudioFile(cat, audioPath)
+		if msg == nil {
+			return fmt.Errorf("cannot find the message with audio %s in the catalog", filepath.Base(audioPath))
+		}
+
+		tag := catalog.NewID3TagFromMessage(msg)
+		thumbnail := ""
+		if len(msg.Series) > 0 {
+			if seri, ok := cat.FindSeriByName(msg.Series[0].Name); ok {
+				thumbnail = seri.Thumbnail
+			}
+		}
+		// stand-alone messages use their own thumbnail, the same as their series in the catalog
+		if thumbnail == "" && msg.Thumb != nil {
+			thumbnail = msg.Thumb.URL
+		}
+		tag.Cover, tag.CoverType = getCoverArt(cmd.Context(), msg.Ministry, thumbnail)
+
+		if err := tagAudio(audioPath, &tag); err != nil {
+			return err
+		}
+		if upload {
+			store, err := getAudioStorage()
+			if err != nil {
+				return err
+			}
+			if err := uploadFileToStorage(store, audioPath, getAudioKey(audioPath), "audio/mpeg"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// tagAudio writes the ID3 tag into an audio file
+func tagAudio(audioPath string, tag *catalog.ID3Tag) error {
+	fmt.Printf("Tagging: %s\n", filepath.Base(audioPath))
+	fmt.Printf("  title: %s\n", tag.Title)
+	if tag.Album != "" {
+		fmt.Printf(" series: %s #%d\n", tag.Album, tag.Track)
+	}
+	return catalog.WriteID3Tag(audioPath, tag)
+}
+
+// newRecordingID3Tag creates the tag for audio that was just extracted from a recording, which
+// isn't in the catalog yet. The title, year, and ministry come from the name of the file, like
+// "2025-03-09 CORE Title.mp3", and the summary is the comment
+func newRecordingID3Tag(audioPath string, speakerName string, summary string) catalog.ID3Tag {
+	ministry := getMinistryFromFileName(audioPath)
+	if ministry == catalog.UnknownMinistry {
+		ministry = catalog.WordOfLife
+	}
+
+	tag := catalog.ID3Tag{
+		Artist:    speakerName,
+		Publisher: ministry.Description(),
+		Comment:   summary,
+	}
+	name := strings.TrimSuffix(filepath.Base(audioPath), filepath.Ext(audioPath))
+	var title []string
+	for index, word := range strings.Fields(name) {
+		if index == 0 && len(word) >= 10 {
+			if date, err := catalog.ParseDateOnly(word[:10]); err == nil {
+				tag.Year = date.Year()
+				continue
+			}
+		}
+		if word == strings.ToUpper(word) && catalog.NewMinistryFromString(word) == ministry {
+			continue
+		}
+		title = append(title, word)
+	}
+	tag.Title = strings.Join(title, " ")
+	if tag.Title == "" {
+		tag.Title = name
+	}
+	tag.Cover, tag.CoverType = getCoverArt(context.Background(), ministry, "")
+	return tag
+}
+
+// findMessageByAudioFile finds the message in the catalog whose audio has the same file name as
+// an audio file. Returns nil if there isn't one
+func findMessageByAudioFile(cat *catalog.Catalog, audioPath string) *catalog.CatalogMessage {
+	name := filepath.Base(audioPath)
+	for index := range cat.Messages {
+		msg := &cat.Messages[index]
+		if !msg.HasAudio() {
+			continue
+		}
+		audioURL, err := url.Parse(msg.Audio.URL)
+		if err != nil {
+			continue
+		}
+		if strings.EqualFold(strings.ReplaceAll(path.Base(audioURL.Path), "+", " "), name) {
+			return msg
+		}
+	}
+	return nil
+}
+
+// getCoverArt gets the cover art for audio and its MIME type. The thumbnail can be a URL or the
+// path of an image in the catalog, and if it's "" or can't be read then the default thumbnail of
+// the ministry is used. Returns nil if there is no cover art
+func getCoverArt(ctx context.Context, ministry catalog.Ministry, thumbnail string) ([]byte, string) {
+	for _, image := range []string{thumbnail, ministry.Thumbnail()} {
+		if image == "" {
+			continue
+		}
+		data, err := readCoverArt(ctx, image)
+		if err != nil {
+			log.Printf("WARNING: Cannot read cover art %s: %s", image, err.Error())
+			continue
+		}
+		return data, http.DetectContentType(data)
+	}
+	return nil, ""
+}
+
+// readCoverArt reads an image from a URL, or from the template directory if it is a path in the
+// catalog
+func readCoverArt(ctx context.Context, image string) ([]byte, error) {
+	if !strings.Contains(image, "://") {
+		templateDir, err := getTemplateDir()
+		if err != nil {
+			return nil, err
+		}
+		return os.ReadFile(filepath.Join(templateDir, filepath.FromSlash(image)))
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, image, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unsuccessful status code: %d", response.StatusCode)
+	}
+	return io.ReadAll(response.Body)
+}
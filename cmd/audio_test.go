@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/WordOfLifeMN/online/storage"
+	"github.com/WordOfLifeMN/online/util"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestAudioCmdTestSuite(t *testing.T) {
+	suite.Run(t, new(AudioCmdTestSuite))
+}
+
+type AudioCmdTestSuite struct {
+	suite.Suite
+}
+
+// newRecording creates the audio of a recording and its transcripts in a directory
+func (t *AudioCmdTestSuite) newRecording(dir string, name string) string {
+	audioPath := filepath.Join(dir, name+".mp3")
+	t.Require().NoError(os.MkdirAll(filepath.Join(dir, "xscript"), 0777))
+	t.Require().NoError(os.WriteFile(audioPath, []byte("MP3"), 0666))
+	t.Require().NoError(os.WriteFile(getTranscribePathFromAudioPath(audioPath, ".text"), []byte("Good morning"), 0666))
+	t.Require().NoError(os.WriteFile(getTranscribePathFromAudioPath(audioPath, ".vtt"), []byte("WEBVTT"), 0666))
+	return audioPath
+}
+
+func (t *AudioCmdTestSuite) TestUploadAudio() {
+	dir := t.T().TempDir()
+	store, err := storage.Open(filepath.Join(dir, "bucket"), storage.Options{})
+	t.Require().NoError(err)
+	audioPath := t.newRecording(filepath.Join(dir, "video"), "2025-03-09 CORE Faith & Hope")
+
+	url, err := uploadAudio(store, audioPath)
+	t.Require().NoError(err)
+	t.Equal("https://s3.us-west-2.amazonaws.com/wordoflife.mn.audio/2025/2025-03-09+CORE+Faith+&+Hope.mp3", url)
+
+	content, err := store.Get(context.Background(), "2025/2025-03-09 CORE Faith & Hope.mp3")
+	t.Require().NoError(err)
+	t.Equal("MP3", string(content))
+
+	// uploading the same audio again is fine
+	_, err = uploadAudio(store, audioPath)
+	t.NoError(err)
+
+	_, err = uploadAudio(store, filepath.Join(dir, "2025-03-16 Missing.mp3"))
+	t.Error(err)
+}
+
+func (t *AudioCmdTestSuite) TestUploadTranscriptions() {
+	dir := t.T().TempDir()
+	store, err := storage.Open(filepath.Join(dir, "bucket"), storage.Options{})
+	t.Require().NoError(err)
+	audioPath := t.newRecording(filepath.Join(dir, "video"), "2025-03-09 Faith")
+
+	urls, err := uploadTranscriptions(store, []string{
+		getTranscribePathFromAudioPath(audioPath, ".text"),
+		getTranscribePathFromAudioPath(audioPath, ".vtt"),
+	})
+	t.Require().NoError(err)
+	t.Equal([]string{
+		"https://s3.us-west-2.amazonaws.com/wordoflife.mn.audio/2025/xscript/2025-03-09+Faith.text",
+		"https://s3.us-west-2.amazonaws.com/wordoflife.mn.audio/2025/xscript/2025-03-09+Faith.vtt",
+	}, urls)
+
+	objects, err := store.List(context.Background(), "2025/xscript/")
+	t.Require().NoError(err)
+	t.Equal([]storage.Object{
+		{Key: "2025/xscript/2025-03-09 Faith.text", Size: 12, ETag: storage.ComputeETag([]byte("Good morning"))},
+		{Key: "2025/xscript/2025-03-09 Faith.vtt", Size: 6, ETag: storage.ComputeETag([]byte("WEBVTT"))},
+	}, objects)
+}
+
+// PNG_IMAGE is enough of a PNG image for its type to be detected
+const PNG_IMAGE = "\x89PNG\r\n\x1a\n"
+
+// useTemplateDir uses a template directory with default thumbnails for the ministries
+func (t *AudioCmdTestSuite) useTemplateDir() {
+	dir := t.T().TempDir()
+	t.Require().NoError(os.MkdirAll(filepath.Join(dir, "static"), 0777))
+	t.Require().NoError(os.WriteFile(filepath.Join(dir, "static", "core.default.thumbnail.png"), []byte(PNG_IMAGE+"CORE"), 0666))
+	t.Require().NoError(os.WriteFile(filepath.Join(dir, "static", "wol.default.thumbnail.png"), []byte(PNG_IMAGE+"WOL"), 0666))
+
+	previous := viper.Get("template-dir")
+	viper.Set("template-dir", dir)
+	t.T().Cleanup(func() { viper.Set("template-dir", previous) })
+}
+
+func (t *AudioCmdTestSuite) TestNewRecordingID3Tag() {
+	t.useTemplateDir()
+
+	t.Equal(catalog.ID3Tag{
+		Title:     "Faith & Hope",
+		Artist:    "Mary Peltz",
+		Year:      2025,
+		Publisher: "C.O.R.E.",
+		Comment:   "SUMMARY",
+		Cover:     []byte(PNG_IMAGE + "CORE"),
+		CoverType: "image/png",
+	}, newRecordingID3Tag("/video/2025-03-09 CORE Faith & Hope.mp3", "Mary Peltz", "SUMMARY"))
+
+	// recordings are for Word of Life unless they say otherwise
+	tag := newRecordingID3Tag("/video/2025-03-16 Hope.mp3", "", "")
+	t.Equal("Hope", tag.Title)
+	t.Equal("Word of Life", tag.Publisher)
+	t.Equal([]byte(PNG_IMAGE+"WOL"), tag.Cover)
+}
+
+func (t *AudioCmdTestSuite) TestGetCoverArt() {
+	t.useTemplateDir()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/series.jpg" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("\xff\xd8\xff\xe0JPEG"))
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	cover, coverType := getCoverArt(ctx, catalog.WordOfLife, server.URL+"/series.jpg")
+	t.Equal("\xff\xd8\xff\xe0JPEG", string(cover))
+	t.Equal("image/jpeg", coverType)
+
+	// the ministry default is used if the thumbnail can't be read
+	cover, coverType = getCoverArt(ctx, catalog.WordOfLife, server.URL+"/missing.jpg")
+	t.Equal(PNG_IMAGE+"WOL", string(cover))
+	t.Equal("image/png", coverType)
+
+	cover, _ = getCoverArt(ctx, catalog.WordOfLife, "static/missing.png")
+	t.Equal(PNG_IMAGE+"WOL", string(cover))
+}
+
+func (t *AudioCmdTestSuite) TestFindMessageByAudioFile() {
+	cat := &catalog.Catalog{
+		Messages: []catalog.CatalogMessage{
+			{Name: "VIDEO"},
+			{Name: "FEAR", Audio: catalog.NewResourceFromString("https://s3.us-west-2.amazonaws.com/wordoflife.mn.audio/2020/2020-10-11+Fear%2C+Part+1.mp3")},
+		},
+	}
+
+	msg := findMessageByAudioFile(cat, "/audio/2020-10-11 Fear, Part 1.mp3")
+	t.Require().NotNil(msg)
+	t.Equal("FEAR", msg.Name)
+	t.Nil(findMessageByAudioFile(cat, "/audio/2020-10-11 Fear, Part 2.mp3"))
+}
+
+// useFakeFFmpeg puts an ffmpeg on the path that writes "MP3" to the audio it is asked to extract,
+// and reports that the audio is clipped when it's analyzed. Returns the file that the arguments of
+// each run are appended to
+func (t *AudioCmdTestSuite) useFakeFFmpeg() string {
+	if runtime.GOOS == "windows" {
+		t.T().Skip("the fake ffmpeg is a shell script")
+	}
+	dir := t.T().TempDir()
+	runs := filepath.Join(dir, "runs.txt")
+	script := `#!/bin/sh
+echo "$@" >>'` + runs + `'
+for last; do :; done
+if [ "$last" != "-" ]; then
+	echo MP3 >"$last"
+	exit 0
+fi
+echo "[Parsed_astats_0 @ 0x1] Peak level dB: 0.000" >&2
+echo "[Parsed_astats_0 @ 0x1] RMS level dB: -18.000" >&2
+`
+	t.Require().NoError(os.WriteFile(filepath.Join(dir, "ffmpeg"), []byte(script), 0777))
+	t.T().Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	for key, value := range map[string]any{"audio-normalize": false, "audio-check": true, "audio-trim": -1.0} {
+		previous := viper.Get(key)
+		viper.Set(key, value)
+		t.T().Cleanup(func() { viper.Set(key, previous) })
+	}
+	return runs
+}
+
+func (t *AudioCmdTestSuite) TestProcessOneAudio_Rejected() {
+	runs := t.useFakeFFmpeg()
+	dir := t.T().TempDir()
+	info := &MessageInfo{VideoPath: filepath.Join(dir, "2025-03-09 Faith.mp4"), SpeakerName: "Vern Peltz"}
+	audioPath := filepath.Join(dir, "2025-03-09 Faith.mp3")
+	rejectedPath := filepath.Join(dir, "2025-03-09 Faith.rejected.mp3")
+
+	// the clipped audio is moved out of the way instead of being uploaded
+	err := processOneAudio(nil, info)
+	if t.Error(err) {
+		t.Contains(err.Error(), "has problems")
+		t.Contains(err.Error(), "2025-03-09 Faith.rejected.mp3")
+	}
+	t.False(util.IsFile(audioPath))
+	t.True(util.IsFile(rejectedPath))
+	t.Empty(info.AudioURL)
+
+	// running again extracts and checks the audio again
+	err = processOneAudio(nil, &MessageInfo{VideoPath: info.VideoPath, SpeakerName: info.SpeakerName})
+	t.Error(err)
+	t.False(util.IsFile(audioPath))
+	t.True(util.IsFile(rejectedPath))
+
+	content, err := os.ReadFile(runs)
+	t.Require().NoError(err)
+	t.Equal(2, strings.Count(string(content), audioPath+"\n"))
+}
+
+func (t *AudioCmdTestSuite) TestNormalizeIsOptional() {
+	// normalizing takes an extra pass over the recording and changes the level of the audio, so
+	// it's only done when asked for
+	defaultValue := audioCmd.PersistentFlags().Lookup("normalize").DefValue
+	t.Equal("false", defaultValue)
+
+	// ffmpeg only runs to find the end of the intro, extract the audio, and check it
+	runs := t.useFakeFFmpeg()
+	viper.Set("audio-normalize", defaultValue)
+	dir := t.T().TempDir()
+	_, err := extractAudioFromVideo(filepath.Join(dir, "2025-03-09 Faith.mp4"), "Vern Peltz")
+	t.Error(err) // the fake audio is clipped
+	content, err := os.ReadFile(runs)
+	t.Require().NoError(err)
+	t.Equal(3, strings.Count(string(content), "\n"))
+	t.NotContains(string(content), "loudnorm")
+}
+
+func (t *AudioCmdTestSuite) TestRejectAudio() {
+	dir := t.T().TempDir()
+	audioPath := filepath.Join(dir, "2025-03-09 Faith.mp3")
+	t.Require().NoError(os.WriteFile(filepath.Join(dir, "2025-03-09 Faith.rejected.mp3"), []byte("OLD"), 0666))
+	t.Require().NoError(os.WriteFile(audioPath, []byte("NEW"), 0666))
+
+	rejectedPath, err := rejectAudio(audioPath)
+	t.Require().NoError(err)
+	t.Equal(filepath.Join(dir, "2025-03-09 Faith.rejected.mp3"), rejectedPath)
+	t.False(util.IsFile(audioPath))
+	content, err := os.ReadFile(rejectedPath)
+	t.Require().NoError(err)
+	t.Equal("NEW", string(content))
+
+	_, err = rejectAudio(audioPath)
+	t.Error(err)
+}
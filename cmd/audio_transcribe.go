@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/WordOfLifeMN/online/storage"
 	"github.com/WordOfLifeMN/online/util"
 	"github.com/spf13/cobra"
 )
@@ -21,18 +22,18 @@ var audioTranscribeCmd = &cobra.Command{
 The input file must be .mp3 and the output will be generated as both a text and
 a vtt file in the 'xscript' sub-directory.
 
-The resulting text files will be uploaded to AWS S3 to the wordoflife.mn.audio bucket
-as 
+The resulting text files will be uploaded to the audio storage (--audio-storage,
+the AWS S3 wordoflife.mn.audio bucket by default) as
 - s3://wordoflife.mn.audio/{year}/xscript/{txt-file-name}
 - s3://wordoflife.mn.audio/{year}/xscript/{vtt-file-name}
 
-Requires 'whisper' and 'aws' be installed and accessible on the path.`,
+Requires 'whisper' be installed and accessible on the path.`,
 	RunE: audioTranscribe,
 }
 
 type xscriptInfo struct {
 	path    string
-	s3URL   string
+	key     string
 	httpURL string
 }
 
@@ -58,6 +59,11 @@ func audioTranscribe(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	store, err := getAudioStorage()
+	if err != nil {
+		return err
+	}
+
 	textPaths, err := transcribeAudio(audioPath)
 	if err != nil {
 		return err
@@ -66,7 +72,7 @@ func audioTranscribe(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("transcribing %s returned no output text", audioPath)
 	}
 
-	if _, err = uploadTranscriptionsToS3(textPaths); err != nil {
+	if _, err = uploadTranscriptions(store, textPaths); err != nil {
 		return err
 	}
 	return nil
@@ -135,7 +141,9 @@ func transcribeAudio(audioPath string) ([]string, error) {
 	return xscriptPaths[0:2], nil
 }
 
-func uploadTranscriptionsToS3(xscriptPaths []string) ([]string, error) {
+// uploadTranscriptions uploads the transcription files to the xscript directory of the year in
+// the audio storage and returns their HTTP URLs
+func uploadTranscriptions(store storage.Storage, xscriptPaths []string) ([]string, error) {
 	var xscriptURLs []string
 	s3Bucket := "wordoflife.mn.audio"
 
@@ -148,8 +156,8 @@ func uploadTranscriptionsToS3(xscriptPaths []string) ([]string, error) {
 		// compute all the file references
 		year := filepath.Base(p)[0:4]
 		xscripts = append(xscripts, xscriptInfo{
-			path:  p,
-			s3URL: fmt.Sprintf("s3://%s/%s/xscript/%s", s3Bucket, year, filepath.Base(p)),
+			path: p,
+			key:  fmt.Sprintf("%s/xscript/%s", year, filepath.Base(p)),
 			httpURL: fmt.Sprintf("https://s3.us-west-2.amazonaws.com/%s/%s/xscript/%s",
 				s3Bucket, year, strings.ReplaceAll(filepath.Base(p), " ", "+")),
 		})
@@ -158,7 +166,7 @@ func uploadTranscriptionsToS3(xscriptPaths []string) ([]string, error) {
 	// write the expectations
 	for _, info := range xscripts {
 		fmt.Printf("Uploading: %s\n", filepath.Base(info.path))
-		fmt.Printf("       to: %s\n", "xscript\\"+filepath.Base(info.s3URL))
+		fmt.Printf("       to: %s\n", "xscript\\"+filepath.Base(info.key))
 	}
 	fmt.Printf("╭───────────────────────────────────────────────────────────────────────────────────┄┄\n")
 	fmt.Printf("│ Public HTML reference for transcription files\n")
@@ -168,11 +176,8 @@ func uploadTranscriptionsToS3(xscriptPaths []string) ([]string, error) {
 	fmt.Printf("╰───────────────────────────────────────────────────────────────────────────────────┄┄\n")
 
 	for _, info := range xscripts {
-		cmd := exec.Command("aws", "s3", "cp", "--content-type", "text/plain", info.path, info.s3URL)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			fmt.Printf("Unable to upload file to S3: %s\n", err)
+		if err := uploadFileToStorage(store, info.path, info.key, "text/plain"); err != nil {
+			fmt.Printf("Unable to upload file: %s\n", err)
 			return xscriptURLs, err
 		}
 		xscriptURLs = append(xscriptURLs, info.httpURL)
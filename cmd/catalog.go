@@ -1,20 +1,24 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"html/template"
 	"io"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	texttemplate "text/template"
 	"time"
 
 	"github.com/WordOfLifeMN/online/catalog"
 	"github.com/WordOfLifeMN/online/util"
-	"github.com/otiai10/copy"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 type catalogCmdStruct struct {
@@ -25,11 +29,18 @@ type catalogCmdStruct struct {
 	View      string // which view to generate catalog for, "all" or "*" for all
 	OutputDir string // directory to write output to
 	Days      int    // number of days to include in recent messages
+	Scan      bool   // find scripture references in the transcripts too
+	Search    bool   // generate the transcript search pages
+	Xscripts  string // directory with a local copy of the transcripts, "" to download them
+	Clean     bool   // delete everything in the output directory instead of only what changed
 
 	// internal reference
-	cat           *catalog.Catalog   // the catalog to process
-	template      *template.Template // html templates for generating pages
-	templateError error              // cached error from trying to load a template
+	cat             *catalog.Catalog       // the catalog to process
+	template        *template.Template     // html templates for generating pages
+	podcastTemplate *texttemplate.Template // xml templates for generating seri podcast feeds
+	templateError   error                  // cached error from trying to load a template
+	output          catalogOutput          // where the generated files are written
+	manifest        *outputManifest        // files written to the output directory, nil if not writing to a directory
 }
 
 const (
@@ -47,7 +58,17 @@ func init() {
 			Long: `Generates the online catalog.
 	
 By default this generates the catalog for all views and ministries, but can 
-be limited with the parameters.`,
+be limited with the parameters.
+
+Only the files whose contents changed are written. A manifest of the files
+(online.manifest.json) is kept in the output directory with a hash of each
+file, and lists the files that were written or removed by the last run. Files
+that are no longer generated are removed. Use --clean to start over with an
+empty output directory.
+
+Each ministry also gets a "coming soon" page of its scheduled messages and an
+iCalendar file (calendar.<ministry>.ics) that people can subscribe to. The
+events are at --service-time in --time-zone on the date of each message.`,
 			RunE: func(cmd *cobra.Command, args []string) error {
 				return catalogCmd.catalog()
 			},
@@ -60,6 +81,17 @@ be limited with the parameters.`,
 	catalogCmd.Flags().StringVar(&catalogCmd.View, "view", "all", "View for catalog: all (default), public, partner, private")
 	catalogCmd.Flags().StringVarP(&catalogCmd.OutputDir, "output", "o", "~/.wolm/online", "Output directory. Defaults to $HOME/.wolm/online")
 	catalogCmd.Flags().IntVar(&catalogCmd.Days, "days", 60, "Number of days to include in the recent message pages. Defaults to 60")
+	catalogCmd.Flags().BoolVar(&catalogCmd.Scan, "scan-transcripts", false, "Also find scripture references in the message transcripts (downloads every transcript)")
+	catalogCmd.Flags().BoolVar(&catalogCmd.Search, "search", false, "Generate the transcript search pages (downloads every transcript)")
+	catalogCmd.Flags().BoolVar(&catalogCmd.Clean, "clean", false, "Delete everything in the output directory and generate all the files again")
+	catalogCmd.Flags().StringVar(&catalogCmd.Xscripts, "transcripts", "", "Directory with a local copy of the audio bucket to read transcripts from instead of downloading them")
+
+	catalogCmd.Flags().String("service-time", "10:00", "Time of day (24 hour) that scheduled messages are taught, for the calendars")
+	viper.BindPFlag("service-time", catalogCmd.Flags().Lookup("service-time"))
+	catalogCmd.Flags().Duration("service-length", 90*time.Minute, "How long a service is, for the calendars")
+	viper.BindPFlag("service-length", catalogCmd.Flags().Lookup("service-length"))
+	catalogCmd.Flags().String("time-zone", "America/Chicago", "Time zone of the service time")
+	viper.BindPFlag("time-zone", catalogCmd.Flags().Lookup("time-zone"))
 }
 
 func (cmd *catalogCmdStruct) catalog() error {
@@ -67,33 +99,24 @@ func (cmd *catalogCmdStruct) catalog() error {
 	log.Printf("Generating catalog in directory %s", cmd.OutputDir)
 
 	// find ministries to generate catalogs for
-	var ministries []catalog.Ministry
-	ministry := catalog.NewMinistryFromString(cmd.Ministry)
-	if ministry != catalog.UnknownMinistry {
-		ministries = append(ministries, ministry)
-	} else if cmd.Ministry == "all" || cmd.Ministry == "*" {
-		ministries = append([]catalog.Ministry{}, catalog.AllMinistries...)
-	} else {
-		return fmt.Errorf("unknown ministry '%s'", cmd.Ministry)
+	ministries, err := parseMinistriesFlag(cmd.Ministry)
+	if err != nil {
+		return err
 	}
 
 	// find views to generate catalogs for
-	var views []catalog.View
-	view := catalog.NewViewFromString(cmd.View)
-	if view != catalog.UnknownView {
-		views = append(views, view)
-	} else if cmd.View == "all" || cmd.View == "*" {
-		views = []catalog.View{
-			catalog.Public,
-			catalog.Partner,
-			// catalog.Private,
-		}
-	} else {
-		return fmt.Errorf("unknown view '%s'", cmd.View)
+	views, err := parseViewsFlag(cmd.View)
+	if err != nil {
+		return err
+	}
+
+	// find when the scheduled messages are taught
+	schedule, err := newServiceSchedule()
+	if err != nil {
+		return err
 	}
 
 	// get the catalog
-	var err error
 	cmd.cat, err = readOnlineContentFromInput(cmd.Context())
 	if err != nil {
 		return err
@@ -104,7 +127,25 @@ func (cmd *catalogCmdStruct) catalog() error {
 	if err := cmd.cat.Initialize(); err != nil {
 		return err
 	}
+	if cmd.Xscripts != "" {
+		catalog.SetTranscriptDir(util.NormalizePath(cmd.Xscripts))
+	}
+	if cmd.Search {
+		log.Printf("Reading transcripts")
+		cmd.loadTranscripts()
+	}
+	if cmd.Scan {
+		log.Printf("Scanning transcripts for scripture references")
+		cmd.cat.AddScripturesFromTranscripts()
+	}
+
+	return cmd.writeCatalog(ministries, views, schedule)
+}
 
+// writeCatalog generates the catalog for the ministries and views into the output directory,
+// writing only the files that changed and removing the ones that are no longer generated. The
+// catalog must already be read and initialized
+func (cmd *catalogCmdStruct) writeCatalog(ministries []catalog.Ministry, views []catalog.View, schedule serviceSchedule) error {
 	// set up the output directory
 	if err := cmd.initializeOutputDir(); err != nil {
 		return err
@@ -115,6 +156,27 @@ func (cmd *catalogCmdStruct) catalog() error {
 		return fmt.Errorf("unable to load templates for generating the catalog: %w", err)
 	}
 
+	// generate all the files
+	if err := cmd.generate(ministries, views, schedule); err != nil {
+		return err
+	}
+
+	// clean up and remember what changed
+	if err := cmd.manifest.RemoveStaleFiles(); err != nil {
+		return err
+	}
+	if err := cmd.manifest.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Catalog generated in %s: %d files written, %d removed, %d unchanged\n",
+		cmd.OutputDir, len(cmd.manifest.Changed), len(cmd.manifest.Removed), len(cmd.manifest.Files)-len(cmd.manifest.Changed))
+
+	return nil
+}
+
+// generate writes all the files of the catalog for the ministries and views to the output. The
+// catalog must already be read and initialized
+func (cmd *catalogCmdStruct) generate(ministries []catalog.Ministry, views []catalog.View, schedule serviceSchedule) error {
 	// set up the static files
 	if err := cmd.copyStaticFilesToOutputDir(ministries); err != nil {
 		return err
@@ -151,6 +213,52 @@ func (cmd *catalogCmdStruct) catalog() error {
 		}
 	}
 
+	// generate the speaker pages
+	log.Printf("Generating speaker pages")
+	for _, ministry := range ministries {
+		for _, view := range views {
+			log.Printf("  Ministry %s (%s)", ministry.Description(), string(view))
+			if err := cmd.createAllSpeakerPages(ministry, view); err != nil {
+				return err
+			}
+		}
+	}
+
+	// generate the tag pages
+	log.Printf("Generating tag pages")
+	for _, ministry := range ministries {
+		for _, view := range views {
+			log.Printf("  Ministry %s (%s)", ministry.Description(), string(view))
+			if err := cmd.createAllTagPages(ministry, view); err != nil {
+				return err
+			}
+		}
+	}
+
+	// generate the scripture index pages
+	log.Printf("Generating scripture index pages")
+	for _, ministry := range ministries {
+		for _, view := range views {
+			log.Printf("  Ministry %s (%s)", ministry.Description(), string(view))
+			if err := cmd.createScriptureIndexPage(ministry, view); err != nil {
+				return err
+			}
+		}
+	}
+
+	// generate the transcript search pages
+	if cmd.Search {
+		log.Printf("Generating transcript search pages")
+		for _, ministry := range ministries {
+			for _, view := range views {
+				log.Printf("  Ministry %s (%s)", ministry.Description(), string(view))
+				if err := cmd.createSearchPage(ministry, view); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
 	// generate recent messages
 	log.Printf("Generating recent message pages")
 	for _, ministry := range ministries {
@@ -174,9 +282,69 @@ func (cmd *catalogCmdStruct) catalog() error {
 		}
 	}
 
+	// generate the scheduled messages and their calendars
+	log.Printf("Generating coming soon pages and calendars")
+	for _, ministry := range ministries {
+		log.Printf("  Ministry %s", ministry.Description())
+		if err := cmd.createComingSoonPage(ministry); err != nil {
+			return err
+		}
+		if err := cmd.createCalendar(ministry, schedule); err != nil {
+			return err
+		}
+	}
+
+	// tell search engines what to crawl
+	if slices.Contains(views, catalog.Public) {
+		log.Printf("Creating site map")
+		if err := cmd.createSitemap(ministries); err != nil {
+			return err
+		}
+		if err := cmd.createRobotsFile(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// findSeriesForMinistryView finds all the series that are displayed in the pages of a ministry
+// and view. The series only contain the messages that are visible in the view
+func (cmd *catalogCmdStruct) findSeriesForMinistryView(ministry catalog.Ministry, view catalog.View) []catalog.CatalogSeri {
+	seriList := cmd.cat.FindSeriesByMinistry(ministry)
+	return catalog.FilterSeriesByView(seriList, view)
+}
+
+// parseMinistriesFlag converts the value of a --ministry flag into a list of ministries. The
+// value can be a single ministry, or "all" or "*" for all ministries
+func parseMinistriesFlag(value string) ([]catalog.Ministry, error) {
+	ministry := catalog.NewMinistryFromString(value)
+	if ministry != catalog.UnknownMinistry {
+		return []catalog.Ministry{ministry}, nil
+	}
+	if value == "all" || value == "*" {
+		return append([]catalog.Ministry{}, catalog.AllMinistries()...), nil
+	}
+	return nil, fmt.Errorf("unknown ministry '%s'", value)
+}
+
+// parseViewsFlag parses the --view flag into the list of views to generate catalogs for. "all"
+// or "*" is the public and partner views
+func parseViewsFlag(value string) ([]catalog.View, error) {
+	view := catalog.NewViewFromString(value)
+	if view != catalog.UnknownView {
+		return []catalog.View{view}, nil
+	}
+	if value == "all" || value == "*" {
+		return []catalog.View{
+			catalog.Public,
+			catalog.Partner,
+			// catalog.Private,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown view '%s'", value)
+}
+
 // loadTemplates all the templates for processing catalog files. Finds all the templates that
 // match catalog.*.html in the templates directory. If this returns an error, then the templates
 // could not be loaded and subsequent calls to the print methods will fail
@@ -213,7 +381,19 @@ func (cmd *catalogCmdStruct) loadTemplates() error {
 			}
 			return dict, nil
 		},
-		"GetCatalogFileNameForSeriList": GetCatalogFileNameForSeriList,
+		// the message and seri divs are passed the ministry and view of the page they are listed
+		// on. these take pointers so the templates can call the methods of the message and seri
+		"messageDiv": func(message *catalog.CatalogMessage, ministry catalog.Ministry, view catalog.View) map[string]interface{} {
+			return map[string]interface{}{"Message": message, "Ministry": ministry, "View": view}
+		},
+		"seriDiv": func(seri *catalog.CatalogSeri, ministry catalog.Ministry, view catalog.View) map[string]interface{} {
+			return map[string]interface{}{"Seri": seri, "Ministry": ministry, "View": view}
+		},
+		"GetCatalogFileNameForSeriList":   GetCatalogFileNameForSeriList,
+		"GetCatalogFileNameForComingSoon": GetCatalogFileNameForComingSoon,
+		"GetCatalogFileNameForScriptures": catalog.GetCatalogFileNameForScriptures,
+		"GetCatalogFileNameForTags":       catalog.GetCatalogFileNameForTags,
+		"GetCatalogFileNameForTag":        catalog.GetCatalogFileNameForTag,
 	})
 
 	// parse the templates
@@ -233,6 +413,12 @@ func (cmd *catalogCmdStruct) loadTemplates() error {
 		return err
 	}
 
+	// the seri podcast feeds are rendered with the podcast templates
+	cmd.podcastTemplate, err = loadPodcastTemplates()
+	if err != nil {
+		return err
+	}
+
 	log.Printf("Loaded templates%s", cmd.template.DefinedTemplates())
 
 	return nil
@@ -242,11 +428,12 @@ func (cmd *catalogCmdStruct) loadTemplates() error {
 // | Output directory management
 // ----------------------------------------------------------------------------
 
-// initializeOutputDir sets up the output directory by making sure it exists and is empty except
-// for the flag file. If the directory already exists and contains the flag file, the contents
-// will be deleted so we can start with a clean slate. If the directory exists but does not
-// contain the flag file, then an error will be returned because this might not be a directory
-// it is safe to delete
+// initializeOutputDir sets up the output directory by making sure it exists and has the flag
+// file, and reads the manifest of the files that were generated in it last time. If the
+// directory already exists and contains the flag file, then it is only emptied if --clean was
+// given or it doesn't have a manifest (it was generated before there were manifests). If the
+// directory exists but does not contain the flag file, then an error will be returned because
+// this might not be a directory it is safe to write to
 func (cmd *catalogCmdStruct) initializeOutputDir() error {
 	if cmd.OutputDir == "" {
 		return fmt.Errorf("no output directory specified")
@@ -262,22 +449,30 @@ before running again, either delete this directory or create the file
 			cmd.OutputDir, FLAG_FILE_NAME, cmd.OutputDir, FLAG_FILE_NAME)
 	}
 
-	// delete the directory and all the files
-	if err := os.RemoveAll(cmd.OutputDir); err != nil {
-		return fmt.Errorf("unable to delete all the files in %s", cmd.OutputDir)
+	// delete the directory and all the files if we're starting over
+	if cmd.Clean || !util.IsFile(filepath.Join(cmd.OutputDir, MANIFEST_FILE_NAME)) {
+		if err := os.RemoveAll(cmd.OutputDir); err != nil {
+			return fmt.Errorf("unable to delete all the files in %s", cmd.OutputDir)
+		}
 	}
 
 	// create the directory and our flag-file
 	if err := os.MkdirAll(cmd.OutputDir, os.FileMode(0777)); err != nil {
 		return fmt.Errorf("cannot create the output directory %s: %w", cmd.OutputDir, err)
 	}
-	flagFile, err := os.Create(filepath.Join(cmd.OutputDir, FLAG_FILE_NAME))
-	if err != nil {
-		return fmt.Errorf("unable to create file %s", filepath.Join(cmd.OutputDir, FLAG_FILE_NAME))
+	if !util.IsFile(filepath.Join(cmd.OutputDir, FLAG_FILE_NAME)) {
+		flagFile, err := os.Create(filepath.Join(cmd.OutputDir, FLAG_FILE_NAME))
+		if err != nil {
+			return fmt.Errorf("unable to create file %s", filepath.Join(cmd.OutputDir, FLAG_FILE_NAME))
+		}
+		flagFile.Close()
 	}
-	flagFile.Close()
 
-	return nil
+	// find out what was generated last time
+	var err error
+	cmd.manifest, err = newOutputManifest(cmd.OutputDir)
+	cmd.output = cmd.manifest
+	return err
 }
 
 // copyStaticFilesToOutputDir copies all the static files to the output directory. This includes
@@ -299,23 +494,35 @@ func (cmd *catalogCmdStruct) copyStaticFilesToOutputDir(ministries []catalog.Min
 	// build a list of the prefixes we need to copy
 	prefixesToCopy := []string{"all.", "css", "pico."}
 	for _, ministry := range ministries {
-		prefixesToCopy = append(prefixesToCopy, string(ministry)+".")
+		prefixesToCopy = append(prefixesToCopy, ministry.StaticFilePrefixes()...)
+		if logo := ministry.Logo(); logo != "" {
+			prefixesToCopy = append(prefixesToCopy, strings.TrimPrefix(logo, "static/"))
+		}
 	}
 
 	// copy the files
 	log.Printf("Copying static files:")
-	opt := copy.Options{
-		Skip: func(src string) (bool, error) {
-			for _, prefix := range prefixesToCopy {
-				if strings.HasPrefix(filepath.Base(src), prefix) {
-					log.Printf("  %s", src)
-					return false, nil
-				}
-			}
-			return true, nil
-		},
-	}
-	return copy.Copy(sourceDir, targetDir, opt)
+	return filepath.WalkDir(sourceDir, func(src string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		if !slices.ContainsFunc(prefixesToCopy, func(prefix string) bool {
+			return strings.HasPrefix(entry.Name(), prefix)
+		}) {
+			return nil
+		}
+
+		log.Printf("  %s", src)
+		content, err := os.ReadFile(src)
+		if err != nil {
+			return fmt.Errorf("cannot read static file %s: %w", src, err)
+		}
+		relPath, err := filepath.Rel(sourceDir, src)
+		if err != nil {
+			return err
+		}
+		return cmd.output.WriteFile(filepath.Join(targetDir, relPath), content)
+	})
 }
 
 // getOutputFilePath generates the path of an output file based on the output parameters. Given a
@@ -332,14 +539,13 @@ func (cmd *catalogCmdStruct) getOutputFilePath(fileName string) string {
 func (cmd *catalogCmdStruct) createStyleSheet(ministry catalog.Ministry) error {
 	// create the file
 	filePath := cmd.getOutputFilePath(fmt.Sprintf("catalog.%s.v3.css", string(ministry)))
-	f, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("cannot create style sheet file %s", filePath)
-	}
-	defer f.Close()
 
 	// write the style sheet to it
-	return cmd.printCatalogStyle(ministry, f)
+	var buf bytes.Buffer
+	if err := cmd.printCatalogStyle(ministry, &buf); err != nil {
+		return fmt.Errorf("cannot print style sheet %s: %w", filePath, err)
+	}
+	return cmd.output.WriteFile(filePath, buf.Bytes())
 }
 
 // printCatalogStyle prints the style sheet file (CSS) for the specific ministry to the writer
@@ -371,15 +577,7 @@ const (
 // createAllCatalogSeriesPages generates all the series list pages for a specific ministry and
 // view. The name of each page will be generated by generateSeriesListPageName()
 func (cmd *catalogCmdStruct) createAllCatalogSeriesPages(ministry catalog.Ministry, view catalog.View) error {
-	var seriList []catalog.CatalogSeri
-	switch ministry {
-	case catalog.WordOfLife:
-		// 2022-12: Pastor wants ATP messages on both ATP and WOL pages
-		seriList = cmd.cat.FindSeriesByMinistry(catalog.WordOfLife, catalog.AskThePastor)
-	default:
-		seriList = cmd.cat.FindSeriesByMinistry(ministry)
-	}
-	seriList = catalog.FilterSeriesByView(seriList, view)
+	seriList := cmd.findSeriesForMinistryView(ministry, view)
 
 	if len(seriList) == 0 {
 		log.Printf("    (no series found)")
@@ -399,18 +597,15 @@ func (cmd *catalogCmdStruct) createAllCatalogSeriesPages(ministry catalog.Minist
 			sort.Sort(catalog.SortSeriNewestToOldest(seriList))
 		}
 
-		// create the file name
-		f, err := os.Create(filePath)
-		if err != nil {
-			return fmt.Errorf("cannot create output file %s: %w", filePath, err)
-		}
-		defer f.Close()
-
 		// write the series list
-		err = cmd.printCatalogSeriList(ministry, view, order, seriList, f)
+		var buf bytes.Buffer
+		err := cmd.printCatalogSeriList(ministry, view, order, seriList, &buf)
 		if err != nil {
 			return fmt.Errorf("cannot print series list to %s: %w", filePath, err)
 		}
+		if err := cmd.output.WriteFile(filePath, buf.Bytes()); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -442,12 +637,19 @@ func (cmd *catalogCmdStruct) printCatalogSeriList(
 		View     catalog.View
 		Order    string
 		Series   []catalog.CatalogSeri
+		Search   string // file name of the transcript search page, "" if there is none
+		Meta     pageMeta
 	}{
 		Date:     catalog.NewDateToday(),
 		Ministry: ministry,
 		View:     view,
 		Order:    order,
 		Series:   series,
+		Meta: newPageMeta(ministry, ministry.Description()+" Catalog",
+			GetCatalogFileNameForSeriList(ministry, view, order)),
+	}
+	if cmd.Search {
+		data.Search = catalog.GetCatalogFileNameForSearch(ministry, view)
 	}
 
 	return cmd.template.ExecuteTemplate(output, "catalog.series.html", data)
@@ -461,15 +663,7 @@ func (cmd *catalogCmdStruct) printCatalogSeriList(
 // view. The name of each page will be the series ID for public pages and a mystic hash if the
 // view is not public, this just makes the private pages harder to guess
 func (cmd *catalogCmdStruct) createAllCatalogSeriPages(ministry catalog.Ministry, view catalog.View) error {
-	var seriList []catalog.CatalogSeri
-	switch ministry {
-	case catalog.WordOfLife:
-		// 2022-12: Pastor wants ATP messages on both ATP and WOL pages
-		seriList = cmd.cat.FindSeriesByMinistry(catalog.WordOfLife, catalog.AskThePastor)
-	default:
-		seriList = cmd.cat.FindSeriesByMinistry(ministry)
-	}
-	seriList = catalog.FilterSeriesByView(seriList, view)
+	seriList := cmd.findSeriesForMinistryView(ministry, view)
 
 	if len(seriList) == 0 {
 		log.Printf("    (no series found)")
@@ -485,19 +679,44 @@ func (cmd *catalogCmdStruct) createAllCatalogSeriPages(ministry catalog.Ministry
 }
 
 // createCatalogSeri creates a catalog page for a single series to a file in the output
-// directory. The name of the page will be the series ID (+ .html)
+// directory. The name of the page will be the series ID (+ .html). If the series has audio, then
+// a podcast feed for the series is created next to the page (series ID + .rss.xml)
 func (cmd *catalogCmdStruct) createCatalogSeriPage(seri *catalog.CatalogSeri) error {
 	// file name is just the View ID
 	filePath := cmd.getOutputFilePath(seri.GetCatalogFileName(seri.View))
 	log.Printf("    %s --> %s", seri.Name, filePath)
 
-	f, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("cannot create output file %s: %w", filePath, err)
+	var buf bytes.Buffer
+	if err := cmd.printCatalogSeri(seri, &buf); err != nil {
+		return err
+	}
+	if err := cmd.output.WriteFile(filePath, buf.Bytes()); err != nil {
+		return err
+	}
+
+	return cmd.createCatalogSeriPodcast(seri)
+}
+
+// createCatalogSeriPodcast creates the podcast feed for a single series in the output directory.
+// Nothing is created if the series has no audio
+func (cmd *catalogCmdStruct) createCatalogSeriPodcast(seri *catalog.CatalogSeri) error {
+	feed := newSeriPodcastFeed(seri)
+	if feed == nil {
+		return nil
 	}
-	defer f.Close()
 
-	return cmd.printCatalogSeri(seri, f)
+	if err := cmd.loadTemplates(); err != nil {
+		return err
+	}
+
+	filePath := cmd.getOutputFilePath(seri.GetPodcastFileName(seri.View))
+	log.Printf("    %s (podcast) --> %s", seri.Name, filePath)
+
+	var buf bytes.Buffer
+	if err := cmd.podcastTemplate.ExecuteTemplate(&buf, "podcast.xml", feed); err != nil {
+		return fmt.Errorf("cannot print podcast to %s: %w", filePath, err)
+	}
+	return cmd.output.WriteFile(filePath, buf.Bytes())
 }
 
 // printCatalogSeri prints a catalog page for a single series to the writer
@@ -509,16 +728,331 @@ func (cmd *catalogCmdStruct) printCatalogSeri(seri *catalog.CatalogSeri, output
 	data := struct {
 		Date     catalog.DateOnly
 		Ministry catalog.Ministry
+		View     catalog.View
 		Seri     *catalog.CatalogSeri
+		Podcast  string // file name of the podcast feed for the seri, "" if there is none
+		Meta     pageMeta
 	}{
 		Date:     catalog.NewDateToday(),
 		Ministry: seri.GetMinistry(),
+		View:     seri.View,
 		Seri:     seri,
+		Meta:     newSeriPageMeta(seri),
+	}
+	if seri.HasAudio() {
+		data.Podcast = seri.GetPodcastFileName(seri.View)
 	}
 
 	return cmd.template.ExecuteTemplate(output, "catalog.seri.html", data)
 }
 
+// ----------------------------------------------------------------------------
+// | Pages containing all the messages of a speaker
+// ----------------------------------------------------------------------------
+
+// createAllSpeakerPages generates a page for each speaker in the series of a ministry and view.
+// The name of each page is generated by catalog.GetCatalogFileNameForSpeaker()
+func (cmd *catalogCmdStruct) createAllSpeakerPages(ministry catalog.Ministry, view catalog.View) error {
+	seriList := cmd.findSeriesForMinistryView(ministry, view)
+	speakers := getSpeakersOfSeries(seriList)
+
+	if len(speakers) == 0 {
+		log.Printf("    (no speakers found)")
+	}
+
+	for _, speaker := range speakers {
+		speakerSeries := catalog.FilterSeriesBySpeaker(seriList, speaker)
+		sort.Stable(catalog.SortSeriNewestToOldest(speakerSeries))
+
+		filePath := cmd.getOutputFilePath(catalog.GetCatalogFileNameForSpeaker(ministry, view, speaker))
+		log.Printf("    %s --> %s", speaker, filePath)
+
+		if err := cmd.createSpeakerPage(filePath, ministry, view, speaker, speakerSeries); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getSpeakersOfSeries gets the names of all the speakers of messages in the series, sorted
+func getSpeakersOfSeries(seriList []catalog.CatalogSeri) []string {
+	var speakers []string
+	for _, seri := range seriList {
+		for _, msg := range seri.Messages {
+			for _, speaker := range msg.Speakers {
+				if speaker != "" && !slices.Contains(speakers, speaker) {
+					speakers = append(speakers, speaker)
+				}
+			}
+		}
+	}
+	sort.Strings(speakers)
+	return speakers
+}
+
+// createSpeakerPage creates the page for a single speaker
+func (cmd *catalogCmdStruct) createSpeakerPage(
+	filePath string,
+	ministry catalog.Ministry,
+	view catalog.View,
+	speaker string,
+	series []catalog.CatalogSeri,
+) error {
+	var buf bytes.Buffer
+	if err := cmd.printSpeakerPage(ministry, view, speaker, series, &buf); err != nil {
+		return fmt.Errorf("cannot print speaker page to %s: %w", filePath, err)
+	}
+	return cmd.output.WriteFile(filePath, buf.Bytes())
+}
+
+// printSpeakerPage prints the page for a single speaker to the writer
+func (cmd *catalogCmdStruct) printSpeakerPage(
+	ministry catalog.Ministry,
+	view catalog.View,
+	speaker string,
+	series []catalog.CatalogSeri,
+	output io.Writer,
+) error {
+	if err := cmd.loadTemplates(); err != nil {
+		return err
+	}
+
+	messageCount := 0
+	for _, seri := range series {
+		messageCount += len(seri.Messages)
+	}
+
+	data := struct {
+		Date         catalog.DateOnly
+		Ministry     catalog.Ministry
+		View         catalog.View
+		Speaker      string
+		Profile      *catalog.Speaker
+		Series       []catalog.CatalogSeri
+		MessageCount int
+		Meta         pageMeta
+	}{
+		Date:         catalog.NewDateToday(),
+		Ministry:     ministry,
+		View:         view,
+		Speaker:      speaker,
+		Profile:      catalog.GetSpeakerRegistry().Find(speaker),
+		Series:       series,
+		MessageCount: messageCount,
+		Meta:         newPageMeta(ministry, speaker, catalog.GetCatalogFileNameForSpeaker(ministry, view, speaker)),
+	}
+
+	return cmd.template.ExecuteTemplate(output, "catalog.speaker.html", data)
+}
+
+// ----------------------------------------------------------------------------
+// | Pages containing the messages about a topic
+// ----------------------------------------------------------------------------
+
+// tagCount is a tag with the number of messages about it, for the tag cloud
+type tagCount struct {
+	Tag   string  // the tag
+	Count int     // number of messages with the tag
+	Size  float64 // font size of the tag in the cloud (rem)
+}
+
+// createAllTagPages generates the tag cloud page and a page for each tag in the series of a
+// ministry and view. The names of the pages are generated by catalog.GetCatalogFileNameForTags()
+// and catalog.GetCatalogFileNameForTag()
+func (cmd *catalogCmdStruct) createAllTagPages(ministry catalog.Ministry, view catalog.View) error {
+	seriList := cmd.findSeriesForMinistryView(ministry, view)
+	tags := getTagsOfSeries(seriList)
+
+	if len(tags) == 0 {
+		log.Printf("    (no tags found)")
+	}
+
+	// tag cloud
+	filePath := cmd.getOutputFilePath(catalog.GetCatalogFileNameForTags(ministry, view))
+	log.Printf("    tags for (%s,%s) --> %s", ministry, view, filePath)
+	if err := cmd.createTagPage(filePath, "catalog.tags.html", ministry, view, "", tags, nil); err != nil {
+		return err
+	}
+
+	// page for each tag
+	for _, tag := range tags {
+		tagSeries := catalog.FilterSeriesByTag(seriList, tag.Tag)
+		sort.Stable(catalog.SortSeriNewestToOldest(tagSeries))
+
+		filePath := cmd.getOutputFilePath(catalog.GetCatalogFileNameForTag(ministry, view, tag.Tag))
+		log.Printf("    %s --> %s", tag.Tag, filePath)
+
+		if err := cmd.createTagPage(filePath, "catalog.tag.html", ministry, view, tag.Tag, nil, tagSeries); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getTagsOfSeries gets all the tags of messages in the series with the number of messages that
+// have each tag, sorted by tag. The size of each tag scales from 0.8rem for the least used tag
+// to 2rem for the most used
+func getTagsOfSeries(seriList []catalog.CatalogSeri) []tagCount {
+	var tags []tagCount
+	indexOf := map[string]int{}  // lower-case tag to its index in tags
+	counted := map[string]bool{} // tag + message, so messages in several series count once
+	for _, seri := range seriList {
+		for _, msg := range seri.Messages {
+			for _, tag := range msg.Tags {
+				key := strings.ToLower(tag)
+				if counted[key+"|"+msg.Date.String()+"|"+msg.Name] {
+					continue
+				}
+				counted[key+"|"+msg.Date.String()+"|"+msg.Name] = true
+
+				if index, ok := indexOf[key]; ok {
+					tags[index].Count++
+					continue
+				}
+				indexOf[key] = len(tags)
+				tags = append(tags, tagCount{Tag: tag, Count: 1})
+			}
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return strings.ToLower(tags[i].Tag) < strings.ToLower(tags[j].Tag) })
+
+	// scale the sizes
+	least, most := 0, 0
+	for _, tag := range tags {
+		if least == 0 || tag.Count < least {
+			least = tag.Count
+		}
+		most = max(most, tag.Count)
+	}
+	for index := range tags {
+		tags[index].Size = 0.8
+		if most > least {
+			tags[index].Size += 1.2 * float64(tags[index].Count-least) / float64(most-least)
+		}
+	}
+
+	return tags
+}
+
+// createTagPage creates the tag cloud page or the page for a single tag
+func (cmd *catalogCmdStruct) createTagPage(
+	filePath string,
+	templateName string,
+	ministry catalog.Ministry,
+	view catalog.View,
+	tag string,
+	tags []tagCount,
+	series []catalog.CatalogSeri,
+) error {
+	var buf bytes.Buffer
+	if err := cmd.printTagPage(templateName, ministry, view, tag, tags, series, &buf); err != nil {
+		return fmt.Errorf("cannot print tag page to %s: %w", filePath, err)
+	}
+	return cmd.output.WriteFile(filePath, buf.Bytes())
+}
+
+// printTagPage prints the tag cloud page or the page for a single tag to the writer
+func (cmd *catalogCmdStruct) printTagPage(
+	templateName string,
+	ministry catalog.Ministry,
+	view catalog.View,
+	tag string,
+	tags []tagCount,
+	series []catalog.CatalogSeri,
+	output io.Writer,
+) error {
+	if err := cmd.loadTemplates(); err != nil {
+		return err
+	}
+
+	messageCount := 0
+	for _, seri := range series {
+		messageCount += len(seri.Messages)
+	}
+
+	data := struct {
+		Date         catalog.DateOnly
+		Ministry     catalog.Ministry
+		View         catalog.View
+		Tag          string
+		Tags         []tagCount
+		Series       []catalog.CatalogSeri
+		MessageCount int
+		Meta         pageMeta
+	}{
+		Date:         catalog.NewDateToday(),
+		Ministry:     ministry,
+		View:         view,
+		Tag:          tag,
+		Tags:         tags,
+		Series:       series,
+		MessageCount: messageCount,
+		Meta:         newPageMeta(ministry, ministry.Description()+" Topics", catalog.GetCatalogFileNameForTags(ministry, view)),
+	}
+	if tag != "" {
+		data.Meta = newPageMeta(ministry, tag, catalog.GetCatalogFileNameForTag(ministry, view, tag))
+	}
+
+	return cmd.template.ExecuteTemplate(output, templateName, data)
+}
+
+// ----------------------------------------------------------------------------
+// | Pages containing the scripture index
+// ----------------------------------------------------------------------------
+
+// createScriptureIndexPage generates the page that lists the messages of a ministry and view by
+// the scriptures they refer to. The name of the page is generated by
+// catalog.GetCatalogFileNameForScriptures()
+func (cmd *catalogCmdStruct) createScriptureIndexPage(ministry catalog.Ministry, view catalog.View) error {
+	seriList := cmd.findSeriesForMinistryView(ministry, view)
+	sort.Stable(catalog.SortSeriOldestToNewest(seriList))
+	index := catalog.NewScriptureIndex(seriList)
+
+	if len(index) == 0 {
+		log.Printf("    (no scriptures found)")
+	}
+
+	filePath := cmd.getOutputFilePath(catalog.GetCatalogFileNameForScriptures(ministry, view))
+	log.Printf("    scriptures for (%s,%s) --> %s", ministry, view, filePath)
+
+	var buf bytes.Buffer
+	if err := cmd.printScriptureIndexPage(ministry, view, index, &buf); err != nil {
+		return fmt.Errorf("cannot print scripture index to %s: %w", filePath, err)
+	}
+	return cmd.output.WriteFile(filePath, buf.Bytes())
+}
+
+// printScriptureIndexPage prints the scripture index page to the writer
+func (cmd *catalogCmdStruct) printScriptureIndexPage(
+	ministry catalog.Ministry,
+	view catalog.View,
+	index []catalog.ScriptureIndexBook,
+	output io.Writer,
+) error {
+	if err := cmd.loadTemplates(); err != nil {
+		return err
+	}
+
+	data := struct {
+		Date     catalog.DateOnly
+		Ministry catalog.Ministry
+		View     catalog.View
+		Books    []catalog.ScriptureIndexBook
+		Meta     pageMeta
+	}{
+		Date:     catalog.NewDateToday(),
+		Ministry: ministry,
+		View:     view,
+		Books:    index,
+		Meta: newPageMeta(ministry, ministry.Description()+" Scripture Index",
+			catalog.GetCatalogFileNameForScriptures(ministry, view)),
+	}
+
+	return cmd.template.ExecuteTemplate(output, "catalog.scripture.html", data)
+}
+
 // ----------------------------------------------------------------------------
 // | Pages containing recent messages
 // ----------------------------------------------------------------------------
@@ -531,7 +1065,7 @@ func (cmd *catalogCmdStruct) createRecentMessagePage(ministry catalog.Ministry)
 	messages := []catalog.CatalogMessage{}
 	for index := range cmd.cat.Messages {
 		msg := cmd.cat.Messages[index]
-		if msg.Ministry != ministry ||
+		if !msg.IsListedIn(ministry) ||
 			msg.Visibility != catalog.Public ||
 			msg.Date.Before(cutoff) {
 			continue
@@ -552,20 +1086,14 @@ func (cmd *catalogCmdStruct) createRecentMessagePage(ministry catalog.Ministry)
 	filePath := cmd.getOutputFilePath(fmt.Sprintf("catalog.%s-recent.html", string(ministry)))
 	log.Printf("    recent messages for %s --> %s", ministry, filePath)
 
-	// create the file name
-	f, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("cannot create output file %s: %w", filePath, err)
-	}
-	defer f.Close()
-
 	// write the series list
-	err = cmd.printRecentMessages(ministry, messages, f)
+	var buf bytes.Buffer
+	err := cmd.printRecentMessages(ministry, messages, &buf)
 	if err != nil {
 		return fmt.Errorf("cannot print series list to %s: %w", filePath, err)
 	}
 
-	return nil
+	return cmd.output.WriteFile(filePath, buf.Bytes())
 }
 
 // printRecentMessages Creates a page with the specified messages. This creates a fake series
@@ -582,11 +1110,16 @@ func (cmd *catalogCmdStruct) printRecentMessages(ministry catalog.Ministry, mess
 	data := struct {
 		Date     catalog.DateOnly
 		Ministry catalog.Ministry
+		View     catalog.View
 		Seri     *catalog.CatalogSeri
+		Podcast  string // recent messages have no podcast feed of their own
+		Meta     pageMeta
 	}{
 		Date:     catalog.NewDateToday(),
 		Ministry: ministry,
+		View:     catalog.Public,
 		Seri:     &seri,
+		Meta:     newPageMeta(ministry, seri.Name, fmt.Sprintf("catalog.%s-recent.html", string(ministry))),
 	}
 
 	return cmd.template.ExecuteTemplate(output, "catalog.seri.html", data)
@@ -602,10 +1135,12 @@ func (cmd *catalogCmdStruct) createBookletPage(ministry catalog.Ministry) error
 	// get the list of resources
 	resources := []catalog.OnlineResource{}
 
-	// find all the appropriate series
+	// find all the appropriate series. booklets that don't belong to any ministry are listed in
+	// all of them
 	seriList := []catalog.CatalogSeri{}
 	for _, seri := range cmd.cat.Series {
-		if (seri.IsBooklet() || seri.GetMinistry() == ministry) &&
+		unassigned := seri.IsBooklet() && seri.GetMinistry() == catalog.UnknownMinistry
+		if (unassigned || seri.IsListedIn(ministry)) &&
 			catalog.IsVisibleInView(seri.Visibility, catalog.Public) {
 			seriList = append(seriList, seri)
 		}
@@ -629,13 +1164,11 @@ func (cmd *catalogCmdStruct) createBookletPage(ministry catalog.Ministry) error
 	filePath := cmd.getOutputFilePath(fmt.Sprintf("catalog.%s-booklets.html", string(ministry)))
 	log.Printf("    %s --> %s", string(ministry), filePath)
 
-	f, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("cannot create output file %s: %w", filePath, err)
+	var buf bytes.Buffer
+	if err := cmd.printOnlineResources("booklet", ministry, resources, &buf); err != nil {
+		return fmt.Errorf("cannot print resources to %s: %w", filePath, err)
 	}
-	defer f.Close()
-
-	return cmd.printOnlineResources("booklet", ministry, resources, f)
+	return cmd.output.WriteFile(filePath, buf.Bytes())
 }
 
 // createResourcePage creates a page that lists all the resources that we have associated with
@@ -670,13 +1203,11 @@ func (cmd *catalogCmdStruct) createResourcePage(ministry catalog.Ministry) error
 	filePath := cmd.getOutputFilePath(fmt.Sprintf("catalog.%s-resources.html", string(ministry)))
 	log.Printf("    %s --> %s", string(ministry), filePath)
 
-	f, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("cannot create output file %s: %w", filePath, err)
+	var buf bytes.Buffer
+	if err := cmd.printOnlineResources("online", ministry, resources, &buf); err != nil {
+		return fmt.Errorf("cannot print resources to %s: %w", filePath, err)
 	}
-	defer f.Close()
-
-	return cmd.printOnlineResources("online", ministry, resources, f)
+	return cmd.output.WriteFile(filePath, buf.Bytes())
 }
 
 func (cmd *catalogCmdStruct) printOnlineResources(
@@ -701,13 +1232,17 @@ func (cmd *catalogCmdStruct) printOnlineResources(
 		Type      string
 		Date      catalog.DateOnly
 		Ministry  catalog.Ministry
+		View      catalog.View
 		Resources []catalog.OnlineResource
+		Meta      pageMeta
 	}{
 		Title:     title,
 		Type:      resourceType,
 		Date:      catalog.NewDateToday(),
 		Ministry:  ministry,
+		View:      catalog.Public,
 		Resources: resources,
+		Meta:      newPageMeta(ministry, title, ""),
 	}
 
 	return cmd.template.ExecuteTemplate(output, "catalog.resources.html", data)
@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/WordOfLifeMN/online/util"
+	"github.com/spf13/viper"
+)
+
+// ----------------------------------------------------------------------------
+// | Scheduled messages
+// ----------------------------------------------------------------------------
+
+const (
+	// maximum length of a line in a calendar file, in bytes, not including the line break
+	ICS_LINE_LENGTH = 75
+
+	// domain that makes the IDs of the events in the calendars unique
+	ICS_UID_DOMAIN = "wordoflifemn.org"
+)
+
+// serviceSchedule is when the scheduled messages are taught. The catalog only knows the date of
+// a message, so every message is assumed to be taught at the service time on its date
+type serviceSchedule struct {
+	hour     int            // hour of the service (0-23)
+	minute   int            // minute of the service
+	length   time.Duration  // how long the service is
+	location *time.Location // time zone of the service
+}
+
+// newServiceSchedule creates the service schedule from the "service-time", "service-length",
+// and "time-zone" settings
+func newServiceSchedule() (serviceSchedule, error) {
+	schedule := serviceSchedule{length: viper.GetDuration("service-length")}
+
+	serviceTime, err := time.Parse("15:04", viper.GetString("service-time"))
+	if err != nil {
+		return schedule, fmt.Errorf("service time '%s' is not a time like 10:00: %w", viper.GetString("service-time"), err)
+	}
+	schedule.hour, schedule.minute = serviceTime.Hour(), serviceTime.Minute()
+
+	schedule.location, err = time.LoadLocation(viper.GetString("time-zone"))
+	if err != nil {
+		return schedule, fmt.Errorf("unknown time zone '%s': %w", viper.GetString("time-zone"), err)
+	}
+
+	if schedule.length <= 0 {
+		return schedule, fmt.Errorf("service length '%s' must be positive", schedule.length)
+	}
+	return schedule, nil
+}
+
+// GetServiceTime gets the time that a message on a date is taught
+func (s serviceSchedule) GetServiceTime(date catalog.DateOnly) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), s.hour, s.minute, 0, 0, s.location)
+}
+
+// GetCatalogFileNameForComingSoon generates the name of the HTML file for the page that lists
+// the scheduled messages of a ministry. The page is public, so the name doesn't need a hash
+func GetCatalogFileNameForComingSoon(ministry catalog.Ministry) string {
+	return fmt.Sprintf("catalog.%s-coming-soon.html", string(ministry))
+}
+
+// GetCalendarFileName generates the name of the iCalendar file of the scheduled messages of a
+// ministry
+func GetCalendarFileName(ministry catalog.Ministry) string {
+	return fmt.Sprintf("calendar.%s.ics", string(ministry))
+}
+
+// findUpcomingSeries finds the public series of a ministry that have messages scheduled for
+// today or later. The series only contain those messages, and are sorted by the date of their
+// first scheduled message
+func (cmd *catalogCmdStruct) findUpcomingSeries(ministry catalog.Ministry, today catalog.DateOnly) []catalog.CatalogSeri {
+	upcoming := []catalog.CatalogSeri{}
+	for _, seri := range cmd.findSeriesForMinistryView(ministry, catalog.Public) {
+		messages := []catalog.CatalogMessage{}
+		for index := range seri.Messages {
+			msg := &seri.Messages[index]
+			if !msg.Date.IsZero() && !msg.Date.Before(today.Time) {
+				messages = append(messages, msg.Copy())
+			}
+		}
+		if len(messages) == 0 {
+			continue
+		}
+
+		seri.Messages = messages
+		upcoming = append(upcoming, seri)
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return getFirstMessageDate(&upcoming[i]).Before(getFirstMessageDate(&upcoming[j]).Time)
+	})
+	return upcoming
+}
+
+// getFirstMessageDate gets the earliest date of the messages in a series
+func getFirstMessageDate(seri *catalog.CatalogSeri) catalog.DateOnly {
+	var first catalog.DateOnly
+	for _, msg := range seri.Messages {
+		if first.IsZero() || msg.Date.Before(first.Time) {
+			first = msg.Date
+		}
+	}
+	return first
+}
+
+// ----------------------------------------------------------------------------
+// | Coming soon page
+// ----------------------------------------------------------------------------
+
+// createComingSoonPage creates the page that lists the scheduled messages of a ministry, with a
+// link to subscribe to its calendar
+func (cmd *catalogCmdStruct) createComingSoonPage(ministry catalog.Ministry) error {
+	series := cmd.findUpcomingSeries(ministry, catalog.NewDateToday())
+
+	filePath := cmd.getOutputFilePath(GetCatalogFileNameForComingSoon(ministry))
+	log.Printf("    coming soon for %s (%d series) --> %s", ministry, len(series), filePath)
+
+	var buf bytes.Buffer
+	if err := cmd.printComingSoonPage(ministry, series, &buf); err != nil {
+		return fmt.Errorf("cannot print coming soon page to %s: %w", filePath, err)
+	}
+
+	return cmd.output.WriteFile(filePath, buf.Bytes())
+}
+
+// printComingSoonPage prints the page that lists the scheduled messages of a ministry
+func (cmd *catalogCmdStruct) printComingSoonPage(ministry catalog.Ministry, series []catalog.CatalogSeri, output io.Writer) error {
+	if err := cmd.loadTemplates(); err != nil {
+		return err
+	}
+
+	messageCount := 0
+	for _, seri := range series {
+		messageCount += len(seri.Messages)
+	}
+
+	calendarURL := getCatalogURL(GetCalendarFileName(ministry))
+	calendarURL = strings.TrimPrefix(strings.TrimPrefix(calendarURL, "https://"), "http://")
+	data := struct {
+		Date         catalog.DateOnly
+		Ministry     catalog.Ministry
+		View         catalog.View
+		Series       []catalog.CatalogSeri
+		MessageCount int
+		Calendar     string       // file name of the calendar
+		Subscribe    template.URL // URL that calendar applications subscribe to
+		Meta         pageMeta
+	}{
+		Date:         catalog.NewDateToday(),
+		Ministry:     ministry,
+		View:         catalog.Public,
+		Series:       series,
+		MessageCount: messageCount,
+		Calendar:     GetCalendarFileName(ministry),
+		Subscribe:    template.URL("webcal://" + calendarURL),
+		Meta:         newPageMeta(ministry, "Coming soon to "+ministry.Description(), GetCatalogFileNameForComingSoon(ministry)),
+	}
+
+	return cmd.template.ExecuteTemplate(output, "catalog.coming-soon.html", data)
+}
+
+// ----------------------------------------------------------------------------
+// | iCalendar feed
+// ----------------------------------------------------------------------------
+
+// createCalendar creates the iCalendar file (RFC 5545) of the scheduled messages of a ministry,
+// which people can subscribe to in their calendar applications
+func (cmd *catalogCmdStruct) createCalendar(ministry catalog.Ministry, schedule serviceSchedule) error {
+	today := catalog.NewDateToday()
+	series := cmd.findUpcomingSeries(ministry, today)
+
+	filePath := cmd.getOutputFilePath(GetCalendarFileName(ministry))
+	log.Printf("    calendar for %s --> %s", ministry, filePath)
+
+	var buf bytes.Buffer
+	if err := printCalendar(ministry, series, schedule, today.Time, &buf); err != nil {
+		return fmt.Errorf("cannot print calendar to %s: %w", filePath, err)
+	}
+
+	return cmd.output.WriteFile(filePath, buf.Bytes())
+}
+
+// printCalendar prints an iCalendar file with an event for each scheduled message in the series,
+// at the service time on the date of the message, and an all-day event for each series that is in
+// progress or has more than one scheduled message, which lasts from the first to the last message
+// of the series. The time stamp is when the events were generated, which is the same for all of
+// them
+func printCalendar(
+	ministry catalog.Ministry,
+	series []catalog.CatalogSeri,
+	schedule serviceSchedule,
+	stamp time.Time,
+	output io.Writer,
+) error {
+	ics := &icsWriter{output: output}
+	dtstamp := stamp.UTC().Format("20060102T150405Z")
+
+	ics.WriteProperty("BEGIN", "VCALENDAR")
+	ics.WriteProperty("VERSION", "2.0")
+	ics.WriteProperty("PRODID", "-//Word of Life Ministries//Online Catalog//EN")
+	ics.WriteProperty("CALSCALE", "GREGORIAN")
+	ics.WriteProperty("METHOD", "PUBLISH")
+	ics.WriteProperty("X-WR-CALNAME", icsEscape(ministry.Description()))
+	ics.WriteProperty("X-WR-CALDESC", icsEscape("Scheduled messages from "+ministry.Description()))
+	ics.WriteProperty("X-WR-TIMEZONE", schedule.location.String())
+
+	// messages can be in more than one series, but are only on the calendar once
+	seen := map[string]bool{}
+	for index := range series {
+		seri := &series[index]
+		seriURL := getCatalogURL(seri.GetCatalogFileName(catalog.Public))
+
+		// series that are in progress (already started) or have more than one scheduled message
+		// are on the calendar as well as their messages
+		first := getFirstMessageDate(seri)
+		start, stop := seri.StartDate, seri.StopDate
+		if start.IsZero() || first.Before(start.Time) {
+			start = first
+		}
+		if stop.Before(start.Time) {
+			stop = start
+		}
+		if len(seri.Messages) > 1 || start.Before(first.Time) {
+			ics.WriteProperty("BEGIN", "VEVENT")
+			ics.WriteProperty("UID", "series-"+seri.GetID()+"@"+ICS_UID_DOMAIN)
+			ics.WriteProperty("DTSTAMP", dtstamp)
+			ics.WriteProperty("DTSTART;VALUE=DATE", start.Time.Format("20060102"))
+			ics.WriteProperty("DTEND;VALUE=DATE", stop.Time.AddDate(0, 0, 1).Format("20060102"))
+			ics.WriteProperty("SUMMARY", icsEscape("Series: "+seri.Name))
+			ics.WriteProperty("DESCRIPTION", icsEscape(describeCalendarEvent(seri.SpeakerString(), seri.Description, seriURL)))
+			ics.WriteProperty("URL", seriURL)
+			ics.WriteProperty("TRANSP", "TRANSPARENT")
+			ics.WriteProperty("END", "VEVENT")
+		}
+
+		for msgIndex := range seri.Messages {
+			msg := &seri.Messages[msgIndex]
+			key := msg.Name + " (" + msg.Date.String() + ")"
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			start := schedule.GetServiceTime(msg.Date)
+			ics.WriteProperty("BEGIN", "VEVENT")
+			ics.WriteProperty("UID", "message-"+util.ComputeHash(key)+"@"+ICS_UID_DOMAIN)
+			ics.WriteProperty("DTSTAMP", dtstamp)
+			ics.WriteProperty("DTSTART", start.UTC().Format("20060102T150405Z"))
+			ics.WriteProperty("DTEND", start.Add(schedule.length).UTC().Format("20060102T150405Z"))
+			ics.WriteProperty("SUMMARY", icsEscape(msg.Name))
+			ics.WriteProperty("DESCRIPTION", icsEscape(describeCalendarEvent(msg.SpeakerString(), msg.Description, seriURL)))
+			ics.WriteProperty("URL", seriURL)
+			ics.WriteProperty("END", "VEVENT")
+		}
+	}
+
+	ics.WriteProperty("END", "VCALENDAR")
+	return ics.err
+}
+
+// describeCalendarEvent creates the description of an event from the speakers, the description
+// of the message or series, and where to find it
+func describeCalendarEvent(speakers string, description string, url string) string {
+	parts := []string{}
+	if speakers != "" {
+		parts = append(parts, speakers)
+	}
+	if description = strings.TrimSpace(description); description != "" {
+		parts = append(parts, description)
+	}
+	parts = append(parts, url)
+	return strings.Join(parts, "\n\n")
+}
+
+// icsWriter writes the content lines of an iCalendar file. Lines end with CRLF and long lines
+// are folded, as required by RFC 5545. The first error is remembered and nothing is written
+// after it
+type icsWriter struct {
+	output io.Writer // where the calendar is written
+	err    error     // first error writing the calendar
+}
+
+// WriteProperty writes a property, like "SUMMARY:Faith", folding it into lines of at most
+// ICS_LINE_LENGTH bytes without splitting UTF-8 characters. The value should already be escaped
+func (w *icsWriter) WriteProperty(name string, value string) {
+	if w.err != nil {
+		return
+	}
+
+	line := name + ":" + value
+	var folded strings.Builder
+	length := 0
+	for _, r := range line {
+		size := len(string(r))
+		if length+size > ICS_LINE_LENGTH {
+			// continuation lines start with a space, which counts towards their length
+			folded.WriteString("\r\n ")
+			length = 1
+		}
+		folded.WriteRune(r)
+		length += size
+	}
+	folded.WriteString("\r\n")
+
+	_, w.err = io.WriteString(w.output, folded.String())
+}
+
+// icsEscape escapes the characters that have a special meaning in the text value of an
+// iCalendar property
+func icsEscape(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(text)
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/WordOfLifeMN/online/catalog"
+)
+
+// ----------------------------------------------------------------------------
+// | Page metadata
+// ----------------------------------------------------------------------------
+
+// maximum length of the description of a page in its metadata
+const PAGE_DESCRIPTION_LENGTH = 200
+
+// pageMeta describes a page so that links to it that are shared on social media have a preview
+// (Open Graph tags), and so search engines know what is on it (JSON-LD). All the URLs are public
+// URLs
+type pageMeta struct {
+	Title       string // title of the page
+	Description string // short description of the page
+	URL         string // URL of the page, "" if it isn't known
+	Image       string // URL of an image that represents the page
+	Audio       string // URL of the audio on the page, "" if none
+	Video       string // URL of the video on the page, "" if none
+	JSONLD      any    // schema.org description of what is on the page, nil if none
+}
+
+// newPageMeta creates the metadata for a page of a ministry that isn't about a single series
+func newPageMeta(ministry catalog.Ministry, title string, fileName string) pageMeta {
+	meta := pageMeta{
+		Title:       title,
+		Description: fmt.Sprintf("Messages and teaching from %s at Word of Life Ministries", ministry.Description()),
+		Image:       getPageImageURL(ministry, ""),
+	}
+	if fileName != "" {
+		meta.URL = getCatalogURL(fileName)
+	}
+	return meta
+}
+
+// newSeriPageMeta creates the metadata for the page of a series. The series should already be
+// filtered to a view. The audio and video are from the first message that has them. Only public
+// pages have JSON-LD, because the other pages are not indexed by search engines
+func newSeriPageMeta(seri *catalog.CatalogSeri) pageMeta {
+	meta := pageMeta{
+		Title:       seri.Name,
+		Description: summarizeDescription(seri.Description),
+		URL:         getCatalogURL(seri.GetCatalogFileName(seri.View)),
+		Image:       getPageImageURL(seri.GetMinistry(), seri.Thumbnail),
+	}
+	if meta.Description == "" {
+		meta.Description = fmt.Sprintf("Messages from the series %s, from %s at Word of Life Ministries",
+			seri.Name, seri.GetMinistry().Description())
+	}
+
+	for index := range seri.Messages {
+		msg := &seri.Messages[index]
+		if meta.Audio == "" && msg.HasAudio() {
+			meta.Audio = msg.Audio.URL
+		}
+		if meta.Video == "" && msg.HasVideo() {
+			meta.Video = msg.Video.URL
+		}
+	}
+
+	if seri.View == catalog.Public {
+		meta.JSONLD = newSeriJSONLD(seri, meta)
+	}
+	return meta
+}
+
+// getPageImageURL gets the image that represents a page. If the thumbnail is a full URL then
+// that is used, if it's a path then it's a file published with the catalog, otherwise the
+// default thumbnail of the ministry is used
+func getPageImageURL(ministry catalog.Ministry, thumbnail string) string {
+	switch {
+	case strings.Contains(thumbnail, "://"):
+		return thumbnail
+	case thumbnail != "":
+		return getCatalogURL(strings.TrimPrefix(thumbnail, "/"))
+	}
+	return getCatalogURL(ministry.Thumbnail())
+}
+
+// summarizeDescription shortens a description to at most PAGE_DESCRIPTION_LENGTH characters,
+// breaking between words, and puts it all on one line
+func summarizeDescription(description string) string {
+	words := strings.Fields(description)
+
+	summary := ""
+	for _, word := range words {
+		if len([]rune(summary))+1+len([]rune(word)) > PAGE_DESCRIPTION_LENGTH-1 {
+			return summary + "…"
+		}
+		if summary != "" {
+			summary += " "
+		}
+		summary += word
+	}
+	return summary
+}
+
+// ----------------------------------------------------------------------------
+// | JSON-LD
+// ----------------------------------------------------------------------------
+
+// newSeriJSONLD describes a series with schema.org types. A series with audio is a
+// PodcastSeries, otherwise it is a CreativeWorkSeries. Each message with audio is a
+// PodcastEpisode, and a message with only video is a VideoObject
+func newSeriJSONLD(seri *catalog.CatalogSeri, meta pageMeta) map[string]any {
+	ld := map[string]any{
+		"@context":    "https://schema.org",
+		"@type":       "CreativeWorkSeries",
+		"name":        seri.Name,
+		"description": meta.Description,
+		"url":         meta.URL,
+		"image":       meta.Image,
+		"publisher":   newOrganizationJSONLD(),
+	}
+	if seri.HasAudio() {
+		ld["@type"] = "PodcastSeries"
+		ld["webFeed"] = getCatalogURL(seri.GetPodcastFileName(seri.View))
+	}
+	if !seri.StartDate.IsZero() {
+		ld["startDate"] = seri.StartDate.String()
+	}
+	if !seri.StopDate.IsZero() && seri.State == catalog.State_Complete {
+		ld["endDate"] = seri.StopDate.String()
+	}
+
+	var parts []map[string]any
+	for index := range seri.Messages {
+		msg := &seri.Messages[index]
+		switch {
+		case msg.HasAudio():
+			parts = append(parts, newEpisodeJSONLD(msg, meta))
+		case msg.HasVideo():
+			parts = append(parts, newVideoJSONLD(msg, meta))
+		}
+	}
+	if len(parts) > 0 {
+		ld["hasPart"] = parts
+	}
+
+	return ld
+}
+
+// newEpisodeJSONLD describes a message with audio as a PodcastEpisode
+func newEpisodeJSONLD(msg *catalog.CatalogMessage, seriMeta pageMeta) map[string]any {
+	ld := newMessageJSONLD("PodcastEpisode", msg, seriMeta)
+	ld["associatedMedia"] = map[string]any{
+		"@type":      "AudioObject",
+		"contentUrl": msg.Audio.URL,
+	}
+	if msg.HasVideo() {
+		ld["video"] = newVideoJSONLD(msg, seriMeta)
+	}
+	return ld
+}
+
+// newVideoJSONLD describes the video of a message as a VideoObject
+func newVideoJSONLD(msg *catalog.CatalogMessage, seriMeta pageMeta) map[string]any {
+	ld := newMessageJSONLD("VideoObject", msg, seriMeta)
+	ld["contentUrl"] = msg.Video.URL
+	ld["thumbnailUrl"] = seriMeta.Image
+	if msg.Thumb != nil && strings.Contains(msg.Thumb.URL, "://") {
+		ld["thumbnailUrl"] = msg.Thumb.URL
+	}
+	if !msg.Date.IsZero() {
+		ld["uploadDate"] = msg.Date.String()
+	}
+	return ld
+}
+
+// newMessageJSONLD describes what all the schema.org types of a message have in common
+func newMessageJSONLD(ldType string, msg *catalog.CatalogMessage, seriMeta pageMeta) map[string]any {
+	ld := map[string]any{
+		"@type": ldType,
+		"name":  msg.Name,
+		"url":   seriMeta.URL,
+	}
+	if description := summarizeDescription(msg.Description); description != "" {
+		ld["description"] = description
+	}
+	if !msg.Date.IsZero() {
+		ld["datePublished"] = msg.Date.String()
+	}
+
+	var authors []map[string]any
+	for _, speaker := range msg.Speakers {
+		authors = append(authors, map[string]any{"@type": "Person", "name": speaker})
+	}
+	if len(authors) > 0 {
+		ld["author"] = authors
+	}
+	return ld
+}
+
+// newOrganizationJSONLD describes Word of Life Ministries
+func newOrganizationJSONLD() map[string]any {
+	return map[string]any{
+		"@type": "Organization",
+		"name":  "Word of Life Ministries",
+		"url":   "http://www.wordoflifemn.org",
+	}
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/WordOfLifeMN/online/util"
+)
+
+// catalogOutput is where the files of the catalog are written
+type catalogOutput interface {
+	// WriteFile writes the contents of a file in the output directory
+	WriteFile(filePath string, content []byte) error
+}
+
+// ----------------------------------------------------------------------------
+// | Manifest of the files in the output directory
+// ----------------------------------------------------------------------------
+
+const (
+	// name of the manifest of the files in an output directory
+	MANIFEST_FILE_NAME string = "online.manifest.json"
+)
+
+// outputManifest keeps track of the files generated in an output directory and a hash of the
+// contents of each. A file is only written if its contents are different from the last time it
+// was generated, and files that were generated last time but not this time are removed. The
+// manifest is saved in the output directory, with the list of files that were written or
+// removed, so the files that need to be uploaded can be found
+type outputManifest struct {
+	Files   map[string]string `json:"files"`             // SHA-256 of the contents of each file, by path relative to the output directory
+	Changed []string          `json:"changed,omitempty"` // files that were written by the last run
+	Removed []string          `json:"removed,omitempty"` // files that were removed by the last run
+
+	dir       string          // output directory
+	generated map[string]bool // files generated by this run, whether they changed or not
+}
+
+// newOutputManifest reads the manifest of an output directory. If the directory has no manifest,
+// then the manifest is empty
+func newOutputManifest(dir string) (*outputManifest, error) {
+	manifest := &outputManifest{
+		Files:     map[string]string{},
+		dir:       dir,
+		generated: map[string]bool{},
+	}
+
+	filePath := filepath.Join(dir, MANIFEST_FILE_NAME)
+	if !util.IsFile(filePath) {
+		return manifest, nil
+	}
+
+	bytes, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read manifest %s: %w", filePath, err)
+	}
+	if err := json.Unmarshal(bytes, manifest); err != nil {
+		return nil, fmt.Errorf("cannot parse manifest %s: %w", filePath, err)
+	}
+	if manifest.Files == nil {
+		manifest.Files = map[string]string{}
+	}
+
+	// the lists of changes are only for the last run
+	manifest.Changed = nil
+	manifest.Removed = nil
+	return manifest, nil
+}
+
+// getRelativePath gets the path of a file in the output directory relative to the directory,
+// which is how the file is named in the manifest
+func (m *outputManifest) getRelativePath(filePath string) (string, error) {
+	relPath, err := filepath.Rel(m.dir, filePath)
+	if err != nil {
+		return "", fmt.Errorf("file %s is not in the output directory %s: %w", filePath, m.dir, err)
+	}
+	return filepath.ToSlash(relPath), nil
+}
+
+// WriteFile writes the contents to a file in the output directory, unless the file already has
+// the same contents
+func (m *outputManifest) WriteFile(filePath string, content []byte) error {
+	relPath, err := m.getRelativePath(filePath)
+	if err != nil {
+		return err
+	}
+	m.generated[relPath] = true
+
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	if m.Files[relPath] == hash && util.IsFile(filePath) {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), os.FileMode(0777)); err != nil {
+		return fmt.Errorf("cannot create directory for %s: %w", filePath, err)
+	}
+	if err := os.WriteFile(filePath, content, os.FileMode(0666)); err != nil {
+		return fmt.Errorf("cannot write output file %s: %w", filePath, err)
+	}
+
+	m.Files[relPath] = hash
+	m.Changed = append(m.Changed, relPath)
+	return nil
+}
+
+// RemoveStaleFiles removes the files that were in the manifest but were not generated by this
+// run, and any directories that are empty because of it
+func (m *outputManifest) RemoveStaleFiles() error {
+	for relPath := range m.Files {
+		if m.generated[relPath] {
+			continue
+		}
+
+		filePath := filepath.Join(m.dir, filepath.FromSlash(relPath))
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("cannot remove stale file %s: %w", filePath, err)
+		}
+		if dir := filepath.Dir(filePath); dir != m.dir {
+			// fails if there are still files in it, which is fine
+			os.Remove(dir)
+		}
+
+		delete(m.Files, relPath)
+		m.Removed = append(m.Removed, relPath)
+	}
+	sort.Strings(m.Removed)
+	return nil
+}
+
+// Save writes the manifest to the output directory
+func (m *outputManifest) Save() error {
+	sort.Strings(m.Changed)
+
+	bytes, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	filePath := filepath.Join(m.dir, MANIFEST_FILE_NAME)
+	if err := os.WriteFile(filePath, bytes, os.FileMode(0666)); err != nil {
+		return fmt.Errorf("cannot write manifest %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+// | Files kept in memory
+// ----------------------------------------------------------------------------
+
+// memoryOutput keeps the generated files in memory instead of writing them to a directory, for
+// serving the catalog without writing it anywhere
+type memoryOutput struct {
+	Files map[string][]byte // contents of each file, by path relative to the output directory
+
+	dir string // output directory the files would be written to
+}
+
+// newMemoryOutput creates an empty set of files for the output directory
+func newMemoryOutput(dir string) *memoryOutput {
+	return &memoryOutput{Files: map[string][]byte{}, dir: dir}
+}
+
+// WriteFile keeps the contents of a file
+func (m *memoryOutput) WriteFile(filePath string, content []byte) error {
+	relPath, err := filepath.Rel(m.dir, filePath)
+	if err != nil {
+		return fmt.Errorf("file %s is not in the output directory %s: %w", filePath, m.dir, err)
+	}
+	m.Files[filepath.ToSlash(relPath)] = content
+	return nil
+}
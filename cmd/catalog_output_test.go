@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/WordOfLifeMN/online/util"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestOutputManifestTestSuite(t *testing.T) {
+	suite.Run(t, new(OutputManifestTestSuite))
+}
+
+type OutputManifestTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (t *OutputManifestTestSuite) SetupTest() {
+	t.dir = t.T().TempDir()
+}
+
+func (t *OutputManifestTestSuite) TestNewManifest() {
+	sut, err := newOutputManifest(t.dir)
+	t.NoError(err)
+	t.Empty(sut.Files)
+}
+
+func (t *OutputManifestTestSuite) TestWriteOnlyChangedFiles() {
+	// first run writes everything
+	sut, err := newOutputManifest(t.dir)
+	t.Require().NoError(err)
+	t.NoError(sut.WriteFile(filepath.Join(t.dir, "same.html"), []byte("same")))
+	t.NoError(sut.WriteFile(filepath.Join(t.dir, "different.html"), []byte("before")))
+	t.NoError(sut.WriteFile(filepath.Join(t.dir, "static", "all.css"), []byte("css")))
+	t.NoError(sut.RemoveStaleFiles())
+	t.NoError(sut.Save())
+	t.Equal([]string{"different.html", "same.html", "static/all.css"}, sut.Changed)
+
+	// second run only writes what changed
+	sut, err = newOutputManifest(t.dir)
+	t.Require().NoError(err)
+	t.Len(sut.Files, 3)
+	t.NoError(sut.WriteFile(filepath.Join(t.dir, "same.html"), []byte("same")))
+	t.NoError(sut.WriteFile(filepath.Join(t.dir, "different.html"), []byte("after")))
+	t.NoError(sut.WriteFile(filepath.Join(t.dir, "static", "all.css"), []byte("css")))
+	t.NoError(sut.RemoveStaleFiles())
+	t.NoError(sut.Save())
+	t.Equal([]string{"different.html"}, sut.Changed)
+	t.Empty(sut.Removed)
+
+	content, err := os.ReadFile(filepath.Join(t.dir, "different.html"))
+	t.NoError(err)
+	t.Equal("after", string(content))
+}
+
+func (t *OutputManifestTestSuite) TestRewriteMissingFiles() {
+	sut, err := newOutputManifest(t.dir)
+	t.Require().NoError(err)
+	t.NoError(sut.WriteFile(filepath.Join(t.dir, "page.html"), []byte("page")))
+	t.NoError(sut.Save())
+	t.NoError(os.Remove(filepath.Join(t.dir, "page.html")))
+
+	sut, err = newOutputManifest(t.dir)
+	t.Require().NoError(err)
+	t.NoError(sut.WriteFile(filepath.Join(t.dir, "page.html"), []byte("page")))
+	t.Equal([]string{"page.html"}, sut.Changed)
+	t.True(util.IsFile(filepath.Join(t.dir, "page.html")))
+}
+
+func (t *OutputManifestTestSuite) TestRemoveStaleFiles() {
+	sut, err := newOutputManifest(t.dir)
+	t.Require().NoError(err)
+	t.NoError(sut.WriteFile(filepath.Join(t.dir, "kept.html"), []byte("kept")))
+	t.NoError(sut.WriteFile(filepath.Join(t.dir, "search.x", "index-00.json"), []byte("{}")))
+	t.NoError(sut.Save())
+
+	// not generated by the second run
+	sut, err = newOutputManifest(t.dir)
+	t.Require().NoError(err)
+	t.NoError(sut.WriteFile(filepath.Join(t.dir, "kept.html"), []byte("kept")))
+	t.NoError(sut.RemoveStaleFiles())
+	t.NoError(sut.Save())
+
+	t.Empty(sut.Changed)
+	t.Equal([]string{"search.x/index-00.json"}, sut.Removed)
+	t.Equal([]string{"kept.html"}, keysOf(sut.Files))
+	t.True(util.IsFile(filepath.Join(t.dir, "kept.html")))
+	t.False(util.DoesPathExist(filepath.Join(t.dir, "search.x")))
+}
+
+// keysOf gets the keys of a map
+func keysOf(m map[string]string) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
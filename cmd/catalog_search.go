@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/WordOfLifeMN/online/catalog"
+)
+
+// ----------------------------------------------------------------------------
+// | Pages for searching the transcripts
+// ----------------------------------------------------------------------------
+
+// number of transcripts that are downloaded at the same time
+const TRANSCRIPT_DOWNLOADS = 8
+
+// loadTranscripts reads the transcripts and captions of all the messages that have them, so
+// that building the search index for each ministry and view doesn't have to wait for them
+func (cmd *catalogCmdStruct) loadTranscripts() {
+	var messages []*catalog.CatalogMessage
+	for index := range cmd.cat.Messages {
+		if cmd.cat.Messages[index].HasTranscript() {
+			messages = append(messages, &cmd.cat.Messages[index])
+		}
+	}
+	log.Printf("  Reading %d transcripts", len(messages))
+
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, TRANSCRIPT_DOWNLOADS)
+	for _, msg := range messages {
+		wg.Go(func() {
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			for _, ext := range []string{".text", ".vtt"} {
+				if _, err := msg.GetTranscript(ext); err != nil {
+					log.Printf("WARNING: %s", err.Error())
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// createSearchPage generates the transcript search page for a ministry and view, and the index
+// that it searches. The page is named by catalog.GetCatalogFileNameForSearch() and the index is
+// in the directory named by catalog.GetCatalogDirNameForSearch()
+func (cmd *catalogCmdStruct) createSearchPage(ministry catalog.Ministry, view catalog.View) error {
+	seriList := cmd.findSeriesForMinistryView(ministry, view)
+	sort.Stable(catalog.SortSeriNewestToOldest(seriList))
+	index := newTranscriptSearchIndex(seriList, view)
+
+	if len(index.Messages) == 0 {
+		log.Printf("    (no transcripts found)")
+	}
+
+	// write the index
+	dirPath := cmd.getOutputFilePath(catalog.GetCatalogDirNameForSearch(ministry, view))
+	log.Printf("    index of %d messages (%d passages) --> %s", len(index.Messages), index.PassageCount(), dirPath)
+	files := map[string]any{"meta.json": index.GetMetaFile()}
+	for name, content := range index.GetIndexFiles() {
+		files[name] = content
+	}
+	for name, content := range index.GetPassageFiles() {
+		files[name] = content
+	}
+	for name, content := range files {
+		if err := cmd.writeJSONFile(filepath.Join(dirPath, name), content); err != nil {
+			return err
+		}
+	}
+
+	// write the page
+	filePath := cmd.getOutputFilePath(catalog.GetCatalogFileNameForSearch(ministry, view))
+	log.Printf("    search for (%s,%s) --> %s", ministry, view, filePath)
+
+	var buf bytes.Buffer
+	if err := cmd.printSearchPage(ministry, view, &buf); err != nil {
+		return fmt.Errorf("cannot print search page to %s: %w", filePath, err)
+	}
+	return cmd.output.WriteFile(filePath, buf.Bytes())
+}
+
+// newTranscriptSearchIndex builds the search index of the transcripts of all the messages in
+// the series. A message that is in more than one of the series is only indexed once, and links
+// to the first series it is in
+func newTranscriptSearchIndex(seriList []catalog.CatalogSeri, view catalog.View) *catalog.SearchIndex {
+	index := catalog.NewSearchIndex()
+	seen := map[string]bool{}
+
+	for _, seri := range seriList {
+		for _, msg := range seri.Messages {
+			if !msg.HasTranscript() || seen[msg.Audio.URL] {
+				continue
+			}
+			seen[msg.Audio.URL] = true
+
+			passages, err := getTranscriptPassages(&msg)
+			if err != nil {
+				log.Printf("WARNING: %s", err.Error())
+				continue
+			}
+
+			index.AddMessage(catalog.SearchMessage{
+				Name:     msg.Name,
+				Speakers: msg.SpeakerString(),
+				Date:     msg.DateString(),
+				Page:     seri.GetCatalogFileName(view),
+				Audio:    msg.Audio.URL,
+			}, passages)
+		}
+	}
+
+	return index
+}
+
+// getTranscriptPassages splits the transcript of a message into passages. If the message has
+// captions, then each passage has the time it starts in the recording
+func getTranscriptPassages(msg *catalog.CatalogMessage) ([]catalog.TranscriptPassage, error) {
+	text, err := msg.GetTranscript(".text")
+	if err != nil {
+		return nil, err
+	}
+
+	var cues []catalog.TranscriptCue
+	if vtt, err := msg.GetTranscript(".vtt"); err == nil {
+		cues = catalog.ParseVTT(vtt)
+	}
+
+	return catalog.SplitTranscript(text, cues), nil
+}
+
+// writeJSONFile writes data to a file in the output directory as compact JSON
+func (cmd *catalogCmdStruct) writeJSONFile(filePath string, data any) error {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("cannot convert %s to JSON: %w", filePath, err)
+	}
+	return cmd.output.WriteFile(filePath, content)
+}
+
+// printSearchPage prints the transcript search page to the writer
+func (cmd *catalogCmdStruct) printSearchPage(ministry catalog.Ministry, view catalog.View, output io.Writer) error {
+	if err := cmd.loadTemplates(); err != nil {
+		return err
+	}
+
+	data := struct {
+		Date     catalog.DateOnly
+		Ministry catalog.Ministry
+		View     catalog.View
+		IndexDir string
+		Meta     pageMeta
+	}{
+		Date:     catalog.NewDateToday(),
+		Ministry: ministry,
+		View:     view,
+		IndexDir: catalog.GetCatalogDirNameForSearch(ministry, view),
+		Meta: newPageMeta(ministry, ministry.Description()+" Transcript Search",
+			catalog.GetCatalogFileNameForSearch(ministry, view)),
+	}
+
+	return cmd.template.ExecuteTemplate(output, "catalog.search.html", data)
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/WordOfLifeMN/online/catalog"
+)
+
+// ----------------------------------------------------------------------------
+// | Files for search engines
+// ----------------------------------------------------------------------------
+
+const (
+	// name of the site map that lists the pages search engines should crawl
+	SITEMAP_FILE_NAME string = "sitemap.xml"
+
+	// name of the file that tells search engines what they can crawl
+	ROBOTS_FILE_NAME string = "robots.txt"
+)
+
+// sitemapURL is a page in the site map
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// sitemapURLSet is the contents of the site map (https://www.sitemaps.org/protocol.html)
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// createSitemap creates the site map of the public pages of the ministries
+func (cmd *catalogCmdStruct) createSitemap(ministries []catalog.Ministry) error {
+	sitemap := cmd.newSitemap(ministries)
+
+	filePath := cmd.getOutputFilePath(SITEMAP_FILE_NAME)
+	log.Printf("    site map (%d pages) --> %s", len(sitemap.URLs), filePath)
+
+	content, err := xml.MarshalIndent(sitemap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot print site map to %s: %w", filePath, err)
+	}
+	return cmd.output.WriteFile(filePath, append([]byte(xml.Header), content...))
+}
+
+// newSitemap builds the site map of the public pages of the ministries, which are the series
+// list pages, the series pages, and the coming soon page. The last time a series page was
+// modified is the date of the last message in the series, and the last time a series list page
+// was modified is the latest of those
+func (cmd *catalogCmdStruct) newSitemap(ministries []catalog.Ministry) sitemapURLSet {
+	sitemap := sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	seen := map[string]bool{}
+
+	addURL := func(fileName string, lastMod catalog.DateOnly) {
+		if seen[fileName] {
+			return
+		}
+		seen[fileName] = true
+
+		url := sitemapURL{Loc: getCatalogURL(fileName)}
+		if !lastMod.IsZero() {
+			url.LastMod = lastMod.String()
+		}
+		sitemap.URLs = append(sitemap.URLs, url)
+	}
+
+	for _, ministry := range ministries {
+		seriList := cmd.findSeriesForMinistryView(ministry, catalog.Public)
+		if len(seriList) == 0 {
+			continue
+		}
+		sort.Stable(catalog.SortSeriByName(seriList))
+
+		var lastMod catalog.DateOnly
+		for _, seri := range seriList {
+			if seri.StopDate.After(lastMod.Time) {
+				lastMod = seri.StopDate
+			}
+		}
+		for _, order := range []string{ALPHABETICAL_ASC, CHRONOLOGICAL_ASC, CHRONOLOGICAL_DESC} {
+			addURL(GetCatalogFileNameForSeriList(ministry, catalog.Public, order), lastMod)
+		}
+
+		for _, seri := range seriList {
+			addURL(seri.GetCatalogFileName(catalog.Public), seri.StopDate)
+		}
+		addURL(GetCatalogFileNameForComingSoon(ministry), catalog.DateOnly{})
+	}
+
+	return sitemap
+}
+
+// createRobotsFile creates the file that tells search engines where the site map is. Nothing is
+// disallowed, because pages that search engines shouldn't index (the pages that are not public)
+// have a "noindex" tag, and a search engine can only see that tag if it can crawl the page. Note
+// that search engines only look for robots.txt at the root of a site, so this only works if the
+// catalog URL is the root of its site
+func (cmd *catalogCmdStruct) createRobotsFile() error {
+	filePath := cmd.getOutputFilePath(ROBOTS_FILE_NAME)
+	log.Printf("    robots --> %s", filePath)
+
+	content := fmt.Sprintf("User-agent: *\nAllow: /\n\nSitemap: %s\n", getCatalogURL(SITEMAP_FILE_NAME))
+	return cmd.output.WriteFile(filePath, []byte(content))
+}
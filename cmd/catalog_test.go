@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/WordOfLifeMN/online/catalog"
 	"github.com/WordOfLifeMN/online/util"
@@ -70,6 +72,41 @@ func (t *CatalogCmdTestSuite) TestOutputDirectoryPrep_DirIsOkToDelete() {
 	t.NoError(os.RemoveAll(testDir))
 }
 
+func (t *CatalogCmdTestSuite) TestOutputDirectoryPrep_DirHasManifest() {
+	// given
+	testDir := "/tmp/testdir"
+	sut := catalogCmdStruct{
+		OutputDir: testDir,
+	}
+	t.NoError(os.MkdirAll(testDir, os.FileMode(0777)))
+	defer os.RemoveAll(testDir)
+	for _, name := range []string{"is.online.catalog.dir", "actual-file.txt"} {
+		f, err := os.Create(filepath.Join(testDir, name))
+		t.NoError(err)
+		f.Close()
+	}
+	t.NoError(os.WriteFile(filepath.Join(testDir, "online.manifest.json"), []byte("{}"), os.FileMode(0666)))
+
+	// when
+	if t.NoError(sut.initializeOutputDir()) {
+
+		// then
+		t.True(util.IsFile(filepath.Join(testDir, "actual-file.txt")))
+		t.NotNil(sut.output)
+	}
+
+	// when starting over
+	sut.Clean = true
+	if t.NoError(sut.initializeOutputDir()) {
+
+		// then
+		t.True(util.IsFile(filepath.Join(testDir, "is.online.catalog.dir")))
+		t.False(util.IsFile(filepath.Join(testDir, "actual-file.txt")))
+	}
+
+	t.NoError(os.RemoveAll(testDir))
+}
+
 func (t *CatalogCmdTestSuite) TestOutputDirectoryPrep_DirShouldNotBeDeleted() {
 	// given
 	testDir := "/tmp/testdir"
@@ -157,3 +194,492 @@ func (t *CatalogCmdTestSuite) TestSeriPage() {
 		t.NoError(err)
 	}
 }
+
+func (t *CatalogCmdTestSuite) TestRecentMessagesTemplate() {
+	sut := catalogCmdStruct{}
+	t.Require().NoError(sut.loadTemplates())
+
+	messages := []catalog.CatalogMessage{
+		{
+			Name:       "MESSAGE-A",
+			Date:       catalog.MustParseDateOnly("2021-09-15"),
+			Ministry:   catalog.WordOfLife,
+			Visibility: catalog.Public,
+			Series:     []catalog.SeriesReference{{Name: "Recent Messages", Index: 1}},
+		},
+	}
+	buf := new(bytes.Buffer)
+
+	err := sut.printRecentMessages(catalog.WordOfLife, messages, buf)
+	t.NoError(err)
+	t.T().Logf("Results of printing:\n%s", buf.String())
+	t.Contains(buf.String(), "<h1>Recent messages from Word of Life</h1>")
+	t.Contains(buf.String(), "MESSAGE-A")
+	t.Contains(buf.String(), `<img src="static/wol.logo.small.png" alt="Word of Life"`)
+	// there is no podcast feed of recent messages
+	t.NotContains(buf.String(), "application/rss+xml")
+}
+
+// +---------------------------------------------------------------------------
+// | Speaker Output
+// +---------------------------------------------------------------------------
+
+func (t *CatalogCmdTestSuite) TestSpeakersOfSeries() {
+	seriList := []catalog.CatalogSeri{
+		{
+			Messages: []catalog.CatalogMessage{
+				{Speakers: []string{"Sven", "Ollie"}},
+				{Speakers: []string{"Ollie"}},
+			},
+		},
+		{
+			Messages: []catalog.CatalogMessage{
+				{Speakers: []string{"Lena", ""}},
+			},
+		},
+	}
+	t.Equal([]string{"Lena", "Ollie", "Sven"}, getSpeakersOfSeries(seriList))
+}
+
+func (t *CatalogCmdTestSuite) TestSpeakerTemplate() {
+	sut := catalogCmdStruct{}
+
+	series := []catalog.CatalogSeri{
+		{
+			Name: "SERIES",
+			View: catalog.Public,
+			Messages: []catalog.CatalogMessage{
+				{
+					Name:       "MESSAGE-A",
+					Date:       catalog.MustParseDateOnly("2021-09-10"),
+					Ministry:   catalog.WordOfLife,
+					Visibility: catalog.Public,
+					Speakers:   []string{"Pastor Vern Peltz"},
+				},
+			},
+		},
+	}
+	series[0].Normalize()
+	buf := new(bytes.Buffer)
+
+	err := sut.printSpeakerPage(catalog.WordOfLife, catalog.Public, "Pastor Vern Peltz", series, buf)
+	t.NoError(err)
+	t.T().Logf("Results of printing:\n%s", buf.String())
+	t.Contains(buf.String(), "<h1>Pastor Vern Peltz</h1>")
+	t.Contains(buf.String(), "SERIES")
+	t.Contains(buf.String(), "MESSAGE-A")
+	t.Contains(buf.String(), "1 message in the Word of Life catalog")
+	t.Contains(buf.String(), catalog.GetCatalogFileNameForSpeaker(catalog.WordOfLife, catalog.Public, "Pastor Vern Peltz"))
+}
+
+func (t *CatalogCmdTestSuite) TestScriptureTemplate() {
+	sut := catalogCmdStruct{}
+
+	series := []catalog.CatalogSeri{
+		{
+			Name: "SERIES",
+			View: catalog.Public,
+			Messages: []catalog.CatalogMessage{
+				{
+					Name:       "MESSAGE-A",
+					Date:       catalog.MustParseDateOnly("2021-09-10"),
+					Ministry:   catalog.WordOfLife,
+					Visibility: catalog.Public,
+					Speakers:   []string{"Pastor Vern Peltz"},
+					Scriptures: []catalog.ScriptureReference{{Book: "John", Chapter: 3, Verse: 16}},
+				},
+			},
+		},
+	}
+	buf := new(bytes.Buffer)
+
+	err := sut.printScriptureIndexPage(catalog.WordOfLife, catalog.Public, catalog.NewScriptureIndex(series), buf)
+	t.NoError(err)
+	t.T().Logf("Results of printing:\n%s", buf.String())
+	t.Contains(buf.String(), "<h1>Word of Life Scripture Index</h1>")
+	t.Contains(buf.String(), `<h3 id="john-3"`)
+	t.Contains(buf.String(), "<b>John 3:16</b>")
+	t.Contains(buf.String(), "MESSAGE-A")
+	t.Contains(buf.String(), series[0].GetCatalogFileName(catalog.Public))
+}
+
+func (t *CatalogCmdTestSuite) TestScriptureLinksOfCrossListedSeries() {
+	sut := catalogCmdStruct{}
+
+	// a CORE series listed on the partner pages of Word of Life
+	series := []catalog.CatalogSeri{
+		{
+			Name:        "SERIES",
+			Description: "Based on Romans 8:28",
+			Ministry:    catalog.CenterOfRelationshipExperience,
+			Visibility:  catalog.Public,
+			View:        catalog.Public,
+			Messages: []catalog.CatalogMessage{
+				{
+					Name:        "MESSAGE-A",
+					Description: "Teaching from John 3:16",
+					Date:        catalog.MustParseDateOnly("2021-09-10"),
+					Ministry:    catalog.CenterOfRelationshipExperience,
+					Visibility:  catalog.Public,
+					Tags:        []string{"healing"},
+				},
+			},
+		},
+	}
+	wolIndex := catalog.GetCatalogFileNameForScriptures(catalog.WordOfLife, catalog.Partner)
+	coreIndex := catalog.GetCatalogFileNameForScriptures(catalog.CenterOfRelationshipExperience, catalog.Public)
+
+	// the description of the series links to the scripture index of the page
+	buf := new(bytes.Buffer)
+	err := sut.printCatalogSeriList(catalog.WordOfLife, catalog.Partner, "az", series, buf)
+	t.NoError(err)
+	t.Contains(buf.String(), wolIndex+"#romans-8")
+	t.NotContains(buf.String(), coreIndex)
+
+	// the description of the message links to the scripture index of the page
+	buf = new(bytes.Buffer)
+	err = sut.printTagPage("catalog.tag.html", catalog.WordOfLife, catalog.Partner, "healing", nil, series, buf)
+	t.NoError(err)
+	t.T().Logf("Results of printing:\n%s", buf.String())
+	t.Contains(buf.String(), wolIndex+"#john-3")
+	t.NotContains(buf.String(), coreIndex)
+}
+
+func (t *CatalogCmdTestSuite) TestTagsOfSeries() {
+	msg := catalog.CatalogMessage{Name: "MESSAGE-A", Tags: []string{"healing", "Marriage"}}
+	series := []catalog.CatalogSeri{
+		{Messages: []catalog.CatalogMessage{msg, {Name: "MESSAGE-B", Tags: []string{"Healing"}}}},
+		{Messages: []catalog.CatalogMessage{msg}},
+	}
+
+	tags := getTagsOfSeries(series)
+	if t.Len(tags, 2) {
+		t.Equal(tagCount{Tag: "healing", Count: 2, Size: 2.0}, tags[0])
+		t.Equal(tagCount{Tag: "Marriage", Count: 1, Size: 0.8}, tags[1])
+	}
+
+	t.Nil(getTagsOfSeries(nil))
+}
+
+func (t *CatalogCmdTestSuite) TestTagTemplates() {
+	sut := catalogCmdStruct{}
+
+	series := []catalog.CatalogSeri{
+		{
+			Name: "SERIES",
+			View: catalog.Public,
+			Messages: []catalog.CatalogMessage{
+				{
+					Name:       "MESSAGE-A",
+					Date:       catalog.MustParseDateOnly("2021-09-10"),
+					Ministry:   catalog.WordOfLife,
+					Visibility: catalog.Public,
+					Tags:       []string{"healing"},
+				},
+			},
+		},
+	}
+	series[0].Normalize()
+
+	// tag cloud
+	buf := new(bytes.Buffer)
+	err := sut.printTagPage("catalog.tags.html", catalog.WordOfLife, catalog.Public, "", getTagsOfSeries(series), nil, buf)
+	t.NoError(err)
+	t.T().Logf("Results of printing:\n%s", buf.String())
+	t.Contains(buf.String(), "<h1>Word of Life Topics</h1>")
+	t.Contains(buf.String(), catalog.GetCatalogFileNameForTag(catalog.WordOfLife, catalog.Public, "healing"))
+	t.Contains(buf.String(), `title="1 message"`)
+
+	// single tag
+	buf = new(bytes.Buffer)
+	err = sut.printTagPage("catalog.tag.html", catalog.WordOfLife, catalog.Public, "healing", nil, series, buf)
+	t.NoError(err)
+	t.T().Logf("Results of printing:\n%s", buf.String())
+	t.Contains(buf.String(), "<h1>healing</h1>")
+	t.Contains(buf.String(), "MESSAGE-A")
+	t.Contains(buf.String(), "1 message in the Word of Life catalog")
+	t.Contains(buf.String(), catalog.GetCatalogFileNameForTags(catalog.WordOfLife, catalog.Public))
+
+	// the tags of the messages link to the pages of the ministry and view of the page
+	buf = new(bytes.Buffer)
+	err = sut.printTagPage("catalog.tag.html", catalog.CenterOfRelationshipExperience, catalog.Partner, "healing", nil, series, buf)
+	t.NoError(err)
+	t.Contains(buf.String(), catalog.GetCatalogFileNameForTag(catalog.CenterOfRelationshipExperience, catalog.Partner, "healing"))
+	t.NotContains(buf.String(), catalog.GetCatalogFileNameForTag(catalog.WordOfLife, catalog.Public, "healing"))
+}
+
+func (t *CatalogCmdTestSuite) TestSearchTemplate() {
+	sut := catalogCmdStruct{}
+
+	buf := new(bytes.Buffer)
+	err := sut.printSearchPage(catalog.WordOfLife, catalog.Public, buf)
+	t.NoError(err)
+	t.T().Logf("Results of printing:\n%s", buf.String())
+	t.Contains(buf.String(), catalog.GetCatalogDirNameForSearch(catalog.WordOfLife, catalog.Public))
+	t.Contains(buf.String(), "<h1>Word of Life Transcript Search</h1>")
+}
+
+// +---------------------------------------------------------------------------
+// | Search engines
+// +---------------------------------------------------------------------------
+
+func (t *CatalogCmdTestSuite) TestNoIndex() {
+	sut := catalogCmdStruct{}
+
+	for _, view := range []catalog.View{catalog.Public, catalog.Partner} {
+		seri := catalog.CatalogSeri{
+			Name: "SERIES",
+			View: view,
+			Messages: []catalog.CatalogMessage{
+				{Name: "MESSAGE-A", Date: catalog.MustParseDateOnly("2021-09-10"), Ministry: catalog.WordOfLife},
+			},
+		}
+
+		buf := new(bytes.Buffer)
+		t.NoError(sut.printCatalogSeri(&seri, buf))
+		if view == catalog.Public {
+			t.NotContains(buf.String(), "noindex")
+		} else {
+			t.Contains(buf.String(), `<meta name="robots" content="noindex, nofollow">`)
+		}
+	}
+}
+
+func (t *CatalogCmdTestSuite) TestSitemap() {
+	testDir := filepath.Join(t.T().TempDir(), "online")
+	sut := catalogCmdStruct{
+		OutputDir: testDir,
+		cat: &catalog.Catalog{
+			Series: []catalog.CatalogSeri{
+				{ID: "WOLS-A", Name: "A", Visibility: catalog.Public},
+				{ID: "WOLS-B", Name: "B", Visibility: catalog.Partner},
+			},
+			Messages: []catalog.CatalogMessage{
+				{
+					Name:       "MESSAGE-A",
+					Date:       catalog.MustParseDateOnly("2021-09-10"),
+					Ministry:   catalog.WordOfLife,
+					Visibility: catalog.Public,
+					Series:     []catalog.SeriesReference{{Name: "A", Index: 1}},
+				},
+				{
+					Name:       "MESSAGE-B",
+					Date:       catalog.MustParseDateOnly("2021-09-17"),
+					Ministry:   catalog.WordOfLife,
+					Visibility: catalog.Partner,
+					Series:     []catalog.SeriesReference{{Name: "B", Index: 1}},
+				},
+			},
+		},
+	}
+	t.Require().NoError(sut.cat.Initialize())
+
+	sitemap := sut.newSitemap([]catalog.Ministry{catalog.WordOfLife, catalog.TheBridgeOutreach})
+	if t.Len(sitemap.URLs, 5) {
+		t.Equal(getCatalogURL(GetCatalogFileNameForSeriList(catalog.WordOfLife, catalog.Public, ALPHABETICAL_ASC)), sitemap.URLs[0].Loc)
+		t.Equal("2021-09-10", sitemap.URLs[0].LastMod)
+		t.Equal(getCatalogURL("WOLS-A.html"), sitemap.URLs[3].Loc)
+		t.Equal("2021-09-10", sitemap.URLs[3].LastMod)
+		t.Equal(getCatalogURL(GetCatalogFileNameForComingSoon(catalog.WordOfLife)), sitemap.URLs[4].Loc)
+	}
+
+	t.Require().NoError(sut.initializeOutputDir())
+	t.NoError(sut.createSitemap([]catalog.Ministry{catalog.WordOfLife}))
+	t.NoError(sut.createRobotsFile())
+
+	content, err := os.ReadFile(filepath.Join(testDir, SITEMAP_FILE_NAME))
+	t.NoError(err)
+	t.Contains(string(content), `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	t.NotContains(string(content), "WOLS-B")
+
+	content, err = os.ReadFile(filepath.Join(testDir, ROBOTS_FILE_NAME))
+	t.NoError(err)
+	t.Contains(string(content), "Sitemap: "+getCatalogURL(SITEMAP_FILE_NAME))
+}
+
+func (t *CatalogCmdTestSuite) TestSeriPageMeta() {
+	sut := catalogCmdStruct{}
+
+	seri := catalog.CatalogSeri{
+		ID:          "WOLS-A",
+		Name:        "SERIES",
+		Description: "About the series",
+		Thumbnail:   "https://example.com/cover.jpg",
+		View:        catalog.Public,
+		Messages: []catalog.CatalogMessage{
+			{
+				Name:     "MESSAGE-A",
+				Date:     catalog.MustParseDateOnly("2021-09-10"),
+				Ministry: catalog.WordOfLife,
+				Speakers: []string{"Vern Peltz"},
+				Audio:    &catalog.OnlineResource{URL: "https://example.com/a.mp3"},
+			},
+			{
+				Name:     "MESSAGE-B",
+				Date:     catalog.MustParseDateOnly("2021-09-15"),
+				Ministry: catalog.WordOfLife,
+				Video:    &catalog.OnlineResource{URL: "https://example.com/b.mp4"},
+			},
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	t.NoError(sut.printCatalogSeri(&seri, buf))
+	t.T().Logf("Results of printing:\n%s", buf.String())
+	t.Contains(buf.String(), `<meta property="og:title" content="SERIES" />`)
+	t.Contains(buf.String(), `<meta property="og:description" content="About the series" />`)
+	t.Contains(buf.String(), `<meta property="og:url" content="`+getCatalogURL("WOLS-A.html")+`" />`)
+	t.Contains(buf.String(), `<meta property="og:image" content="https://example.com/cover.jpg" />`)
+	t.Contains(buf.String(), `<meta property="og:audio" content="https://example.com/a.mp3" />`)
+	t.Contains(buf.String(), `<meta property="og:video" content="https://example.com/b.mp4" />`)
+	t.Contains(buf.String(), `<script type="application/ld+json">{"@context":"https://schema.org","@type":"PodcastSeries"`)
+	t.Contains(buf.String(), `{"@type":"PodcastEpisode","associatedMedia":{"@type":"AudioObject","contentUrl":"https://example.com/a.mp3"}`)
+	t.Contains(buf.String(), `{"@type":"VideoObject","contentUrl":"https://example.com/b.mp4"`)
+
+	// pages that aren't public aren't described to search engines
+	seri.View = catalog.Partner
+	buf = new(bytes.Buffer)
+	t.NoError(sut.printCatalogSeri(&seri, buf))
+	t.Contains(buf.String(), `<meta property="og:title" content="SERIES" />`)
+	t.NotContains(buf.String(), "application/ld+json")
+}
+
+func (t *CatalogCmdTestSuite) TestSummarizeDescription() {
+	t.Equal("", summarizeDescription(""))
+	t.Equal("About the series", summarizeDescription("  About\nthe   series "))
+
+	long := strings.Repeat("word ", 100)
+	summary := summarizeDescription(long)
+	t.LessOrEqual(len([]rune(summary)), PAGE_DESCRIPTION_LENGTH)
+	t.True(strings.HasSuffix(summary, "word…"))
+}
+
+func (t *CatalogCmdTestSuite) newScheduledCatalog() *catalog.Catalog {
+	cat := &catalog.Catalog{
+		Series: []catalog.CatalogSeri{
+			{ID: "WOLS-A", Name: "A", Visibility: catalog.Public, Description: "Faith, hope; and love"},
+			{ID: "WOLS-B", Name: "B", Visibility: catalog.Public},
+		},
+		Messages: []catalog.CatalogMessage{
+			{
+				Name:       "MESSAGE-A1",
+				Date:       catalog.MustParseDateOnly("2021-09-05"),
+				Ministry:   catalog.WordOfLife,
+				Visibility: catalog.Public,
+				Series:     []catalog.SeriesReference{{Name: "A", Index: 1}},
+			},
+			{
+				Name:       "MESSAGE-A2",
+				Date:       catalog.MustParseDateOnly("2021-09-12"),
+				Ministry:   catalog.WordOfLife,
+				Visibility: catalog.Public,
+				Speakers:   []string{"Vern Peltz"},
+				Series:     []catalog.SeriesReference{{Name: "A", Index: 2}},
+			},
+			{
+				Name:       "MESSAGE-B1",
+				Date:       catalog.MustParseDateOnly("2021-09-10"),
+				Ministry:   catalog.WordOfLife,
+				Visibility: catalog.Public,
+				Series:     []catalog.SeriesReference{{Name: "B", Index: 1}},
+			},
+			{
+				Name:       "MESSAGE-B2",
+				Date:       catalog.MustParseDateOnly("2021-09-17"),
+				Ministry:   catalog.WordOfLife,
+				Visibility: catalog.Partner,
+				Series:     []catalog.SeriesReference{{Name: "B", Index: 2}},
+			},
+		},
+	}
+	t.Require().NoError(cat.Initialize())
+	return cat
+}
+
+func (t *CatalogCmdTestSuite) TestFindUpcomingSeries() {
+	sut := catalogCmdStruct{cat: t.newScheduledCatalog()}
+
+	series := sut.findUpcomingSeries(catalog.WordOfLife, catalog.MustParseDateOnly("2021-09-10"))
+	if t.Len(series, 2) {
+		// soonest first, only with scheduled public messages
+		t.Equal("B", series[0].Name)
+		if t.Len(series[0].Messages, 1) {
+			t.Equal("MESSAGE-B1", series[0].Messages[0].Name)
+		}
+		t.Equal("A", series[1].Name)
+		if t.Len(series[1].Messages, 1) {
+			t.Equal("MESSAGE-A2", series[1].Messages[0].Name)
+		}
+	}
+
+	t.Empty(sut.findUpcomingSeries(catalog.WordOfLife, catalog.MustParseDateOnly("2021-09-13")))
+}
+
+func (t *CatalogCmdTestSuite) TestComingSoonTemplate() {
+	sut := catalogCmdStruct{cat: t.newScheduledCatalog()}
+	series := sut.findUpcomingSeries(catalog.WordOfLife, catalog.MustParseDateOnly("2021-09-10"))
+
+	buf := new(bytes.Buffer)
+	t.NoError(sut.printComingSoonPage(catalog.WordOfLife, series, buf))
+	t.Contains(buf.String(), "<h1>Coming Soon</h1>")
+	t.Contains(buf.String(), "2 scheduled messages")
+	t.Contains(buf.String(), `href="webcal://`)
+	t.Contains(buf.String(), GetCalendarFileName(catalog.WordOfLife)+`" download`)
+	t.Contains(buf.String(), "MESSAGE-A2")
+	t.NotContains(buf.String(), "MESSAGE-A1")
+
+	buf = new(bytes.Buffer)
+	t.NoError(sut.printComingSoonPage(catalog.WordOfLife, nil, buf))
+	t.Contains(buf.String(), "There are no messages scheduled right now")
+}
+
+func (t *CatalogCmdTestSuite) TestCalendar() {
+	sut := catalogCmdStruct{cat: t.newScheduledCatalog()}
+	series := sut.findUpcomingSeries(catalog.WordOfLife, catalog.MustParseDateOnly("2021-09-10"))
+
+	location, err := time.LoadLocation("America/Chicago")
+	t.Require().NoError(err)
+	schedule := serviceSchedule{hour: 10, minute: 30, length: 90 * time.Minute, location: location}
+
+	buf := new(bytes.Buffer)
+	t.NoError(printCalendar(catalog.WordOfLife, series, schedule, catalog.MustParseDateOnly("2021-09-10").Time, buf))
+	t.T().Logf("Results of printing:\n%s", buf.String())
+
+	ics := buf.String()
+	t.True(strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	t.True(strings.HasSuffix(ics, "END:VCALENDAR\r\n"))
+	t.Equal(3, strings.Count(ics, "BEGIN:VEVENT"))
+
+	// messages are at the service time, in UTC (CDT is UTC-5)
+	t.Contains(ics, "SUMMARY:MESSAGE-B1\r\n")
+	t.Contains(ics, "DTSTART:20210910T153000Z\r\n")
+	t.Contains(ics, "DTEND:20210910T170000Z\r\n")
+	t.Contains(ics, "DTSTAMP:20210910T000000Z\r\n")
+
+	// the series in progress lasts all day from its first to its last message, and a series
+	// that is only one scheduled message isn't on the calendar
+	t.Contains(ics, "SUMMARY:Series: A\r\n")
+	t.Contains(ics, "DTSTART;VALUE=DATE:20210905\r\n")
+	t.Contains(ics, "DTEND;VALUE=DATE:20210913\r\n")
+	t.Contains(ics, `\n\nFaith\, hope\; and love\n\n`)
+	t.NotContains(ics, "SUMMARY:Series: B")
+
+	for _, line := range strings.Split(ics, "\r\n") {
+		t.LessOrEqual(len(line), ICS_LINE_LENGTH)
+	}
+}
+
+func (t *CatalogCmdTestSuite) TestICSWriter() {
+	buf := new(bytes.Buffer)
+	ics := &icsWriter{output: buf}
+	ics.WriteProperty("SUMMARY", strings.Repeat("é", 50))
+	t.NoError(ics.err)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+	if t.Len(lines, 2) {
+		t.Equal("SUMMARY:"+strings.Repeat("é", 33), lines[0])
+		t.Equal(" "+strings.Repeat("é", 17), lines[1])
+	}
+
+	t.Equal(`a\\b\;c\,d\ne`, icsEscape("a\\b;c,d\r\ne"))
+}
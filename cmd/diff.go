@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/spf13/cobra"
+)
+
+type diffCmdStruct struct {
+	cobra.Command // diff command definition
+
+	// flags for the diff command
+	Format string // format of the report: text, json, or markdown
+}
+
+// diffCmd represents the diff command
+var diffCmd *diffCmdStruct
+
+func init() {
+	diffCmd = &diffCmdStruct{
+		Command: cobra.Command{
+			Use:   "diff OLD-FILE [NEW-FILE] [--format=text|json|markdown]",
+			Short: "Compare two snapshots of the catalog",
+			Long: `Reports what changed between two catalogs, like a copy saved by 'dump' when the
+catalog was last published and the current online content.
+
+If NEW-FILE is not given, then the new catalog is read from the --input or
+--sheet-id. Series are matched by their ID (or name if they don't have an ID)
+and messages are matched by their name and date. The report lists the series
+and messages that were added, removed, or changed (field by field), and any
+changes of visibility, starting with anything that became public.`,
+			Example: `diff ~/.wolm/online.cache.json --sheet-id 1vvhIGMPvVF-DtWoYsEbVBvzk_VtLyKuIw_zyLdsB-JY`,
+			Args:    cobra.RangeArgs(1, 2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return diffCmd.diff(args)
+			},
+		},
+	}
+
+	rootCmd.AddCommand(&diffCmd.Command)
+
+	diffCmd.Flags().StringVar(&diffCmd.Format, "format", "text", "Format of the report: text (default), json, or markdown")
+}
+
+func (cmd *diffCmdStruct) diff(args []string) error {
+	initLogging()
+
+	oldCatalog, err := readOnlineContentFromFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	var newCatalog *catalog.Catalog
+	if len(args) > 1 {
+		newCatalog, err = readOnlineContentFromFile(args[1])
+	} else {
+		newCatalog, err = readOnlineContentFromInput(cmd.Context())
+	}
+	if err != nil {
+		return err
+	}
+
+	diff, err := catalog.NewCatalogDiff(oldCatalog, newCatalog)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(cmd.Format) {
+	case "text", "":
+		return printDiffText(diff, os.Stdout)
+	case "json":
+		return printDiffJSON(diff, os.Stdout)
+	case "markdown", "md":
+		return printDiffMarkdown(diff, os.Stdout)
+	}
+	return fmt.Errorf("unknown format '%s'. use text, json, or markdown", cmd.Format)
+}
+
+// ----------------------------------------------------------------------------
+// | Reports
+// ----------------------------------------------------------------------------
+
+// describeVisibilityChange describes how the visibility of an entry changed, like
+// "partner -> public"
+func describeVisibilityChange(entry catalog.EntryDiff) string {
+	oldVisibility, newVisibility := string(entry.OldVisibility), string(entry.NewVisibility)
+	if entry.Change == catalog.DiffAdded {
+		oldVisibility = "(added)"
+	}
+	return oldVisibility + " -> " + newVisibility
+}
+
+// describeDiffCounts summarizes the number of changes to a list of entries, like
+// "1 added, 0 removed, 2 changed"
+func describeDiffCounts(entries []catalog.EntryDiff) string {
+	counts := map[catalog.DiffChange]int{}
+	for _, entry := range entries {
+		counts[entry.Change]++
+	}
+	return fmt.Sprintf("%d added, %d removed, %d changed",
+		counts[catalog.DiffAdded], counts[catalog.DiffRemoved], counts[catalog.DiffChanged])
+}
+
+// printDiffText prints the differences as plain text
+func printDiffText(diff *catalog.CatalogDiff, output io.Writer) error {
+	if diff.IsEmpty() {
+		fmt.Fprintln(output, "No changes")
+		return nil
+	}
+
+	fmt.Fprintf(output, "Series:   %s\n", describeDiffCounts(diff.Series))
+	fmt.Fprintf(output, "Messages: %s\n", describeDiffCounts(diff.Messages))
+
+	if changes := diff.GetVisibilityChanges(); len(changes) > 0 {
+		fmt.Fprintf(output, "\nVisibility changes:\n")
+		for _, entry := range changes {
+			flag := "  "
+			if entry.BecamePublic() {
+				flag = "! "
+			}
+			fmt.Fprintf(output, "  %s%s '%s': %s\n", flag, entry.Kind, describeDiffEntry(entry), describeVisibilityChange(entry))
+		}
+	}
+
+	for _, section := range []struct {
+		title   string
+		entries []catalog.EntryDiff
+	}{{"Series", diff.Series}, {"Messages", diff.Messages}} {
+		if len(section.entries) == 0 {
+			continue
+		}
+		fmt.Fprintf(output, "\n%s:\n", section.title)
+		for _, entry := range section.entries {
+			switch entry.Change {
+			case catalog.DiffAdded:
+				fmt.Fprintf(output, "  + %s (%s)\n", describeDiffEntry(entry), entry.NewVisibility)
+			case catalog.DiffRemoved:
+				fmt.Fprintf(output, "  - %s (%s)\n", describeDiffEntry(entry), entry.OldVisibility)
+			case catalog.DiffChanged:
+				fmt.Fprintf(output, "  ~ %s\n", describeDiffEntry(entry))
+				for _, field := range entry.Fields {
+					fmt.Fprintf(output, "      %s: %s -> %s\n", field.Field, describeDiffValue(field.Old), describeDiffValue(field.New))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// printDiffMarkdown prints the differences as Markdown, suitable for pasting into an issue or
+// email
+func printDiffMarkdown(diff *catalog.CatalogDiff, output io.Writer) error {
+	fmt.Fprintf(output, "# Catalog changes\n\n")
+	if diff.IsEmpty() {
+		fmt.Fprintln(output, "No changes")
+		return nil
+	}
+
+	fmt.Fprintf(output, "- **Series:** %s\n", describeDiffCounts(diff.Series))
+	fmt.Fprintf(output, "- **Messages:** %s\n", describeDiffCounts(diff.Messages))
+
+	if changes := diff.GetVisibilityChanges(); len(changes) > 0 {
+		fmt.Fprintf(output, "\n## Visibility changes\n\n")
+		fmt.Fprintf(output, "| | Kind | Entry | Visibility |\n|---|---|---|---|\n")
+		for _, entry := range changes {
+			flag := ""
+			if entry.BecamePublic() {
+				flag = "**public**"
+			}
+			fmt.Fprintf(output, "| %s | %s | %s | %s |\n", flag, entry.Kind, escapeMarkdown(describeDiffEntry(entry)), describeVisibilityChange(entry))
+		}
+	}
+
+	for _, section := range []struct {
+		title   string
+		entries []catalog.EntryDiff
+	}{{"Series", diff.Series}, {"Messages", diff.Messages}} {
+		if len(section.entries) == 0 {
+			continue
+		}
+		fmt.Fprintf(output, "\n## %s\n", section.title)
+		for _, entry := range section.entries {
+			switch entry.Change {
+			case catalog.DiffAdded:
+				fmt.Fprintf(output, "\n- **Added** %s (%s)\n", escapeMarkdown(describeDiffEntry(entry)), entry.NewVisibility)
+			case catalog.DiffRemoved:
+				fmt.Fprintf(output, "\n- **Removed** %s (%s)\n", escapeMarkdown(describeDiffEntry(entry)), entry.OldVisibility)
+			case catalog.DiffChanged:
+				fmt.Fprintf(output, "\n- **Changed** %s\n\n", escapeMarkdown(describeDiffEntry(entry)))
+				fmt.Fprintf(output, "  | Field | Old | New |\n  |---|---|---|\n")
+				for _, field := range entry.Fields {
+					fmt.Fprintf(output, "  | %s | %s | %s |\n", field.Field,
+						escapeMarkdown(describeDiffValue(field.Old)), escapeMarkdown(describeDiffValue(field.New)))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// printDiffJSON prints the differences as JSON, with the visibility changes in their own list
+func printDiffJSON(diff *catalog.CatalogDiff, output io.Writer) error {
+	report := struct {
+		*catalog.CatalogDiff
+		Visibility []catalog.EntryDiff `json:"visibility-changes,omitempty"`
+	}{
+		CatalogDiff: diff,
+		Visibility:  diff.GetVisibilityChanges(),
+	}
+
+	bytes, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(output, string(bytes))
+	return nil
+}
+
+// describeDiffEntry describes which series or message an entry is. Messages are described by
+// their key (name and date), and series by their name and key (ID)
+func describeDiffEntry(entry catalog.EntryDiff) string {
+	if strings.HasPrefix(entry.Key, entry.Name) {
+		return entry.Key
+	}
+	return fmt.Sprintf("%s [%s]", entry.Name, entry.Key)
+}
+
+// describeDiffValue describes the JSON value of a field for a report
+func describeDiffValue(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
+
+// escapeMarkdown escapes the characters in text that would break a Markdown table
+func escapeMarkdown(text string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(text)
+}
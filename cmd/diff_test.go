@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestDiffCmdTestSuite(t *testing.T) {
+	suite.Run(t, new(DiffCmdTestSuite))
+}
+
+type DiffCmdTestSuite struct {
+	suite.Suite
+	diff *catalog.CatalogDiff
+}
+
+func (t *DiffCmdTestSuite) SetupTest() {
+	oldCatalog := &catalog.Catalog{
+		Series:   []catalog.CatalogSeri{{ID: "S1", Name: "Series", Visibility: catalog.Partner}},
+		Messages: []catalog.CatalogMessage{{Name: "Old | Message", Date: catalog.MustParseDateOnly("2021-09-10"), Visibility: catalog.Public}},
+	}
+	newCatalog := &catalog.Catalog{
+		Series:   []catalog.CatalogSeri{{ID: "S1", Name: "Series", Visibility: catalog.Public}},
+		Messages: []catalog.CatalogMessage{{Name: "New Message", Date: catalog.MustParseDateOnly("2021-09-17"), Visibility: catalog.Private}},
+	}
+
+	var err error
+	t.diff, err = catalog.NewCatalogDiff(oldCatalog, newCatalog)
+	t.Require().NoError(err)
+}
+
+func (t *DiffCmdTestSuite) TestText() {
+	buf := new(bytes.Buffer)
+	t.NoError(printDiffText(t.diff, buf))
+	t.T().Logf("Results of printing:\n%s", buf.String())
+	t.Contains(buf.String(), "Series:   0 added, 0 removed, 1 changed")
+	t.Contains(buf.String(), "Messages: 1 added, 1 removed, 0 changed")
+	t.Contains(buf.String(), "! series 'Series [S1]': partner -> public")
+	t.Contains(buf.String(), `visibility: "partner" -> "public"`)
+	t.Contains(buf.String(), "+ New Message (2021-09-17) (private)")
+	t.Contains(buf.String(), "- Old | Message (2021-09-10) (public)")
+}
+
+func (t *DiffCmdTestSuite) TestMarkdown() {
+	buf := new(bytes.Buffer)
+	t.NoError(printDiffMarkdown(t.diff, buf))
+	t.T().Logf("Results of printing:\n%s", buf.String())
+	t.Contains(buf.String(), "| **public** | series | Series [S1] | partner -> public |")
+	t.Contains(buf.String(), `| visibility | "partner" | "public" |`)
+	t.Contains(buf.String(), `- **Removed** Old \| Message (2021-09-10) (public)`)
+}
+
+func (t *DiffCmdTestSuite) TestJSON() {
+	buf := new(bytes.Buffer)
+	t.NoError(printDiffJSON(t.diff, buf))
+
+	var report map[string][]catalog.EntryDiff
+	t.NoError(json.Unmarshal(buf.Bytes(), &report))
+	t.Len(report["series"], 1)
+	t.Len(report["messages"], 2)
+	if t.Len(report["visibility-changes"], 1) {
+		t.Equal("S1", report["visibility-changes"][0].Key)
+	}
+}
+
+func (t *DiffCmdTestSuite) TestNoChanges() {
+	buf := new(bytes.Buffer)
+	t.NoError(printDiffText(&catalog.CatalogDiff{}, buf))
+	t.Equal("No changes\n", buf.String())
+}
@@ -1,24 +1,49 @@
 package cmd
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/WordOfLifeMN/online/gclient"
 	"github.com/WordOfLifeMN/online/util"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
 // dumpCmd represents the dump command
 var dumpCmd = &cobra.Command{
-	Use:     "dump [--sheet-id ID | --input FILE]",
-	Short:   "Read the content and output the data in JSON",
-	Long:    `Used to make a local copy of the data.`,
-	Example: `dump --sheet-id 1vvhIGMPvVF-DtWoYsEbVBvzk_VtLyKuIw_zyLdsB-JY >/tmp/catalog.json`,
-	RunE:    dump,
+	Use:   "dump [--sheet-id ID | --input FILE] [--format json|yaml|csv|xlsx] [--enrich]",
+	Short: "Read the content and output the data in JSON, YAML, CSV, or XLSX",
+	Long: `Used to make a local copy of the data.
+
+JSON and YAML have all the data of the catalog. CSV and XLSX are in the shape of
+the spreadsheet, with one row for each message, so they can be handed to someone
+to edit and read back in with --input. XLSX has a tab for each ministry and a
+"Series" tab with all of the series, so the series are read back in unchanged.
+CSV has a "Series" row (or "Booklet" row for a series without messages) before
+the first message of each series. The ID, dates, and jacket of a series aren't
+in those rows, and the ID is made from the name when it's read back in.
+
+With --enrich, the facts about the media of each message are found and kept
+with the message: whether there is a transcript, and the size, content type,
+and duration of the audio. Then 'catalog' and 'podcast' can run from the JSON
+or YAML without looking them up on the network. The CSV and XLSX don't have
+the facts.
+
+If --output isn't given, the extension of the default file is changed to match
+the format.`,
+	Example: `dump --sheet-id 1vvhIGMPvVF-DtWoYsEbVBvzk_VtLyKuIw_zyLdsB-JY >/tmp/catalog.json
+dump --format xlsx --output /tmp/catalog.xlsx
+dump --enrich --output ~/.wolm/online.cache.json`,
+	RunE: dump,
 }
 
 func init() {
@@ -26,25 +51,41 @@ func init() {
 
 	dumpCmd.Flags().StringP("output", "o", "~/.wolm/online.cache.json", "File to output to")
 	viper.BindPFlag("output", dumpCmd.Flags().Lookup("output"))
+	dumpCmd.Flags().String("format", "json", "Format of the output: json (default), yaml, csv, or xlsx")
+	dumpCmd.Flags().Bool("enrich", false, "Find whether each message has a transcript, and the size, type, and duration of its audio (uses the network)")
 }
 
 func dump(cmd *cobra.Command, args []string) error {
 	initLogging()
 
+	format, _ := cmd.Flags().GetString("format")
+	format = strings.ToLower(format)
+	if _, ok := dumpFormats[format]; !ok {
+		return fmt.Errorf("unknown format '%s'. use json, yaml, csv, or xlsx", format)
+	}
+
 	catalog, err := readOnlineContentFromInput(cmd.Context())
 	if err != nil {
 		return err
 	}
+	if enrich, _ := cmd.Flags().GetBool("enrich"); enrich {
+		if err := catalog.LoadMediaFacts(cmd.Context()); err != nil {
+			return err
+		}
+	}
 
-	bytes, err := json.MarshalIndent(catalog, "", "  ")
-	if err != nil {
+	var output bytes.Buffer
+	if err := dumpFormats[format](catalog, &output); err != nil {
 		return err
 	}
 
 	outFileName := viper.GetString("output")
+	if !cmd.Flags().Changed("output") && strings.HasSuffix(outFileName, ".json") {
+		outFileName = strings.TrimSuffix(outFileName, filepath.Ext(outFileName)) + "." + format
+	}
 	// fmt.Printf("TODO(km) outFileName = %s\n", outFileName)
 	if outFileName == "" || strings.Contains(outFileName, "stdout") {
-		fmt.Print(string(bytes))
+		os.Stdout.Write(output.Bytes())
 	} else {
 		outFile, err := os.Create(util.NormalizePath(outFileName))
 		if err != nil {
@@ -52,8 +93,63 @@ func dump(cmd *cobra.Command, args []string) error {
 		}
 		defer outFile.Close()
 		log.Printf("Writing message data to %s\n", outFileName)
-		fmt.Fprint(outFile, string(bytes))
+		if _, err := outFile.Write(output.Bytes()); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+// ----------------------------------------------------------------------------
+// | Formats
+// ----------------------------------------------------------------------------
+
+// functions that write a catalog in each format of the dump
+var dumpFormats = map[string]func(cat *catalog.Catalog, output io.Writer) error{
+	"json": dumpJSON,
+	"yaml": dumpYAML,
+	"csv":  gclient.WriteCatalogAsCSV,
+	"xlsx": gclient.WriteCatalogAsXLSX,
+}
+
+// dumpJSON writes the catalog as indented JSON
+func dumpJSON(cat *catalog.Catalog, output io.Writer) error {
+	bytes, err := json.MarshalIndent(cat, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = output.Write(bytes)
+	return err
+}
+
+// dumpYAML writes the catalog as YAML. The YAML has the same fields in the same order as the
+// JSON, because it's made from the JSON (which is also YAML) with the styles of JSON removed
+func dumpYAML(cat *catalog.Catalog, output io.Writer) error {
+	bytes, err := json.Marshal(cat)
+	if err != nil {
+		return err
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(bytes, &document); err != nil {
+		return err
+	}
+	clearYAMLStyle(&document)
+
+	encoder := yaml.NewEncoder(output)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// clearYAMLStyle removes the flow style and quotes of a node and everything in it, so it's
+// written in the plain block style of YAML. Strings that need quotes still get them
+func clearYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearYAMLStyle(child)
+	}
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v3"
+)
+
+func TestDumpCmdTestSuite(t *testing.T) {
+	suite.Run(t, new(DumpCmdTestSuite))
+}
+
+type DumpCmdTestSuite struct {
+	suite.Suite
+}
+
+func (t *DumpCmdTestSuite) TestYAML() {
+	cat := &catalog.Catalog{
+		Series: []catalog.CatalogSeri{{ID: "S1", Name: "Faith: Part 1", Visibility: catalog.Public}},
+		Messages: []catalog.CatalogMessage{{
+			Name:       "true",
+			Date:       catalog.MustParseDateOnly("2021-09-10"),
+			Speakers:   []string{"Vern Peltz"},
+			Visibility: catalog.Public,
+			Audio:      &catalog.OnlineResource{URL: "https://example.com/a.mp3", Metadata: map[string]string{"seconds": "123"}},
+		}},
+	}
+
+	buf := new(bytes.Buffer)
+	t.Require().NoError(dumpYAML(cat, buf))
+	t.T().Logf("Results of printing:\n%s", buf.String())
+	t.Contains(buf.String(), "\n  - id: S1\n    name: 'Faith: Part 1'\n")
+	t.Contains(buf.String(), "\n  - date: \"2021-09-10\"\n    name: \"true\"\n")
+
+	// the YAML has the same data as the JSON
+	var fromYAML, fromJSON any
+	t.Require().NoError(yaml.Unmarshal(buf.Bytes(), &fromYAML))
+	buf.Reset()
+	t.Require().NoError(dumpJSON(cat, buf))
+	t.Require().NoError(json.Unmarshal(buf.Bytes(), &fromJSON))
+	t.Equal(fromJSON, fromYAML)
+}
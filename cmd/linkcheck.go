@@ -0,0 +1,411 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/WordOfLifeMN/online/util"
+	"github.com/spf13/cobra"
+)
+
+type linkcheckCmdStruct struct {
+	cobra.Command // linkcheck command definition
+
+	// flags for the linkcheck command
+	CacheFile string        // file the results of checking links are kept in
+	TTL       time.Duration // how long a link that worked is trusted
+	Timeout   time.Duration // how long to wait for a link to respond
+	Workers   int           // number of links checked at the same time
+}
+
+// catalogLink is a URL in the catalog and where it is used
+type catalogLink struct {
+	URL     string // URL of the link
+	Field   string // what the link is, like "audio" or "booklet"
+	Seri    string // name of the series the link is in, or the first series of its message
+	Message string // name of the message the link is in, or "" if it's a link of the series
+	Public  bool   // the link is in public content
+}
+
+// linkStatus is the result of checking a link
+type linkStatus struct {
+	Checked time.Time `json:"checked"`           // when the link was checked
+	Status  int       `json:"status,omitempty"`  // HTTP status of the response, 0 if there was no response
+	Problem string    `json:"problem,omitempty"` // why the link is broken, "" if it works
+}
+
+// user agent the links are requested with. some sites don't answer requests that don't look like
+// they come from a browser
+const LINKCHECK_USER_AGENT = "Mozilla/5.0 (compatible; wolm-online-linkcheck/1.0)"
+
+// number of bytes of a video page that are read to find out if the video is unavailable
+const LINKCHECK_PAGE_SIZE = 2 * 1024 * 1024
+
+// text in the pages of video sites that shows the video is unavailable even though the page is
+// found, by the domain of the site
+var unavailableVideoMarkers = map[string][]string{
+	"youtube.com": {
+		`"playabilityStatus":{"status":"ERROR"`,
+		`"playabilityStatus":{"status":"UNPLAYABLE"`,
+	},
+	"rumble.com": {
+		"This video has been removed",
+		"This video is unavailable",
+		"Video unavailable",
+	},
+}
+
+// linkcheckCmd represents the linkcheck command
+var linkcheckCmd *linkcheckCmdStruct
+
+func init() {
+	linkcheckCmd = &linkcheckCmdStruct{
+		Command: cobra.Command{
+			Use:   "linkcheck [--ttl=24h] [--workers=8] [--cache=~/.wolm/linkcheck.cache.json]",
+			Short: "Check that every link in the catalog works",
+			Long: `Checks every link in the catalog: the audio, video, thumbnail, and resources
+of the messages, and the booklets, resources, jacket, and thumbnail of the
+series.
+
+Redirects are followed, and YouTube and Rumble pages that say the video is
+unavailable are broken even though the page is there. The broken links are
+reported by series and message.
+
+The links that work are kept in --cache so they aren't checked again until they
+are older than --ttl. Use --ttl=0 to check every link again. Broken links are
+checked again every time, so a fixed link is no longer reported.
+
+The command fails if any link in public content is broken. Broken links in
+partner and private content are only reported.`,
+			Example: `linkcheck --input ~/.wolm/online.cache.json --ttl 0`,
+			Args:    cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return linkcheckCmd.linkcheck()
+			},
+		},
+	}
+
+	rootCmd.AddCommand(&linkcheckCmd.Command)
+
+	linkcheckCmd.Flags().StringVar(&linkcheckCmd.CacheFile, "cache", "~/.wolm/linkcheck.cache.json", "File to keep the results of checking links in, or \"\" to not keep them")
+	linkcheckCmd.Flags().DurationVar(&linkcheckCmd.TTL, "ttl", 24*time.Hour, "How long a link that worked is trusted before checking it again")
+	linkcheckCmd.Flags().DurationVar(&linkcheckCmd.Timeout, "timeout", 30*time.Second, "How long to wait for a link to respond")
+	linkcheckCmd.Flags().IntVar(&linkcheckCmd.Workers, "workers", 8, "Number of links to check at the same time")
+}
+
+func (cmd *linkcheckCmdStruct) linkcheck() error {
+	initLogging()
+
+	cat, err := readOnlineContentFromInput(cmd.Context())
+	if err != nil {
+		return err
+	}
+	links := getCatalogLinks(cat)
+
+	// only check the links that weren't checked recently
+	cacheFile := ""
+	if cmd.CacheFile != "" {
+		cacheFile = util.NormalizePath(cmd.CacheFile)
+	}
+	statuses := loadLinkCache(cacheFile, cmd.TTL, time.Now())
+	var urls []string
+	recent := 0
+	seen := map[string]bool{}
+	for _, link := range links {
+		if seen[link.URL] {
+			continue
+		}
+		seen[link.URL] = true
+		if _, ok := statuses[link.URL]; ok {
+			recent++
+		} else {
+			urls = append(urls, link.URL)
+		}
+	}
+	log.Printf("Checking %d links (%d worked in the last %s)", len(urls), recent, cmd.TTL)
+
+	client := &http.Client{Timeout: cmd.Timeout}
+	for url, status := range checkLinks(cmd.Context(), client, urls, cmd.Workers) {
+		statuses[url] = status
+	}
+	if err := saveLinkCache(cacheFile, statuses); err != nil {
+		log.Printf("WARNING: %s", err.Error())
+	}
+
+	// report the broken links
+	report := util.NewIndentingReport(util.ReportOut)
+	broken, publicBroken := reportBrokenLinks(report, cat, links, statuses)
+	if publicBroken > 0 {
+		return fmt.Errorf("%d of %d links are broken, and %d are in public content (see report above)", broken, len(links), publicBroken)
+	}
+	fmt.Printf("Checked %d links: %d broken\n", len(links), broken)
+
+	return nil
+}
+
+// getCatalogLinks gets all the links of the series and messages in the catalog. Links that are
+// only paths, like thumbnails in the catalog, are not included
+func getCatalogLinks(cat *catalog.Catalog) []catalogLink {
+	var links []catalogLink
+	add := func(url string, field string, seri string, message string, visibility catalog.View) {
+		if !strings.Contains(url, "://") {
+			return
+		}
+		links = append(links, catalogLink{
+			URL:     url,
+			Field:   field,
+			Seri:    seri,
+			Message: message,
+			Public:  catalog.IsVisibleInView(visibility, catalog.Public),
+		})
+	}
+
+	for _, seri := range cat.Series {
+		for _, booklet := range seri.Booklets {
+			add(booklet.URL, "booklet", seri.Name, "", seri.Visibility)
+		}
+		for _, resource := range seri.Resources {
+			add(resource.URL, "resource", seri.Name, "", seri.Visibility)
+		}
+		add(seri.Jacket, "jacket", seri.Name, "", seri.Visibility)
+		add(seri.Thumbnail, "thumbnail", seri.Name, "", seri.Visibility)
+	}
+
+	for _, msg := range cat.Messages {
+		seriName := ""
+		if len(msg.Series) > 0 {
+			seriName = msg.Series[0].Name
+		}
+		messageName := fmt.Sprintf("%s %s", msg.Date.String(), msg.Name)
+		if msg.Audio != nil {
+			add(msg.Audio.URL, "audio", seriName, messageName, msg.Visibility)
+		}
+		if msg.Video != nil {
+			add(msg.Video.URL, "video", seriName, messageName, msg.Visibility)
+		}
+		if msg.Thumb != nil {
+			add(msg.Thumb.URL, "thumb", seriName, messageName, msg.Visibility)
+		}
+		for _, resource := range msg.Resources {
+			add(resource.URL, "resource", seriName, messageName, msg.Visibility)
+		}
+	}
+
+	return links
+}
+
+// checkLinks checks the links with a number of workers at the same time, and returns the status
+// of each link by URL
+func checkLinks(ctx context.Context, client *http.Client, urls []string, workers int) map[string]linkStatus {
+	statuses := map[string]linkStatus{}
+	var statusesMu sync.Mutex
+
+	queue := make(chan string)
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Go(func() {
+			for url := range queue {
+				status := checkLink(ctx, client, url)
+				if status.Problem != "" {
+					log.Printf("  Broken: %s: %s", url, status.Problem)
+				}
+				statusesMu.Lock()
+				statuses[url] = status
+				statusesMu.Unlock()
+			}
+		})
+	}
+	for _, url := range urls {
+		queue <- url
+	}
+	close(queue)
+	wg.Wait()
+
+	return statuses
+}
+
+// checkLink checks that a link works. The link is broken if it can't be requested, if it gets an
+// error status after following the redirects, or if it is a page for a video that is unavailable
+func checkLink(ctx context.Context, client *http.Client, url string) linkStatus {
+	status := linkStatus{Checked: time.Now()}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		status.Problem = err.Error()
+		return status
+	}
+	request.Header.Set("User-Agent", LINKCHECK_USER_AGENT)
+	response, err := client.Do(request)
+	if err != nil {
+		// the error already has the URL in it, so only keep the reason
+		if reason := errors.Unwrap(err); reason != nil {
+			err = reason
+		}
+		status.Problem = err.Error()
+		return status
+	}
+	defer response.Body.Close()
+
+	status.Status = response.StatusCode
+	if response.StatusCode >= 400 {
+		status.Problem = response.Status
+		return status
+	}
+
+	// video sites have a page even for videos that are gone
+	markers := getUnavailableVideoMarkers(response.Request.URL.Hostname())
+	if len(markers) == 0 {
+		return status
+	}
+	page, err := io.ReadAll(io.LimitReader(response.Body, LINKCHECK_PAGE_SIZE))
+	if err != nil {
+		status.Problem = err.Error()
+		return status
+	}
+	for _, marker := range markers {
+		if strings.Contains(string(page), marker) {
+			status.Problem = "video unavailable"
+			return status
+		}
+	}
+	return status
+}
+
+// getUnavailableVideoMarkers gets the text that shows a video is unavailable for the host of a
+// video site, or nil if it isn't a video site
+func getUnavailableVideoMarkers(host string) []string {
+	host = strings.ToLower(host)
+	for domain, markers := range unavailableVideoMarkers {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return markers
+		}
+	}
+	return nil
+}
+
+// reportBrokenLinks reports the broken links grouped by series and message, and returns the
+// number of broken links and how many of them are in public content
+func reportBrokenLinks(report *util.IndentingReport, cat *catalog.Catalog, links []catalogLink, statuses map[string]linkStatus) (int, int) {
+	// series in the order of the catalog, then the ones that are only named by messages
+	var seriNames []string
+	linksBySeri := map[string][]catalogLink{}
+	for _, seri := range cat.Series {
+		if _, ok := linksBySeri[seri.Name]; !ok {
+			seriNames = append(seriNames, seri.Name)
+			linksBySeri[seri.Name] = nil
+		}
+	}
+	for _, link := range links {
+		if _, ok := linksBySeri[link.Seri]; !ok {
+			seriNames = append(seriNames, link.Seri)
+		}
+		linksBySeri[link.Seri] = append(linksBySeri[link.Seri], link)
+	}
+
+	broken := 0
+	publicBroken := 0
+	for _, seriName := range seriNames {
+		if seriName == "" {
+			report.StartSection("Messages without a series")
+		} else {
+			report.StartSection(fmt.Sprintf("Series %q", seriName))
+		}
+
+		messageName := ""
+		for _, link := range linksBySeri[seriName] {
+			status := statuses[link.URL]
+			if status.Problem == "" {
+				continue
+			}
+			if link.Message != messageName {
+				if messageName != "" {
+					report.StopSection()
+				}
+				messageName = link.Message
+				report.StartSection(fmt.Sprintf("Message %q", messageName))
+			}
+
+			broken++
+			visibility := ""
+			if link.Public {
+				publicBroken++
+			} else {
+				visibility = " (not public)"
+			}
+			report.Printf("%s %s: %s%s", link.Field, link.URL, status.Problem, visibility)
+		}
+		if messageName != "" {
+			report.StopSection()
+		}
+
+		report.StopSection()
+	}
+
+	return broken, publicBroken
+}
+
+// loadLinkCache loads the links that worked when they were checked within the TTL. Broken links
+// are always checked again, since most are fixed soon after they are reported. Returns an empty
+// cache if there is no cache file, or it can't be read
+func loadLinkCache(cacheFile string, ttl time.Duration, now time.Time) map[string]linkStatus {
+	statuses := map[string]linkStatus{}
+	if cacheFile == "" {
+		return statuses
+	}
+
+	data, err := os.ReadFile(cacheFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return statuses
+	}
+	var cached map[string]linkStatus
+	if err == nil {
+		err = json.Unmarshal(data, &cached)
+	}
+	if err != nil {
+		log.Printf("WARNING: Cannot read the link cache %s, so all the links will be checked: %s", cacheFile, err.Error())
+		return statuses
+	}
+
+	for url, status := range cached {
+		if status.Problem == "" && now.Sub(status.Checked) < ttl {
+			statuses[url] = status
+		}
+	}
+	return statuses
+}
+
+// saveLinkCache saves the links that worked. The broken links aren't saved
+func saveLinkCache(cacheFile string, statuses map[string]linkStatus) error {
+	if cacheFile == "" {
+		return nil
+	}
+
+	working := map[string]linkStatus{}
+	for url, status := range statuses {
+		if status.Problem == "" {
+			working[url] = status
+		}
+	}
+	data, err := json.MarshalIndent(working, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot save the link cache %s: %w", cacheFile, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0777); err != nil {
+		return fmt.Errorf("cannot save the link cache %s: %w", cacheFile, err)
+	}
+	if err := os.WriteFile(cacheFile, data, 0666); err != nil {
+		return fmt.Errorf("cannot save the link cache %s: %w", cacheFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/WordOfLifeMN/online/util"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestLinkcheckCmdTestSuite(t *testing.T) {
+	suite.Run(t, new(LinkcheckCmdTestSuite))
+}
+
+type LinkcheckCmdTestSuite struct {
+	suite.Suite
+}
+
+// roundTripFunc lets a function be the transport of a client
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// newLinkServer serves pages for every host, and returns a client that sends all requests to it
+func (t *LinkcheckCmdTestSuite) newLinkServer() *http.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Host + r.URL.Path {
+		case "example.com/ok.pdf", "www.youtube.com/watch":
+			if r.URL.Query().Get("v") == "gone" {
+				fmt.Fprint(w, `<script>var ytInitialPlayerResponse = {"playabilityStatus":{"status":"ERROR","reason":"Video unavailable"}};</script>`)
+				return
+			}
+			fmt.Fprint(w, `<html>OK</html>`)
+		case "example.com/moved.pdf":
+			http.Redirect(w, r, "/ok.pdf", http.StatusMovedPermanently)
+		case "example.com/moved-away.pdf":
+			http.Redirect(w, r, "/missing.pdf", http.StatusFound)
+		case "rumble.com/v123-removed.html":
+			fmt.Fprint(w, `<html><h1>This video has been removed</h1></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.T().Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	t.Require().NoError(err)
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			serverRequest := r.Clone(r.Context())
+			serverRequest.URL.Scheme = serverURL.Scheme
+			serverRequest.Host = r.URL.Host
+			serverRequest.URL.Host = serverURL.Host
+			response, err := http.DefaultTransport.RoundTrip(serverRequest)
+			if response != nil {
+				response.Request = r
+			}
+			return response, err
+		}),
+	}
+}
+
+func (t *LinkcheckCmdTestSuite) TestCheckLinks() {
+	client := t.newLinkServer()
+
+	statuses := checkLinks(context.Background(), client, []string{
+		"https://example.com/ok.pdf",
+		"https://example.com/missing.pdf",
+		"https://example.com/moved.pdf",
+		"https://example.com/moved-away.pdf",
+		"https://www.youtube.com/watch?v=here",
+		"https://www.youtube.com/watch?v=gone",
+		"https://rumble.com/v123-removed.html",
+		"not a url",
+	}, 3)
+
+	t.Len(statuses, 8)
+	t.Equal("", statuses["https://example.com/ok.pdf"].Problem)
+	t.Equal(http.StatusOK, statuses["https://example.com/ok.pdf"].Status)
+	t.False(statuses["https://example.com/ok.pdf"].Checked.IsZero())
+	t.Equal("404 Not Found", statuses["https://example.com/missing.pdf"].Problem)
+	t.Equal(http.StatusNotFound, statuses["https://example.com/missing.pdf"].Status)
+	t.Equal("", statuses["https://example.com/moved.pdf"].Problem)
+	t.Equal("404 Not Found", statuses["https://example.com/moved-away.pdf"].Problem)
+	t.Equal("", statuses["https://www.youtube.com/watch?v=here"].Problem)
+	t.Equal("video unavailable", statuses["https://www.youtube.com/watch?v=gone"].Problem)
+	t.Equal("video unavailable", statuses["https://rumble.com/v123-removed.html"].Problem)
+	t.NotEqual("", statuses["not a url"].Problem)
+}
+
+func (t *LinkcheckCmdTestSuite) TestGetCatalogLinks() {
+	cat := &catalog.Catalog{
+		Series: []catalog.CatalogSeri{
+			{
+				Name:       "SERIES",
+				Visibility: catalog.Public,
+				Booklets:   []catalog.OnlineResource{{URL: "https://example.com/booklet.pdf"}},
+				Jacket:     "https://example.com/jacket.jpg",
+				Thumbnail:  "static/img/thumb.jpg",
+			},
+		},
+		Messages: []catalog.CatalogMessage{
+			{
+				Name:       "MESSAGE",
+				Date:       catalog.MustParseDateOnly("2024-01-07"),
+				Visibility: catalog.Partner,
+				Series:     []catalog.SeriesReference{{Name: "SERIES", Index: 1}},
+				Audio:      &catalog.OnlineResource{URL: "https://example.com/audio.mp3"},
+				Resources:  []catalog.OnlineResource{{URL: "https://example.com/notes.pdf"}},
+			},
+		},
+	}
+
+	t.Equal([]catalogLink{
+		{URL: "https://example.com/booklet.pdf", Field: "booklet", Seri: "SERIES", Public: true},
+		{URL: "https://example.com/jacket.jpg", Field: "jacket", Seri: "SERIES", Public: true},
+		{URL: "https://example.com/audio.mp3", Field: "audio", Seri: "SERIES", Message: "2024-01-07 MESSAGE"},
+		{URL: "https://example.com/notes.pdf", Field: "resource", Seri: "SERIES", Message: "2024-01-07 MESSAGE"},
+	}, getCatalogLinks(cat))
+}
+
+func (t *LinkcheckCmdTestSuite) TestReportBrokenLinks() {
+	cat := &catalog.Catalog{Series: []catalog.CatalogSeri{{Name: "EMPTY"}, {Name: "SERIES"}}}
+	links := []catalogLink{
+		{URL: "https://example.com/booklet.pdf", Field: "booklet", Seri: "SERIES", Public: true},
+		{URL: "https://example.com/a.mp3", Field: "audio", Seri: "SERIES", Message: "2024-01-07 A", Public: true},
+		{URL: "https://example.com/a.pdf", Field: "resource", Seri: "SERIES", Message: "2024-01-07 A", Public: true},
+		{URL: "https://example.com/b.mp3", Field: "audio", Seri: "SERIES", Message: "2024-01-14 B", Public: true},
+		{URL: "https://example.com/c.mp3", Field: "audio", Message: "2024-01-21 C"},
+	}
+	statuses := map[string]linkStatus{
+		"https://example.com/booklet.pdf": {Status: 404, Problem: "404 Not Found"},
+		"https://example.com/a.mp3":       {Status: 200},
+		"https://example.com/a.pdf":       {Status: 403, Problem: "403 Forbidden"},
+		"https://example.com/b.mp3":       {Status: 200},
+		"https://example.com/c.mp3":       {Problem: "timeout"},
+	}
+
+	report := util.NewIndentingReport(util.ReportSilent)
+	broken, publicBroken := reportBrokenLinks(report, cat, links, statuses)
+
+	t.Equal(3, broken)
+	t.Equal(2, publicBroken)
+	t.Equal(`Series "SERIES":
+   booklet https://example.com/booklet.pdf: 404 Not Found
+   Message "2024-01-07 A":
+      resource https://example.com/a.pdf: 403 Forbidden
+Messages without a series:
+   Message "2024-01-21 C":
+      audio https://example.com/c.mp3: timeout (not public)
+`, report.String())
+}
+
+func (t *LinkcheckCmdTestSuite) TestLinkCache() {
+	cacheFile := filepath.Join(t.T().TempDir(), "wolm", "linkcheck.cache.json")
+	now := time.Now()
+
+	// nothing cached yet
+	t.Empty(loadLinkCache(cacheFile, time.Hour, now))
+
+	t.Require().NoError(saveLinkCache(cacheFile, map[string]linkStatus{
+		"https://example.com/new.pdf":    {Checked: now.Add(-time.Minute), Status: 200},
+		"https://example.com/old.pdf":    {Checked: now.Add(-2 * time.Hour), Status: 200},
+		"https://example.com/broken.pdf": {Checked: now.Add(-time.Minute), Status: 404, Problem: "404 Not Found"},
+	}))
+
+	// only the recent results are used
+	statuses := loadLinkCache(cacheFile, time.Hour, now)
+	t.Len(statuses, 1)
+	t.Equal(200, statuses["https://example.com/new.pdf"].Status)
+	t.Empty(loadLinkCache(cacheFile, 0, now))
+	t.Empty(loadLinkCache("", time.Hour, now))
+
+	// broken links aren't saved
+	data, err := os.ReadFile(cacheFile)
+	t.Require().NoError(err)
+	t.NotContains(string(data), "broken.pdf")
+}
+
+func (t *LinkcheckCmdTestSuite) TestLinkCache_BrokenLinksAreCheckedAgain() {
+	cacheFile := filepath.Join(t.T().TempDir(), "linkcheck.cache.json")
+	now := time.Now()
+
+	// a cache written before broken links were left out of it
+	t.Require().NoError(os.WriteFile(cacheFile, []byte(`{
+		"https://example.com/fixed.pdf": {"checked": "`+now.Add(-time.Minute).Format(time.RFC3339)+`", "status": 404, "problem": "404 Not Found"}
+	}`), 0666))
+
+	t.Empty(loadLinkCache(cacheFile, time.Hour, now))
+}
@@ -0,0 +1,396 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/WordOfLifeMN/online/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type podcastCmdStruct struct {
+	cobra.Command // podcast command definition
+
+	// flags for the podcast command
+	Ministry  string // which ministry to generate podcasts for, "all" or "*" for all
+	OutputDir string // directory to write output to
+	Days      int    // number of days of messages to include in the feeds, 0 for all
+
+	// internal reference
+	cat           *catalog.Catalog   // the catalog to process
+	template      *template.Template // xml templates for generating feeds
+	templateError error              // cached error from trying to load a template
+}
+
+const (
+	// name of the OPML file that lists all the podcast feeds
+	PODCAST_OPML_FILE_NAME string = "podcasts.opml"
+
+	// bit rate that ffmpeg encodes our mp3 files with by default. used to estimate the duration
+	// of an episode from the size of the audio file
+	PODCAST_AUDIO_BIT_RATE int = 128_000
+)
+
+// podcastCmd represents the podcast command
+var podcastCmd *podcastCmdStruct
+
+func init() {
+	podcastCmd = &podcastCmdStruct{
+		Command: cobra.Command{
+			Use:   "podcast [--ministry=all] [--days=0] [--output=~/.wolm/podcast]",
+			Short: "Generate the podcast feeds",
+			Long: `Generates an RSS podcast feed for each ministry.
+
+Each feed contains the public messages of the ministry that have audio, newest
+first. The feeds include iTunes and Podcasting 2.0 tags so they can be
+submitted to podcast directories. An OPML file listing all of the feeds is
+also written to the output directory.`,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return podcastCmd.podcast()
+			},
+		},
+	}
+
+	rootCmd.AddCommand(&podcastCmd.Command)
+
+	podcastCmd.Flags().StringVar(&podcastCmd.Ministry, "ministry", "all", "Ministry to generate podcast for: all (default), wol, core (with sub-ministry), tbo, ask-pastor, or faith-freedom")
+	podcastCmd.Flags().StringVarP(&podcastCmd.OutputDir, "output", "o", "~/.wolm/podcast", "Output directory. Defaults to $HOME/.wolm/podcast")
+	podcastCmd.Flags().IntVar(&podcastCmd.Days, "days", 0, "Number of days of messages to include in the feeds. Defaults to 0 (all messages)")
+
+	podcastCmd.Flags().String("podcast-url", "https://s3.us-west-2.amazonaws.com/wordoflife.mn.podcast", "Public URL that the podcast feeds are published to")
+	viper.BindPFlag("podcast-url", podcastCmd.Flags().Lookup("podcast-url"))
+}
+
+func (cmd *podcastCmdStruct) podcast() error {
+	initLogging()
+
+	outputDir := util.NormalizePath(cmd.OutputDir)
+	log.Printf("Generating podcasts in directory %s", outputDir)
+
+	// find ministries to generate podcasts for
+	ministries, err := parseMinistriesFlag(cmd.Ministry)
+	if err != nil {
+		return err
+	}
+
+	// get the catalog
+	cmd.cat, err = readOnlineContentFromInput(cmd.Context())
+	if err != nil {
+		return err
+	}
+	if !cmd.cat.IsValid(false) {
+		return fmt.Errorf("catalog is not valid. run 'check' on it")
+	}
+	if err := cmd.cat.Initialize(); err != nil {
+		return err
+	}
+
+	// set up the output directory
+	if err := os.MkdirAll(outputDir, os.FileMode(0777)); err != nil {
+		return fmt.Errorf("cannot create the output directory %s: %w", outputDir, err)
+	}
+
+	// generate one feed per ministry
+	log.Printf("Generating podcast feeds")
+	var feeds []*podcastFeed
+	for _, ministry := range ministries {
+		feed := cmd.newMinistryPodcastFeed(ministry)
+		if len(feed.Episodes) == 0 {
+			log.Printf("  Ministry %s (no episodes found)", ministry.Description())
+			continue
+		}
+
+		filePath := filepath.Join(outputDir, GetPodcastFileNameForMinistry(ministry))
+		log.Printf("  Ministry %s (%d episodes) --> %s", ministry.Description(), len(feed.Episodes), filePath)
+		if err := cmd.createPodcastFile(filePath, "podcast.xml", feed); err != nil {
+			return err
+		}
+		feeds = append(feeds, feed)
+	}
+
+	// generate the list of all the feeds
+	filePath := filepath.Join(outputDir, PODCAST_OPML_FILE_NAME)
+	log.Printf("Generating podcast list --> %s", filePath)
+	opml := struct {
+		Title string
+		Date  time.Time
+		Feeds []*podcastFeed
+	}{
+		Title: "Word of Life Ministries Podcasts",
+		Date:  time.Now(),
+		Feeds: feeds,
+	}
+	return cmd.createPodcastFile(filePath, "podcast.opml", opml)
+}
+
+// loadTemplates loads the templates for podcast feeds. If this returns an error, then the
+// templates could not be loaded and subsequent calls to the print methods will fail
+func (cmd *podcastCmdStruct) loadTemplates() error {
+	if cmd.template != nil {
+		// already loaded the templates
+		return nil
+	}
+	if cmd.templateError != nil {
+		// we already tried and cached an error so don't try again
+		return cmd.templateError
+	}
+
+	cmd.template, cmd.templateError = loadPodcastTemplates()
+	return cmd.templateError
+}
+
+// createPodcastFile renders the named podcast template with the data into a file
+func (cmd *podcastCmdStruct) createPodcastFile(filePath string, templateName string, data any) error {
+	if err := cmd.loadTemplates(); err != nil {
+		return fmt.Errorf("unable to load templates for generating the podcast: %w", err)
+	}
+	return createPodcastFile(cmd.template, filePath, templateName, data)
+}
+
+// newMinistryPodcastFeed builds the feed for a ministry from all the public messages in the
+// ministry that have audio
+func (cmd *podcastCmdStruct) newMinistryPodcastFeed(ministry catalog.Ministry) *podcastFeed {
+	now := time.Now()
+	cutoff := now.AddDate(0, 0, -1*cmd.Days)
+
+	// get the messages for this ministry
+	messages := []catalog.CatalogMessage{}
+	for index := range cmd.cat.Messages {
+		msg := &cmd.cat.Messages[index]
+		if !msg.IsListedIn(ministry) ||
+			msg.Visibility != catalog.Public ||
+			!msg.HasAudio() ||
+			msg.Date.After(now) ||
+			(cmd.Days > 0 && msg.Date.Before(cutoff)) {
+			continue
+		}
+		messages = append(messages, msg.Copy())
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].Date.Time.After(messages[j].Date.Time)
+	})
+
+	return &podcastFeed{
+		Title:         "Word of Life Ministries: " + ministry.Description(),
+		Description:   fmt.Sprintf("Messages and teaching from %s at Word of Life Ministries", ministry.Description()),
+		Link:          getCatalogURL(GetCatalogFileNameForSeriList(ministry, catalog.Public, CHRONOLOGICAL_DESC)),
+		FeedURL:       getPodcastURL(GetPodcastFileNameForMinistry(ministry)),
+		ImageURL:      getPodcastImageURL(ministry, ""),
+		CopyrightYear: now.Year(),
+		Episodes:      newPodcastEpisodes(messages),
+	}
+}
+
+// newSeriPodcastFeed builds the feed for a single seri. The seri should already be filtered to
+// a view, and the feed contains all the messages of the seri that have audio, in the order of
+// the seri. Returns nil if the seri has no audio
+func newSeriPodcastFeed(seri *catalog.CatalogSeri) *podcastFeed {
+	if !seri.HasAudio() {
+		return nil
+	}
+
+	messages := []catalog.CatalogMessage{}
+	for index := range seri.Messages {
+		if seri.Messages[index].HasAudio() {
+			messages = append(messages, seri.Messages[index])
+		}
+	}
+
+	description := seri.Description
+	if description == "" {
+		description = "Messages from the series " + seri.Name
+	}
+
+	feed := &podcastFeed{
+		Title:         seri.Name,
+		Description:   description,
+		Link:          getCatalogURL(seri.GetCatalogFileName(seri.View)),
+		FeedURL:       getCatalogURL(seri.GetPodcastFileName(seri.View)),
+		ImageURL:      getPodcastImageURL(seri.GetMinistry(), seri.Thumbnail),
+		CopyrightYear: seri.StopDate.Year(),
+		Serial:        true,
+		Blocked:       seri.View != catalog.Public,
+		Episodes:      newPodcastEpisodes(messages),
+	}
+	if feed.CopyrightYear <= 1 {
+		feed.CopyrightYear = time.Now().Year()
+	}
+
+	// number the episodes by their position in the series
+	for index := range feed.Episodes {
+		ref := feed.Episodes[index].FindSeriesReference(seri.Name)
+		if ref != nil && ref.Index > 0 {
+			feed.Episodes[index].Number = ref.Index
+		}
+	}
+
+	return feed
+}
+
+// GetPodcastFileNameForMinistry generates the name of the file that contains the podcast feed
+// for a ministry
+func GetPodcastFileNameForMinistry(ministry catalog.Ministry) string {
+	return "podcast." + string(ministry) + ".rss.xml"
+}
+
+// getPodcastURL gets the public URL of a podcast file that is published to the podcast site
+func getPodcastURL(fileName string) string {
+	return strings.TrimSuffix(viper.GetString("podcast-url"), "/") + "/" + fileName
+}
+
+// getCatalogURL gets the public URL of a file that is published to the catalog site
+func getCatalogURL(fileName string) string {
+	return strings.TrimSuffix(viper.GetString("catalog-url"), "/") + "/" + fileName
+}
+
+// getPodcastImageURL gets the cover art for a podcast. If the thumbnail is a full URL then that
+// is used, otherwise the default thumbnail of the ministry is used
+func getPodcastImageURL(ministry catalog.Ministry, thumbnail string) string {
+	if strings.Contains(thumbnail, "://") {
+		return thumbnail
+	}
+	return getCatalogURL(ministry.Thumbnail())
+}
+
+// ----------------------------------------------------------------------------
+// | Feed rendering
+// ----------------------------------------------------------------------------
+
+// podcastFeed contains everything needed to render one podcast feed
+type podcastFeed struct {
+	Title         string           // name of the podcast
+	Description   string           // description of the podcast
+	Link          string           // web page for the podcast
+	FeedURL       string           // public URL of the feed itself
+	ImageURL      string           // cover art for the podcast
+	CopyrightYear int              // year of the copyright notice
+	Serial        bool             // true if episodes should be listed in order, not newest first
+	Blocked       bool             // true if the feed should not be listed in podcast directories
+	Episodes      []podcastEpisode // episodes in the order they should be listed
+}
+
+// podcastEpisode describes one item in a podcast feed. It is a message with the additional
+// facts that a podcast needs but aren't part of the catalog
+type podcastEpisode struct {
+	*catalog.CatalogMessage
+
+	Number        int    // episode number within a serial podcast, 0 if not numbered
+	PubDate       string // date the episode was published, RFC-1123 format
+	AudioSize     int    // size of the audio file in bytes, 0 if unknown
+	AudioType     string // content type of the audio file
+	Duration      string // duration of the audio, "h:mm:ss", or "" if unknown
+	ImageURL      string // cover art for the episode, or "" to use the podcast image
+	TranscriptURL string // URL of the .vtt transcript, or "" if there is none
+}
+
+// cache of audio file sizes by URL, so messages that appear in several feeds are only looked up
+// once
+var podcastAudioSizeCache = map[string]int{}
+
+// newPodcastEpisodes creates episodes for all the messages, in the same order as the messages.
+// Note that this makes network calls to get the size of the audio and to find transcripts, unless
+// they are in the media facts of the messages
+func newPodcastEpisodes(messages []catalog.CatalogMessage) []podcastEpisode {
+	episodes := make([]podcastEpisode, 0, len(messages))
+	for index := range messages {
+		msg := &messages[index]
+		episode := podcastEpisode{
+			CatalogMessage: msg,
+			PubDate:        getPodcastPubDate(msg.Date),
+		}
+		if size, ok := podcastAudioSizeCache[msg.Audio.URL]; ok {
+			episode.AudioSize = size
+		} else {
+			// the length of an enclosure is 0 when it's unknown, since -1 isn't valid
+			episode.AudioSize = max(msg.GetAudioSize(), 0)
+			podcastAudioSizeCache[msg.Audio.URL] = episode.AudioSize
+		}
+		episode.AudioType = "audio/mpeg"
+		episode.Duration = getPodcastDuration(episode.AudioSize)
+		if msg.Media != nil {
+			if msg.Media.AudioType != "" {
+				episode.AudioType = msg.Media.AudioType
+			}
+			if msg.Media.AudioSeconds > 0 {
+				episode.Duration = formatPodcastDuration(msg.Media.AudioSeconds)
+			}
+		}
+		if msg.Thumb != nil && strings.Contains(msg.Thumb.URL, "://") {
+			episode.ImageURL = msg.Thumb.URL
+		}
+		if msg.HasTranscript() {
+			episode.TranscriptURL = msg.GetTranscriptURL(".vtt")
+		}
+		episodes = append(episodes, episode)
+	}
+	return episodes
+}
+
+// getPodcastPubDate gets the publication date of a message. Messages are given at the Sunday
+// morning service, so the time is 10am central time
+func getPodcastPubDate(date catalog.DateOnly) string {
+	loc, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		loc = time.UTC
+	}
+	t := time.Date(date.Year(), date.Month(), date.Day(), 10, 0, 0, 0, loc)
+	return t.Format(time.RFC1123Z)
+}
+
+// getPodcastDuration estimates the duration of an episode from the size of the audio file.
+// Returns "" if the size is unknown
+func getPodcastDuration(audioSize int) string {
+	if audioSize <= 0 {
+		return ""
+	}
+	return formatPodcastDuration(audioSize * 8 / PODCAST_AUDIO_BIT_RATE)
+}
+
+// formatPodcastDuration formats a duration in seconds as "h:mm:ss"
+func formatPodcastDuration(seconds int) string {
+	return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// loadPodcastTemplates loads the podcast.* templates from the template directory
+func loadPodcastTemplates() (*template.Template, error) {
+	log.Printf("Loading podcast templates")
+	templateDir, err := getTemplateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	t := template.New("podcast")
+	t.Funcs(template.FuncMap{
+		"xml": func(s string) (string, error) {
+			var b strings.Builder
+			err := xml.EscapeText(&b, []byte(s))
+			return b.String(), err
+		},
+	})
+
+	log.Printf("  Templates %s", filepath.Join(templateDir, "podcast.*"))
+	return t.ParseGlob(filepath.Join(templateDir, "podcast.*"))
+}
+
+// createPodcastFile renders the named podcast template with the data into a file
+func createPodcastFile(t *template.Template, filePath string, templateName string, data any) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("cannot create output file %s: %w", filePath, err)
+	}
+	defer f.Close()
+
+	if err := t.ExecuteTemplate(f, templateName, data); err != nil {
+		return fmt.Errorf("cannot print podcast to %s: %w", filePath, err)
+	}
+	return nil
+}
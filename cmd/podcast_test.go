@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/stretchr/testify/suite"
+)
+
+// Runs the test suite as a test
+func TestPodcastCmdTestSuite(t *testing.T) {
+	suite.Run(t, new(PodcastCmdTestSuite))
+}
+
+type PodcastCmdTestSuite struct {
+	suite.Suite
+}
+
+// +---------------------------------------------------------------------------
+// | Episode details
+// +---------------------------------------------------------------------------
+
+func (t *PodcastCmdTestSuite) TestPodcastDuration() {
+	t.Equal("", getPodcastDuration(-1))
+	t.Equal("", getPodcastDuration(0))
+	t.Equal("0:00:01", getPodcastDuration(16_000))
+	t.Equal("0:01:00", getPodcastDuration(60*16_000))
+	t.Equal("1:02:03", getPodcastDuration((3600+2*60+3)*16_000))
+}
+
+func (t *PodcastCmdTestSuite) TestPodcastPubDate() {
+	t.Equal("Sun, 12 Sep 2021 10:00:00 -0500", getPodcastPubDate(catalog.MustParseDateOnly("2021-09-12")))
+	t.Equal("Sun, 12 Dec 2021 10:00:00 -0600", getPodcastPubDate(catalog.MustParseDateOnly("2021-12-12")))
+}
+
+func (t *PodcastCmdTestSuite) TestPodcastFileName() {
+	t.Equal("podcast.wol.rss.xml", GetPodcastFileNameForMinistry(catalog.WordOfLife))
+	t.Equal("podcast.core.rss.xml", GetPodcastFileNameForMinistry(catalog.CenterOfRelationshipExperience))
+}
+
+// +---------------------------------------------------------------------------
+// | Feed Output
+// +---------------------------------------------------------------------------
+
+func (t *PodcastCmdTestSuite) TestPodcastTemplate() {
+	tmpl, err := loadPodcastTemplates()
+	t.Require().NoError(err)
+
+	msg := catalog.CatalogMessage{
+		Name:        "MESSAGE & MORE",
+		Description: "DESCRIPTION",
+		Date:        catalog.MustParseDateOnly("2021-09-12"),
+		Ministry:    catalog.WordOfLife,
+		Speakers:    []string{"Vern Peltz"},
+		Audio:       &catalog.OnlineResource{URL: "https://example.com/audio.mp3"},
+	}
+	feed := podcastFeed{
+		Title:         "TITLE",
+		Description:   "FEED-DESCRIPTION",
+		Link:          "https://example.com/catalog.html",
+		FeedURL:       "https://example.com/podcast.rss.xml",
+		ImageURL:      "https://example.com/image.png",
+		CopyrightYear: 2021,
+		Episodes: []podcastEpisode{
+			{
+				CatalogMessage: &msg,
+				PubDate:        getPodcastPubDate(msg.Date),
+				AudioSize:      60 * 16_000,
+				AudioType:      "audio/mpeg",
+				Duration:       getPodcastDuration(60 * 16_000),
+				TranscriptURL:  "https://example.com/audio.vtt",
+			},
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	t.Require().NoError(tmpl.ExecuteTemplate(buf, "podcast.xml", &feed))
+	t.T().Logf("Results of printing:\n%s", buf.String())
+
+	// must be well-formed xml
+	t.NoError(xml.Unmarshal(buf.Bytes(), new(struct{})))
+
+	t.Contains(buf.String(), "<title>TITLE</title>")
+	t.Contains(buf.String(), "<title>MESSAGE &amp; MORE</title>")
+	t.Contains(buf.String(), `<enclosure url="https://example.com/audio.mp3" length="960000" type="audio/mpeg" />`)
+	t.Contains(buf.String(), "<itunes:author>Vern Peltz</itunes:author>")
+	t.Contains(buf.String(), "<itunes:duration>0:01:00</itunes:duration>")
+	t.Contains(buf.String(), `<podcast:transcript url="https://example.com/audio.vtt"`)
+	t.NotContains(buf.String(), `<itunes:image href=""`)
+}
+
+func (t *PodcastCmdTestSuite) TestPodcastListTemplate() {
+	tmpl, err := loadPodcastTemplates()
+	t.Require().NoError(err)
+
+	data := struct {
+		Title string
+		Date  time.Time
+		Feeds []*podcastFeed
+	}{
+		Title: "TITLE",
+		Date:  time.Now(),
+		Feeds: []*podcastFeed{
+			{Title: "FEED-A", FeedURL: "https://example.com/a.rss.xml"},
+			{Title: "FEED-B", FeedURL: "https://example.com/b.rss.xml"},
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	t.Require().NoError(tmpl.ExecuteTemplate(buf, "podcast.opml", data))
+	t.T().Logf("Results of printing:\n%s", buf.String())
+
+	t.NoError(xml.Unmarshal(buf.Bytes(), new(struct{})))
+	t.Contains(buf.String(), `xmlUrl="https://example.com/a.rss.xml"`)
+	t.Contains(buf.String(), `xmlUrl="https://example.com/b.rss.xml"`)
+}
+
+func (t *PodcastCmdTestSuite) TestSeriPodcastFeed_NoAudio() {
+	seri := catalog.CatalogSeri{
+		Name: "SERIES",
+		Messages: []catalog.CatalogMessage{
+			{Name: "MESSAGE-A", Ministry: catalog.WordOfLife},
+		},
+	}
+	t.Nil(newSeriPodcastFeed(&seri))
+}
+
+func (t *PodcastCmdTestSuite) TestSeriPodcastFeed() {
+	// given a partner seri with messages whose audio size is already known
+	seri := catalog.CatalogSeri{
+		Name:       "SERIES",
+		Visibility: catalog.Partner,
+		View:       catalog.Partner,
+		Messages: []catalog.CatalogMessage{
+			{
+				Name:     "MESSAGE-B",
+				Date:     catalog.MustParseDateOnly("2021-09-12"),
+				Ministry: catalog.WordOfLife,
+				Series:   []catalog.SeriesReference{{Name: "SERIES", Index: 2}},
+				Audio:    &catalog.OnlineResource{URL: "https://example.com/b.mp3"},
+			},
+			{
+				Name:     "MESSAGE-A",
+				Date:     catalog.MustParseDateOnly("2021-09-19"),
+				Ministry: catalog.WordOfLife,
+				Series:   []catalog.SeriesReference{{Name: "SERIES", Index: 1}},
+				Audio:    &catalog.OnlineResource{URL: "https://example.com/a.mp3"},
+			},
+			{
+				Name:     "MESSAGE-C",
+				Date:     catalog.MustParseDateOnly("2021-09-26"),
+				Ministry: catalog.WordOfLife,
+				Series:   []catalog.SeriesReference{{Name: "SERIES", Index: 3}},
+			},
+		},
+	}
+	seri.Normalize()
+	previous := podcastAudioSizeCache
+	podcastAudioSizeCache = map[string]int{"https://example.com/a.mp3": 16_000, "https://example.com/b.mp3": 16_000}
+	t.T().Cleanup(func() { podcastAudioSizeCache = previous })
+
+	// when
+	feed := newSeriPodcastFeed(&seri)
+
+	// then episodes follow the series order and skip messages without audio
+	t.Require().NotNil(feed)
+	t.True(feed.Serial)
+	t.True(feed.Blocked)
+	t.Contains(feed.FeedURL, seri.GetPodcastFileName(catalog.Partner))
+	t.Require().Len(feed.Episodes, 2)
+	t.Equal("MESSAGE-A", feed.Episodes[0].Name)
+	t.Equal(1, feed.Episodes[0].Number)
+	t.Equal("MESSAGE-B", feed.Episodes[1].Name)
+	t.Equal(2, feed.Episodes[1].Number)
+}
+
+func (t *PodcastCmdTestSuite) TestPodcastEpisodes_MediaFacts() {
+	// given a message whose media facts were found by dump --enrich
+	messages := []catalog.CatalogMessage{
+		{
+			Name:     "MESSAGE",
+			Date:     catalog.MustParseDateOnly("2021-09-12"),
+			Ministry: catalog.WordOfLife,
+			Audio:    &catalog.OnlineResource{URL: "https://example.com/enriched.mp3"},
+			Media:    &catalog.MediaFacts{AudioSize: 1_000_000, AudioType: "audio/mp3", AudioSeconds: 3725},
+		},
+	}
+
+	// when
+	episodes := newPodcastEpisodes(messages)
+
+	// then the facts are used instead of estimates
+	t.Require().Len(episodes, 1)
+	t.Equal(1_000_000, episodes[0].AudioSize)
+	t.Equal("audio/mp3", episodes[0].AudioType)
+	t.Equal("1:02:05", episodes[0].Duration)
+}
+
+func (t *PodcastCmdTestSuite) TestPodcastEpisodes_UnknownSize() {
+	// given audio whose size can't be found
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	catalog.SetTranscriptDir(t.T().TempDir())
+	defer catalog.SetTranscriptDir("")
+	messages := []catalog.CatalogMessage{
+		{
+			Name:     "MESSAGE",
+			Date:     catalog.MustParseDateOnly("2021-09-12"),
+			Ministry: catalog.WordOfLife,
+			Audio:    &catalog.OnlineResource{URL: server.URL + "/missing.mp3"},
+		},
+	}
+
+	// when
+	episodes := newPodcastEpisodes(messages)
+
+	// then the length of the enclosure is unknown, which is 0
+	t.Require().Len(episodes, 1)
+	t.Equal(0, episodes[0].AudioSize)
+	t.Equal("", episodes[0].Duration)
+}
@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/WordOfLifeMN/online/storage"
+	"github.com/WordOfLifeMN/online/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type publishCmdStruct struct {
+	cobra.Command // publish command definition
+
+	// flags for the publish command
+	OutputDir string // directory the catalog is generated in before it is uploaded
+	Days      int    // number of days to include in recent messages
+	Clean     bool   // generate all the files again instead of only what changed
+	DryRun    bool   // only print what would be uploaded and deleted
+	Delete    bool   // delete the files in the target that are no longer generated
+}
+
+// publishResult is what was done to the files in the target when publishing
+type publishResult struct {
+	Uploaded  []string // keys of the files that were new or changed
+	Deleted   []string // keys of the files that were no longer generated
+	Unchanged int      // number of files that were already up to date
+}
+
+// publishCmd represents the publish command
+var publishCmd *publishCmdStruct
+
+func init() {
+	publishCmd = &publishCmdStruct{
+		Command: cobra.Command{
+			Use:   "publish [--target=s3://wordoflife.mn.catalog] [--output=~/.wolm/online] [--dry-run]",
+			Short: "Check, generate, and upload the online catalog",
+			Long: `Checks the catalog, generates it for all ministries and views, and uploads
+it to the storage where it is served from.
+
+Nothing is generated or uploaded if the catalog isn't valid (the same as
+'check'). The catalog is generated in --output first, then only the files
+whose contents are different from what is in the target are uploaded, and
+files in the target that are no longer generated are deleted.
+
+The target is an S3 bucket with an optional prefix (s3://bucket/prefix), or a
+directory. Use --s3-endpoint to upload to an S3-compatible service instead of
+AWS. The AWS credentials are read from the environment or ~/.aws/credentials
+the same as the AWS CLI.`,
+			Args: cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return publishCmd.publish()
+			},
+		},
+	}
+
+	rootCmd.AddCommand(&publishCmd.Command)
+
+	publishCmd.Flags().StringVarP(&publishCmd.OutputDir, "output", "o", "~/.wolm/online", "Directory to generate the catalog in before uploading it. Defaults to $HOME/.wolm/online")
+	publishCmd.Flags().IntVar(&publishCmd.Days, "days", 60, "Number of days to include in the recent message pages. Defaults to 60")
+	publishCmd.Flags().BoolVar(&publishCmd.Clean, "clean", false, "Delete everything in the output directory and generate all the files again")
+	publishCmd.Flags().BoolVar(&publishCmd.DryRun, "dry-run", false, "Only print the files that would be uploaded or deleted")
+	publishCmd.Flags().BoolVar(&publishCmd.Delete, "delete", true, "Delete files in the target that are no longer in the catalog")
+
+	publishCmd.Flags().String("target", "s3://wordoflife.mn.catalog", "Where to publish the catalog: s3://bucket/prefix or a directory")
+	viper.BindPFlag("publish-target", publishCmd.Flags().Lookup("target"))
+}
+
+func (cmd *publishCmdStruct) publish() error {
+	initLogging()
+
+	ministries, err := parseMinistriesFlag("all")
+	if err != nil {
+		return err
+	}
+	views, err := parseViewsFlag("all")
+	if err != nil {
+		return err
+	}
+	schedule, err := newServiceSchedule()
+	if err != nil {
+		return err
+	}
+	target, err := openStorage(viper.GetString("publish-target"))
+	if err != nil {
+		return err
+	}
+
+	// get the catalog, and don't publish anything that's broken
+	cat, err := readOnlineContentFromInput(cmd.Context())
+	if err != nil {
+		return err
+	}
+	if !cat.IsValid(true) {
+		return fmt.Errorf("the online catalog was not valid (see errors above), so nothing was published")
+	}
+	if err := cat.Initialize(); err != nil {
+		return err
+	}
+
+	// generate it
+	generator := &catalogCmdStruct{
+		OutputDir: util.NormalizePath(cmd.OutputDir),
+		Days:      cmd.Days,
+		Clean:     cmd.Clean,
+		cat:       cat,
+	}
+	if err := generator.writeCatalog(ministries, views, schedule); err != nil {
+		return err
+	}
+
+	// upload it
+	log.Printf("Publishing catalog to %s", viper.GetString("publish-target"))
+	result, err := syncDirToStorage(cmd.Context(), generator.OutputDir, target, cmd.DryRun, cmd.Delete)
+	if err != nil {
+		return err
+	}
+
+	verb := "Published"
+	if cmd.DryRun {
+		verb = "Dry run of publishing"
+		for _, key := range result.Uploaded {
+			fmt.Printf("  upload %s\n", key)
+		}
+		for _, key := range result.Deleted {
+			fmt.Printf("  delete %s\n", key)
+		}
+	}
+	fmt.Printf("%s catalog to %s: %d files uploaded, %d deleted, %d unchanged\n",
+		verb, viper.GetString("publish-target"), len(result.Uploaded), len(result.Deleted), result.Unchanged)
+
+	return nil
+}
+
+// syncDirToStorage makes the files in the target the same as the files in a catalog directory.
+// Files are only uploaded if their contents are different, and files that aren't in the
+// directory are deleted if deleteStale is set. Nothing is changed on a dry run
+func syncDirToStorage(ctx context.Context, dir string, target storage.Storage, dryRun bool, deleteStale bool) (*publishResult, error) {
+	source, err := storage.Open(dir, storage.Options{})
+	if err != nil {
+		return nil, err
+	}
+	localObjects, err := source.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	remoteObjects, err := target.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	remoteETags := map[string]string{}
+	for _, object := range remoteObjects {
+		remoteETags[object.Key] = object.ETag
+	}
+
+	result := &publishResult{}
+	published := map[string]bool{}
+	for _, object := range localObjects {
+		if !isPublishedFile(object.Key) {
+			continue
+		}
+		published[object.Key] = true
+
+		// objects uploaded in parts don't have the MD5 as their ETag, so they're uploaded again
+		if remoteETags[object.Key] == object.ETag {
+			result.Unchanged++
+			continue
+		}
+
+		result.Uploaded = append(result.Uploaded, object.Key)
+		if dryRun {
+			continue
+		}
+		log.Printf("  Uploading %s", object.Key)
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(object.Key)))
+		if err != nil {
+			return result, fmt.Errorf("cannot read %s: %w", object.Key, err)
+		}
+		if err := target.Put(ctx, object.Key, content, getPublishOptions(object.Key)); err != nil {
+			return result, err
+		}
+	}
+
+	if !deleteStale {
+		return result, nil
+	}
+	for _, object := range remoteObjects {
+		if published[object.Key] || !isPublishedFile(object.Key) {
+			continue
+		}
+
+		result.Deleted = append(result.Deleted, object.Key)
+		if dryRun {
+			continue
+		}
+		log.Printf("  Deleting %s", object.Key)
+		if err := target.Delete(ctx, object.Key); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// isPublishedFile checks if a file in the catalog directory is part of the catalog, rather than
+// one of the files used to keep track of the directory
+func isPublishedFile(key string) bool {
+	return key != FLAG_FILE_NAME && key != MANIFEST_FILE_NAME
+}
+
+// content types of the files in the catalog, the ones that are text are UTF-8
+var publishContentTypes = map[string]string{
+	".html": "text/html; charset=utf-8",
+	".css":  "text/css; charset=utf-8",
+	".js":   "text/javascript; charset=utf-8",
+	".json": "application/json; charset=utf-8",
+	".xml":  "application/xml; charset=utf-8",
+	".opml": "text/x-opml; charset=utf-8",
+	".ics":  "text/calendar; charset=utf-8",
+	".txt":  "text/plain; charset=utf-8",
+}
+
+// getPublishOptions gets how a file in the catalog is uploaded. Pages, feeds, and calendars
+// change whenever the catalog does, so browsers only cache them for a few minutes. The styles
+// and images can be cached for a day
+func getPublishOptions(key string) storage.PutOptions {
+	ext := strings.ToLower(path.Ext(key))
+
+	contentType, ok := publishContentTypes[ext]
+	if !ok {
+		contentType = mime.TypeByExtension(ext)
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	cacheControl := "public, max-age=86400"
+	switch ext {
+	case ".html", ".json", ".xml", ".opml", ".ics", ".txt":
+		cacheControl = "public, max-age=300"
+	}
+
+	return storage.PutOptions{
+		ContentType:  contentType,
+		CacheControl: cacheControl,
+		Public:       true,
+	}
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/WordOfLifeMN/online/storage"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestPublishCmdTestSuite(t *testing.T) {
+	suite.Run(t, new(PublishCmdTestSuite))
+}
+
+type PublishCmdTestSuite struct {
+	suite.Suite
+}
+
+// writeFiles writes files with their contents into a directory
+func (t *PublishCmdTestSuite) writeFiles(dir string, files map[string]string) {
+	for name, content := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		t.Require().NoError(os.MkdirAll(filepath.Dir(filePath), 0777))
+		t.Require().NoError(os.WriteFile(filePath, []byte(content), 0666))
+	}
+}
+
+func (t *PublishCmdTestSuite) TestSyncDirToStorage() {
+	dir := t.T().TempDir()
+	localDir := filepath.Join(dir, "catalog")
+	targetDir := filepath.Join(dir, "bucket")
+	t.writeFiles(localDir, map[string]string{
+		FLAG_FILE_NAME:        "",
+		MANIFEST_FILE_NAME:    "{}",
+		"catalog.html":        "<html>new</html>",
+		"catalog.wol.v3.css":  "p {}",
+		"static/all.logo.png": "png",
+	})
+	t.writeFiles(targetDir, map[string]string{
+		"catalog.html":       "<html>old</html>",
+		"catalog.wol.v3.css": "p {}",
+		"catalog.gone.html":  "gone",
+	})
+	target, err := storage.Open(targetDir, storage.Options{})
+	t.Require().NoError(err)
+
+	// a dry run doesn't change anything
+	result, err := syncDirToStorage(context.Background(), localDir, target, true, true)
+	t.Require().NoError(err)
+	t.Equal([]string{"catalog.html", "static/all.logo.png"}, result.Uploaded)
+	t.Equal([]string{"catalog.gone.html"}, result.Deleted)
+	t.Equal(1, result.Unchanged)
+	t.FileExists(filepath.Join(targetDir, "catalog.gone.html"))
+	t.NoFileExists(filepath.Join(targetDir, "static", "all.logo.png"))
+
+	// without deleting
+	result, err = syncDirToStorage(context.Background(), localDir, target, false, false)
+	t.Require().NoError(err)
+	t.Len(result.Uploaded, 2)
+	t.Empty(result.Deleted)
+	t.FileExists(filepath.Join(targetDir, "catalog.gone.html"))
+	t.FileExists(filepath.Join(targetDir, "static", "all.logo.png"))
+
+	// only what's different is changed after that
+	result, err = syncDirToStorage(context.Background(), localDir, target, false, true)
+	t.Require().NoError(err)
+	t.Empty(result.Uploaded)
+	t.Equal([]string{"catalog.gone.html"}, result.Deleted)
+	t.Equal(3, result.Unchanged)
+
+	objects, err := target.List(context.Background(), "")
+	t.Require().NoError(err)
+	t.Equal([]storage.Object{
+		{Key: "catalog.html", Size: 16, ETag: storage.ComputeETag([]byte("<html>new</html>"))},
+		{Key: "catalog.wol.v3.css", Size: 4, ETag: storage.ComputeETag([]byte("p {}"))},
+		{Key: "static/all.logo.png", Size: 3, ETag: storage.ComputeETag([]byte("png"))},
+	}, objects)
+}
+
+func (t *PublishCmdTestSuite) TestGetPublishOptions() {
+	options := getPublishOptions("catalog.wol-public-az-seri.html")
+	t.Equal("text/html; charset=utf-8", options.ContentType)
+	t.Equal("public, max-age=300", options.CacheControl)
+	t.True(options.Public)
+
+	t.Equal("text/calendar; charset=utf-8", getPublishOptions("calendar.wol.ics").ContentType)
+	t.Equal("application/xml; charset=utf-8", getPublishOptions("sitemap.xml").ContentType)
+	t.Equal("text/css; charset=utf-8", getPublishOptions("catalog.wol.v3.css").ContentType)
+	t.Equal("public, max-age=86400", getPublishOptions("catalog.wol.v3.css").CacheControl)
+
+	options = getPublishOptions("static/all.icon_mp3.PNG")
+	t.Equal("image/png", options.ContentType)
+	t.Equal("public, max-age=86400", options.CacheControl)
+
+	t.Equal("application/octet-stream", getPublishOptions("README").ContentType)
+}
+
+func (t *PublishCmdTestSuite) TestRefuseInvalidCatalog() {
+	dir := t.T().TempDir()
+	inputFile := filepath.Join(dir, "online.cache.json")
+	t.writeFiles(dir, map[string]string{
+		"online.cache.json": `{"series": [], "messages": [{"date": "2021-09-12", "name": "Faith", "speakers": ["Vern Peltz"],
+			"ministry": "wol", "type": "message", "visibility": "public", "series": [{"name": "Missing", "index": 1}]}]}`,
+	})
+	for key, value := range map[string]string{"input": inputFile, "publish-target": filepath.Join(dir, "bucket")} {
+		previous := viper.Get(key)
+		viper.Set(key, value)
+		t.T().Cleanup(func() { viper.Set(key, previous) })
+	}
+
+	command := &publishCmdStruct{OutputDir: filepath.Join(dir, "catalog"), Days: 60}
+	err := command.publish()
+	if t.Error(err) {
+		t.Contains(err.Error(), "nothing was published")
+	}
+	t.NoDirExists(filepath.Join(dir, "catalog"))
+	t.NoDirExists(filepath.Join(dir, "bucket"))
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/WordOfLifeMN/online/util"
+	"github.com/spf13/cobra"
+)
+
+type queryCmdStruct struct {
+	cobra.Command // query command definition
+
+	// flags for the query command
+	Format     string   // format of the results: table, csv, or json
+	Columns    []string // fields to print in a table or CSV, nil for the default ones
+	StandAlone bool     // also query the series created for stand-alone messages
+	Xscripts   string   // directory with a local copy of the transcripts, "" to download them
+}
+
+// queryCmd represents the query command
+var queryCmd *queryCmdStruct
+
+// fields printed by default for each target of a query
+var defaultQueryColumns = map[catalog.QueryTarget][]string{
+	catalog.QueryMessages: {"date", "name", "speaker", "ministry", "type", "visibility", "series"},
+	catalog.QuerySeries:   {"id", "name", "start-date", "end-date", "ministry", "visibility"},
+}
+
+func init() {
+	queryCmd = &queryCmdStruct{
+		Command: cobra.Command{
+			Use:   "query messages|series [TERM...] [--format=table|csv|json]",
+			Short: "Find the messages or series that match a filter",
+			Long: `Lists the messages or series of the catalog that match all the terms.
+
+Each argument is one term. A term compares a field to one or more values
+(separated by commas, any of which can match) with =, !=, ~ (contains), or for
+dates <, <=, >, or >=. Text is compared without regard to case. Dates can be a
+year, a month, or a day, and are compared only as far as the value goes, so
+date=2019 is any day in 2019. A term can also test whether something is there,
+with has:PROPERTY or no:PROPERTY.
+
+Message fields:      ` + strings.Join(catalog.GetQueryFields(catalog.QueryMessages), ", ") + `
+Message properties:  ` + strings.Join(catalog.GetQueryProperties(catalog.QueryMessages), ", ") + `
+Series fields:       ` + strings.Join(catalog.GetQueryFields(catalog.QuerySeries), ", ") + `
+Series properties:   ` + strings.Join(catalog.GetQueryProperties(catalog.QuerySeries), ", ") + `
+
+A ministry matches everything listed in its catalog. Series created for
+stand-alone messages are only queried with --stand-alone. Testing for
+transcripts downloads the list of transcripts, unless --transcripts is given.`,
+			Example: `query messages "speaker=Mary Peltz" visibility=public date=2019 no:transcript
+query series ministry=core no:thumbnail --format csv`,
+			Args: cobra.MinimumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return queryCmd.query(args)
+			},
+		},
+	}
+
+	rootCmd.AddCommand(&queryCmd.Command)
+
+	queryCmd.Flags().StringVar(&queryCmd.Format, "format", "table", "Format of the results: table (default), csv, or json")
+	queryCmd.Flags().StringSliceVar(&queryCmd.Columns, "columns", nil, "Fields to print in a table or CSV, separated by commas")
+	queryCmd.Flags().BoolVar(&queryCmd.StandAlone, "stand-alone", false, "Also query the series created for stand-alone messages")
+	queryCmd.Flags().StringVar(&queryCmd.Xscripts, "transcripts", "", "Directory with a local copy of the audio bucket to find transcripts in instead of downloading the list")
+}
+
+func (cmd *queryCmdStruct) query(args []string) error {
+	initLogging()
+
+	target := catalog.QueryTarget(strings.ToLower(args[0]))
+	if target == "message" {
+		target = catalog.QueryMessages
+	}
+	query, err := catalog.ParseQuery(target, args[1:])
+	if err != nil {
+		return err
+	}
+
+	columns := cmd.Columns
+	if len(columns) == 0 {
+		columns = defaultQueryColumns[target]
+	}
+	for _, column := range columns {
+		if !slices.Contains(catalog.GetQueryFields(target), column) {
+			return fmt.Errorf("%s don't have the field '%s'. use one of: %s", target, column,
+				strings.Join(catalog.GetQueryFields(target), ", "))
+		}
+	}
+
+	cat, err := readOnlineContentFromInput(cmd.Context())
+	if err != nil {
+		return err
+	}
+	seriesCount := len(cat.Series)
+	if err := cat.Initialize(); err != nil {
+		return err
+	}
+	if !cmd.StandAlone {
+		// the series of stand-alone messages are added to the end of the list
+		cat.Series = cat.Series[:seriesCount]
+	}
+	if cmd.Xscripts != "" {
+		catalog.SetTranscriptDir(util.NormalizePath(cmd.Xscripts))
+	}
+
+	var results any
+	var rows [][]string
+	total := len(cat.Messages)
+	switch target {
+	case catalog.QueryMessages:
+		messages := query.FilterMessages(cat.Messages)
+		results = messages
+		for index := range messages {
+			rows = append(rows, getQueryRow(columns, func(field string) []string {
+				return catalog.GetMessageField(&messages[index], field)
+			}))
+		}
+	case catalog.QuerySeries:
+		total = len(cat.Series)
+		series := query.FilterSeries(cat.Series)
+		results = series
+		for index := range series {
+			rows = append(rows, getQueryRow(columns, func(field string) []string {
+				return catalog.GetSeriField(&series[index], field)
+			}))
+		}
+	}
+
+	switch strings.ToLower(cmd.Format) {
+	case "table", "":
+		err := printQueryTable(columns, rows, os.Stdout)
+		fmt.Printf("%d of %d %s matched\n", len(rows), total, target)
+		return err
+	case "csv":
+		return printQueryCSV(columns, rows, os.Stdout)
+	case "json":
+		return printQueryJSON(results, os.Stdout)
+	}
+	return fmt.Errorf("unknown format '%s'. use table, csv, or json", cmd.Format)
+}
+
+// ----------------------------------------------------------------------------
+// | Results
+// ----------------------------------------------------------------------------
+
+// getQueryRow gets the values of the columns of a message or series. A field with several values
+// (like speakers) is printed as a list separated by semicolons, like they are in the spreadsheet
+func getQueryRow(columns []string, getField func(field string) []string) []string {
+	row := make([]string, 0, len(columns))
+	for _, column := range columns {
+		row = append(row, strings.Join(getField(column), "; "))
+	}
+	return row
+}
+
+// printQueryTable prints the results as a table
+func printQueryTable(columns []string, rows [][]string, output io.Writer) error {
+	table := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, strings.ToUpper(strings.Join(columns, "\t")))
+	for _, row := range rows {
+		// a line break in a value would break the table
+		fmt.Fprintln(table, strings.NewReplacer("\r", " ", "\n", " ").Replace(strings.Join(row, "\t")))
+	}
+	return table.Flush()
+}
+
+// printQueryCSV prints the results as CSV with a header row
+func printQueryCSV(columns []string, rows [][]string, output io.Writer) error {
+	writer := csv.NewWriter(output)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// printQueryJSON prints the messages or series that matched as JSON, the same way they are in a
+// dump of the catalog
+func printQueryJSON(results any, output io.Writer) error {
+	bytes, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(output, string(bytes))
+	return nil
+}
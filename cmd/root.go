@@ -12,6 +12,7 @@ import (
 
 	"github.com/WordOfLifeMN/online/catalog"
 	"github.com/WordOfLifeMN/online/gclient"
+	"github.com/WordOfLifeMN/online/storage"
 	"github.com/WordOfLifeMN/online/util"
 	"github.com/spf13/cobra"
 
@@ -48,11 +49,29 @@ func init() {
 	rootCmd.PersistentFlags().String("sheet-id", "", "ID of Google spreadsheet that contains the series and messages")
 	viper.BindPFlag("sheet-id", rootCmd.PersistentFlags().Lookup("sheet-id"))
 
-	rootCmd.PersistentFlags().StringP("input", "i", "", "Path to JSON file to read catalog from (overrides --sheet-id)")
+	rootCmd.PersistentFlags().StringP("input", "i", "", "Path to a JSON or XLSX file, or a directory of CSV files, to read catalog from (overrides --sheet-id)")
 	viper.BindPFlag("input", rootCmd.PersistentFlags().Lookup("input"))
 
 	rootCmd.PersistentFlags().String("openai-key", "", "OpenAI API key")
 	viper.BindPFlag("openai-key", rootCmd.PersistentFlags().Lookup("openai-key"))
+
+	rootCmd.PersistentFlags().String("catalog-url", "https://s3.us-west-2.amazonaws.com/wordoflife.mn.catalog", "Public URL that the online catalog is published to")
+	viper.BindPFlag("catalog-url", rootCmd.PersistentFlags().Lookup("catalog-url"))
+
+	rootCmd.PersistentFlags().String("speakers-file", "", "Path to YAML file with the speaker registry (defaults to the built-in list of speakers)")
+	viper.BindPFlag("speakers-file", rootCmd.PersistentFlags().Lookup("speakers-file"))
+
+	rootCmd.PersistentFlags().String("ministries-file", "", "Path to YAML file with the ministry registry (defaults to the built-in list of ministries)")
+	viper.BindPFlag("ministries-file", rootCmd.PersistentFlags().Lookup("ministries-file"))
+
+	rootCmd.PersistentFlags().String("audio-storage", "s3://wordoflife.mn.audio", "Where the audio files and their transcripts are stored: s3://bucket/prefix or a directory")
+	viper.BindPFlag("audio-storage", rootCmd.PersistentFlags().Lookup("audio-storage"))
+
+	rootCmd.PersistentFlags().String("s3-endpoint", "", "URL of an S3-compatible service to use instead of AWS S3")
+	viper.BindPFlag("s3-endpoint", rootCmd.PersistentFlags().Lookup("s3-endpoint"))
+
+	rootCmd.PersistentFlags().String("s3-region", "us-west-2", "AWS region of the S3 buckets")
+	viper.BindPFlag("s3-region", rootCmd.PersistentFlags().Lookup("s3-region"))
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -80,6 +99,42 @@ func initConfig() {
 		err = fmt.Errorf("cannot read configuration file: %w", err)
 		fmt.Fprintf(os.Stderr, "%s", err.Error())
 	}
+
+	cobra.CheckErr(initMinistries())
+	cobra.CheckErr(initSpeakers())
+	catalog.SetAudioStorage(getAudioStorage)
+}
+
+// initMinistries loads the ministry registry from the configured file. If no file is
+// configured, then the built-in registry is used
+func initMinistries() error {
+	ministriesFile := viper.GetString("ministries-file")
+	if ministriesFile == "" {
+		return nil
+	}
+
+	registry, err := catalog.NewMinistryRegistryFromFile(util.NormalizePath(ministriesFile))
+	if err != nil {
+		return err
+	}
+	catalog.SetMinistryRegistry(registry)
+	return nil
+}
+
+// initSpeakers loads the speaker registry from the configured file. If no file is configured,
+// then the built-in registry is used
+func initSpeakers() error {
+	speakersFile := viper.GetString("speakers-file")
+	if speakersFile == "" {
+		return nil
+	}
+
+	registry, err := catalog.NewSpeakerRegistryFromFile(util.NormalizePath(speakersFile))
+	if err != nil {
+		return err
+	}
+	catalog.SetSpeakerRegistry(registry)
+	return nil
 }
 
 // initLogging updates the configuration for the default logger
@@ -107,11 +162,7 @@ func readOnlineContentFromInput(ctx context.Context) (*catalog.Catalog, error) {
 	// check if reading from file
 	inputFile := viper.GetString("input")
 	if inputFile != "" {
-		inputFile = util.NormalizePath(inputFile)
-		if strings.HasSuffix(strings.ToUpper(inputFile), ".JSON") {
-			return catalog.NewCatalogFromJSON(inputFile)
-		}
-		return nil, fmt.Errorf("filetype %s is not supported", inputFile)
+		return readOnlineContentFromFile(inputFile)
 	}
 
 	// check if reading from Google Sheet
@@ -129,6 +180,39 @@ func readOnlineContentFromInput(ctx context.Context) (*catalog.Catalog, error) {
 	return nil, fmt.Errorf("no input specified. please provide an --input or --sheet-id parameter, or configure a default sheet-id in the ~/.wolm/online.yaml file")
 }
 
+// readOnlineContentFromFile reads the content of a catalog from a file, which can be a JSON dump of
+// the catalog, an XLSX download of the spreadsheet, or a directory with a CSV file for each tab of
+// the spreadsheet. Returns an error if the type of file is not supported
+func readOnlineContentFromFile(inputFile string) (*catalog.Catalog, error) {
+	inputFile = util.NormalizePath(inputFile)
+	if util.IsDirectory(inputFile) {
+		return gclient.NewCatalogFromCSVDir(inputFile)
+	}
+	if strings.HasSuffix(strings.ToUpper(inputFile), ".JSON") {
+		return catalog.NewCatalogFromJSON(inputFile)
+	}
+	if strings.HasSuffix(strings.ToUpper(inputFile), ".XLSX") {
+		return gclient.NewCatalogFromXLSX(inputFile)
+	}
+	return nil, fmt.Errorf("filetype %s is not supported. use a .json or .xlsx file, or a directory of .csv files", inputFile)
+}
+
+// openStorage opens the storage for a target, which is either "s3://bucket/prefix" or a directory
+func openStorage(target string) (storage.Storage, error) {
+	if !strings.HasPrefix(target, "s3://") {
+		target = util.NormalizePath(target)
+	}
+	return storage.Open(target, storage.Options{
+		Endpoint: viper.GetString("s3-endpoint"),
+		Region:   viper.GetString("s3-region"),
+	})
+}
+
+// getAudioStorage opens the storage with the audio files and their transcripts
+func getAudioStorage() (storage.Storage, error) {
+	return openStorage(viper.GetString("audio-storage"))
+}
+
 // getTemplatePath finds the template with the specified name in the template directory. Returns
 // err if a template with the name cannot be found
 func getTemplatePath(templateName string) (string, error) {
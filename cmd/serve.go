@@ -0,0 +1,429 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"maps"
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/WordOfLifeMN/online/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// directory the served files are generated in. the files are only in memory, so this is only
+	// used to name them
+	SERVE_OUTPUT_DIR string = "online"
+	// path of the stream of events that tell the browser to reload the page
+	SERVE_EVENTS_PATH string = "/_events"
+	// path that reads the input again and renders the catalog
+	SERVE_REBUILD_PATH string = "/_rebuild"
+)
+
+type serveCmdStruct struct {
+	cobra.Command // serve command definition
+
+	// flags for the serve command
+	Ministry string        // which ministries to serve, "all" or "*" for all
+	View     string        // which views to serve, "all" or "*" for all
+	Address  string        // host and port to listen on
+	Days     int           // number of days to include in recent messages
+	Interval time.Duration // how often to check the templates and input for changes
+
+	// internal state
+	ministries []catalog.Ministry // ministries being served
+	views      []catalog.View     // views being served
+	schedule   serviceSchedule    // when the scheduled messages are taught
+	source     []byte             // JSON of the catalog as it was read from the input, nil to read it
+	renderLock sync.Mutex         // only renders one catalog at a time
+
+	lock       sync.RWMutex      // lock for everything below
+	files      map[string][]byte // generated files, by path relative to the output directory
+	renderErr  error             // error from the last time the catalog was rendered, nil if it worked
+	renderedAt time.Time         // when the catalog was last rendered
+	rendered   chan struct{}     // closed when the catalog is rendered again
+}
+
+// serveCmd represents the serve command
+var serveCmd *serveCmdStruct
+
+func init() {
+	serveCmd = &serveCmdStruct{
+		Command: cobra.Command{
+			Use:   "serve [--ministry=all] [--view=all] [--address=localhost:8080]",
+			Short: "Preview the online catalog in a browser",
+			Long: `Generates the online catalog in memory and serves it over HTTP, to see changes
+to the templates or the catalog without publishing it.
+
+The templates directory and the --input file (or directory) are checked for
+changes, and the catalog is generated again when they change. Pages open in a
+browser are reloaded when it is. When reading from the spreadsheet, the sheet is
+only read again from the "Read the input again" link on the index page.
+
+The index page (/) has links to the catalog of every ministry and view.`,
+			Example: `serve -i testdata/small-catalog.json
+serve --sheet-id 1vvhIGMPvVF-DtWoYsEbVBvzk_VtLyKuIw_zyLdsB-JY --ministry core --address :9000`,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return serveCmd.serve()
+			},
+		},
+	}
+
+	rootCmd.AddCommand(&serveCmd.Command)
+
+	serveCmd.Flags().StringVar(&serveCmd.Ministry, "ministry", "all", "Ministry to serve the catalog of: all (default), or one ministry")
+	serveCmd.Flags().StringVar(&serveCmd.View, "view", "all", "View to serve the catalog of: all (default), public, partner, private")
+	serveCmd.Flags().StringVar(&serveCmd.Address, "address", "localhost:8080", "Host and port to listen on")
+	serveCmd.Flags().IntVar(&serveCmd.Days, "days", 60, "Number of days to include in the recent message pages. Defaults to 60")
+	serveCmd.Flags().DurationVar(&serveCmd.Interval, "interval", time.Second, "How often to check the templates and input for changes")
+}
+
+func (cmd *serveCmdStruct) serve() error {
+	initLogging()
+
+	var err error
+	if cmd.ministries, err = parseMinistriesFlag(cmd.Ministry); err != nil {
+		return err
+	}
+	if cmd.views, err = parseViewsFlag(cmd.View); err != nil {
+		return err
+	}
+	if cmd.schedule, err = newServiceSchedule(); err != nil {
+		return err
+	}
+	templateDir, err := getTemplateDir()
+	if err != nil {
+		return err
+	}
+
+	// links to the catalog, like in the podcasts, should stay on this server
+	url := cmd.Address
+	if strings.HasPrefix(url, ":") {
+		url = "localhost" + url
+	}
+	viper.Set("catalog-url", "http://"+url)
+
+	watched := []string{templateDir}
+	if input := viper.GetString("input"); input != "" {
+		watched = append(watched, util.NormalizePath(input))
+	}
+
+	// a catalog that can't be rendered is reported in the browser, so it can be fixed while
+	// serving
+	cmd.render(cmd.Context(), true)
+	go cmd.watch(cmd.Context(), watched)
+
+	fmt.Printf("Serving the catalog at http://%s/ (press Ctrl+C to stop)\n", url)
+	return http.ListenAndServe(cmd.Address, cmd)
+}
+
+// ----------------------------------------------------------------------------
+// | Rendering
+// ----------------------------------------------------------------------------
+
+// render generates all the files of the catalog in memory, and tells the browsers to reload. If
+// readInput is true, or the input was never read, then the catalog is read from the input first
+func (cmd *serveCmdStruct) render(ctx context.Context, readInput bool) {
+	cmd.renderLock.Lock()
+	defer cmd.renderLock.Unlock()
+
+	start := time.Now()
+	files, err := cmd.generate(ctx, readInput)
+	if err != nil {
+		log.Printf("ERROR: Cannot render the catalog: %s", err)
+	} else {
+		log.Printf("Rendered %d files in %s", len(files), time.Since(start).Round(time.Millisecond))
+	}
+
+	cmd.lock.Lock()
+	defer cmd.lock.Unlock()
+	if err == nil {
+		// keep the last files that worked, for the pages that still work
+		cmd.files = files
+	}
+	cmd.renderErr = err
+	cmd.renderedAt = time.Now()
+	if cmd.rendered != nil {
+		close(cmd.rendered)
+	}
+	cmd.rendered = make(chan struct{})
+}
+
+// generate reads the catalog if needed and generates all the files of the catalog in memory
+func (cmd *serveCmdStruct) generate(ctx context.Context, readInput bool) (map[string][]byte, error) {
+	if readInput || cmd.source == nil {
+		cat, err := readOnlineContentFromInput(ctx)
+		if err != nil {
+			return nil, err
+		}
+		// the catalog is changed when it's initialized, so keep a copy to start from each time
+		if cmd.source, err = json.Marshal(cat); err != nil {
+			return nil, err
+		}
+	}
+
+	cat := &catalog.Catalog{}
+	if err := json.Unmarshal(cmd.source, cat); err != nil {
+		return nil, err
+	}
+	if !cat.IsValid(false) {
+		return nil, fmt.Errorf("catalog is not valid. run 'check' on it")
+	}
+	if err := cat.Initialize(); err != nil {
+		return nil, err
+	}
+
+	output := newMemoryOutput(SERVE_OUTPUT_DIR)
+	generator := &catalogCmdStruct{
+		OutputDir: SERVE_OUTPUT_DIR,
+		Days:      cmd.Days,
+		cat:       cat,
+		output:    output,
+	}
+	if err := generator.loadTemplates(); err != nil {
+		return nil, fmt.Errorf("unable to load templates for generating the catalog: %w", err)
+	}
+	if err := generator.generate(cmd.ministries, cmd.views, cmd.schedule); err != nil {
+		return nil, err
+	}
+	return output.Files, nil
+}
+
+// watch checks the files at the paths for changes every interval, and renders the catalog again
+// when they change. The input is read again if it's one of the files that changed
+func (cmd *serveCmdStruct) watch(ctx context.Context, paths []string) {
+	snapshots := map[string]map[string]fileSnapshot{}
+	for _, watched := range paths {
+		snapshots[watched] = getFileSnapshots(watched)
+	}
+
+	ticker := time.NewTicker(cmd.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed, readInput := false, false
+		for index, watched := range paths {
+			snapshot := getFileSnapshots(watched)
+			if !maps.Equal(snapshot, snapshots[watched]) {
+				log.Printf("Found changes in %s", watched)
+				snapshots[watched] = snapshot
+				changed = true
+				// the templates are always first, and everything else is input
+				readInput = readInput || index > 0
+			}
+		}
+		if changed {
+			cmd.render(ctx, readInput)
+		}
+	}
+}
+
+// fileSnapshot is what is known about a file to tell if it changed
+type fileSnapshot struct {
+	modTime time.Time // when the file was last changed
+	size    int64     // size of the file
+}
+
+// getFileSnapshots gets a snapshot of a file, or of all the files in a directory, by path.
+// Files that can't be read are left out
+func getFileSnapshots(root string) map[string]fileSnapshot {
+	snapshots := map[string]fileSnapshot{}
+	filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			snapshots[filePath] = fileSnapshot{modTime: info.ModTime(), size: info.Size()}
+		}
+		return nil
+	})
+	return snapshots
+}
+
+// ----------------------------------------------------------------------------
+// | Serving
+// ----------------------------------------------------------------------------
+
+// script added to every HTML page to reload the page when the catalog is rendered again
+const serveReloadScript = `<script>new EventSource("` + SERVE_EVENTS_PATH + `").onmessage = function() { location.reload(); };</script>`
+
+// ServeHTTP serves the index page, the reload events, and the generated files
+func (cmd *serveCmdStruct) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		cmd.serveIndex(w)
+		return
+	case SERVE_EVENTS_PATH:
+		cmd.serveEvents(w, r)
+		return
+	case SERVE_REBUILD_PATH:
+		cmd.render(r.Context(), true)
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	fileName := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+	isHTML := strings.HasSuffix(fileName, ".html")
+
+	cmd.lock.RLock()
+	content, ok := cmd.files[fileName]
+	renderErr := cmd.renderErr
+	cmd.lock.RUnlock()
+
+	if isHTML && renderErr != nil {
+		// show what's wrong until it's fixed, then reload
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "<!DOCTYPE html><html><body><h1>Cannot render the catalog</h1><pre>%s</pre>%s</body></html>",
+			template.HTMLEscapeString(renderErr.Error()), serveReloadScript)
+		return
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(fileName))
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	if isHTML {
+		content = addReloadScript(content)
+	}
+	w.Write(content)
+}
+
+// addReloadScript adds the script that reloads the page to the end of the body of an HTML page
+func addReloadScript(content []byte) []byte {
+	index := bytes.LastIndex(bytes.ToLower(content), []byte("</body>"))
+	if index < 0 {
+		return append(append([]byte{}, content...), serveReloadScript...)
+	}
+
+	page := make([]byte, 0, len(content)+len(serveReloadScript))
+	page = append(page, content[:index]...)
+	page = append(page, serveReloadScript...)
+	return append(page, content[index:]...)
+}
+
+// serveEvents sends an event to the browser every time the catalog is rendered, until the browser
+// goes away
+func (cmd *serveCmdStruct) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "events are not supported", http.StatusInternalServerError)
+		return
+	}
+	getRendered := func() chan struct{} {
+		cmd.lock.RLock()
+		defer cmd.lock.RUnlock()
+		return cmd.rendered
+	}
+
+	// anything rendered after the browser connects is an event
+	rendered := getRendered()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-rendered:
+			rendered = getRendered()
+			fmt.Fprint(w, "data: reload\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// template of the index page, which has links to the catalog of every ministry and view
+var serveIndexTemplate = template.Must(template.New("index").Funcs(template.FuncMap{
+	"GetCatalogFileNameForSeriList":   GetCatalogFileNameForSeriList,
+	"GetCatalogFileNameForComingSoon": GetCatalogFileNameForComingSoon,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>Online catalog preview</title>
+    <style>
+        body { font-family: sans-serif; margin: 2rem; }
+        td, th { padding: 0.3rem 1rem; text-align: left; }
+        pre { color: #b00; white-space: pre-wrap; }
+    </style>
+</head>
+<body>
+    <h1>Online catalog preview</h1>
+    {{if .Error}}<pre>{{.Error}}</pre>{{end}}
+    <table>
+        <tr>
+            <th>Ministry</th>
+            {{range .Views}}<th>{{.}}</th>{{end}}
+            <th></th>
+        </tr>
+        {{range $ministry := .Ministries}}
+        <tr>
+            <td>{{$ministry.Description}}</td>
+            {{range $.Views}}<td><a href="{{GetCatalogFileNameForSeriList $ministry . $.Order}}">Series</a></td>{{end}}
+            <td><a href="{{GetCatalogFileNameForComingSoon $ministry}}">Coming soon</a></td>
+        </tr>
+        {{end}}
+    </table>
+    <p>{{.FileCount}} files rendered at {{.RenderedAt.Format "3:04:05 PM"}}. <a href="{{.Rebuild}}">Read the input again</a></p>
+</body>
+</html>
+`))
+
+// serveIndex serves the index page
+func (cmd *serveCmdStruct) serveIndex(w http.ResponseWriter) {
+	cmd.lock.RLock()
+	data := struct {
+		Ministries []catalog.Ministry
+		Views      []catalog.View
+		Order      string
+		Error      error
+		FileCount  int
+		RenderedAt time.Time
+		Rebuild    string
+	}{
+		Ministries: cmd.ministries,
+		Views:      cmd.views,
+		Order:      CHRONOLOGICAL_DESC,
+		Error:      cmd.renderErr,
+		FileCount:  len(cmd.files),
+		RenderedAt: cmd.renderedAt,
+		Rebuild:    SERVE_REBUILD_PATH,
+	}
+	cmd.lock.RUnlock()
+
+	var buf bytes.Buffer
+	if err := serveIndexTemplate.Execute(&buf, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(addReloadScript(buf.Bytes()))
+}
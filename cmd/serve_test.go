@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestServeCmdTestSuite(t *testing.T) {
+	suite.Run(t, new(ServeCmdTestSuite))
+}
+
+type ServeCmdTestSuite struct {
+	suite.Suite
+}
+
+// newServer creates a server with some files already rendered
+func (t *ServeCmdTestSuite) newServer() *serveCmdStruct {
+	return &serveCmdStruct{
+		ministries: []catalog.Ministry{catalog.WordOfLife},
+		views:      []catalog.View{catalog.Public, catalog.Partner},
+		files: map[string][]byte{
+			"catalog.html":       []byte("<html><body><p>Hi</p></BODY></html>"),
+			"static/all.css":     []byte("body { color: red; }"),
+			"calendar.wol.ics":   []byte("BEGIN:VCALENDAR\r\n"),
+			"catalog.wol.v3.css": []byte("p { }"),
+		},
+		rendered: make(chan struct{}),
+	}
+}
+
+// get gets a page from the server
+func (t *ServeCmdTestSuite) get(server *serveCmdStruct, url string) *httptest.ResponseRecorder {
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, url, nil))
+	return response
+}
+
+func (t *ServeCmdTestSuite) TestAddReloadScript() {
+	t.Equal("<body>x"+serveReloadScript+"</BODY>", string(addReloadScript([]byte("<body>x</BODY>"))))
+	t.Equal("x"+serveReloadScript, string(addReloadScript([]byte("x"))))
+}
+
+func (t *ServeCmdTestSuite) TestServeFiles() {
+	server := t.newServer()
+
+	response := t.get(server, "/catalog.html")
+	t.Equal(http.StatusOK, response.Code)
+	t.Equal("text/html; charset=utf-8", response.Header().Get("Content-Type"))
+	t.Equal("<html><body><p>Hi</p>"+serveReloadScript+"</BODY></html>", response.Body.String())
+
+	response = t.get(server, "/static/../static/all.css")
+	t.Equal(http.StatusOK, response.Code)
+	t.Contains(response.Header().Get("Content-Type"), "text/css")
+	t.Equal("body { color: red; }", response.Body.String())
+
+	response = t.get(server, "/calendar.wol.ics")
+	t.Contains(response.Header().Get("Content-Type"), "text/calendar")
+
+	response = t.get(server, "/missing.html")
+	t.Equal(http.StatusNotFound, response.Code)
+}
+
+func (t *ServeCmdTestSuite) TestServeError() {
+	server := t.newServer()
+	server.renderErr = fmt.Errorf("template: <oops>")
+
+	// pages show the error, but everything else still works
+	response := t.get(server, "/catalog.html")
+	t.Equal(http.StatusInternalServerError, response.Code)
+	t.Contains(response.Body.String(), "template: &lt;oops&gt;")
+	t.Contains(response.Body.String(), serveReloadScript)
+
+	response = t.get(server, "/static/all.css")
+	t.Equal(http.StatusOK, response.Code)
+
+	response = t.get(server, "/")
+	t.Contains(response.Body.String(), "template: &lt;oops&gt;")
+}
+
+func (t *ServeCmdTestSuite) TestServeIndex() {
+	server := t.newServer()
+
+	response := t.get(server, "/")
+	t.Equal(http.StatusOK, response.Code)
+	t.Contains(response.Body.String(), `href="`+GetCatalogFileNameForSeriList(catalog.WordOfLife, catalog.Public, CHRONOLOGICAL_DESC)+`"`)
+	t.Contains(response.Body.String(), `href="`+GetCatalogFileNameForSeriList(catalog.WordOfLife, catalog.Partner, CHRONOLOGICAL_DESC)+`"`)
+	t.Contains(response.Body.String(), `href="`+GetCatalogFileNameForComingSoon(catalog.WordOfLife)+`"`)
+	t.Contains(response.Body.String(), "4 files rendered")
+	t.Contains(response.Body.String(), serveReloadScript)
+}
+
+func (t *ServeCmdTestSuite) TestGenerate() {
+	cat, err := catalog.NewCatalogFromJSON("../testdata/small-catalog.json")
+	t.Require().NoError(err)
+
+	server := t.newServer()
+	server.views = []catalog.View{catalog.Public}
+	server.source, err = json.Marshal(cat)
+	t.Require().NoError(err)
+
+	// the catalog is rendered from the same source every time
+	for range 2 {
+		server.render(context.Background(), false)
+		t.Require().NoError(server.renderErr)
+		t.Contains(server.files, GetCatalogFileNameForSeriList(catalog.WordOfLife, catalog.Public, CHRONOLOGICAL_DESC))
+		t.Contains(server.files, "catalog.wol.v3.css")
+		t.NotContains(server.files, "catalog.html")
+	}
+}
+
+func (t *ServeCmdTestSuite) TestReloadEvents() {
+	server := t.newServer()
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	response, err := http.Get(httpServer.URL + SERVE_EVENTS_PATH)
+	t.Require().NoError(err)
+	defer response.Body.Close()
+	t.Equal("text/event-stream", response.Header.Get("Content-Type"))
+
+	// tell the browser when the catalog is rendered again
+	server.lock.Lock()
+	close(server.rendered)
+	server.rendered = make(chan struct{})
+	server.lock.Unlock()
+
+	buf := make([]byte, 64)
+	n, err := response.Body.Read(buf)
+	t.NoError(err)
+	t.Equal("data: reload\n\n", string(buf[:n]))
+}
+
+func (t *ServeCmdTestSuite) TestGetFileSnapshots() {
+	dir := t.T().TempDir()
+	filePath := filepath.Join(dir, "sub", "catalog.seri.html")
+	t.Require().NoError(os.MkdirAll(filepath.Dir(filePath), 0777))
+	t.Require().NoError(os.WriteFile(filePath, []byte("one"), 0666))
+
+	before := getFileSnapshots(dir)
+	t.Len(before, 1)
+	t.Equal(before, getFileSnapshots(dir))
+
+	t.Require().NoError(os.WriteFile(filePath, []byte("three"), 0666))
+	t.NotEqual(before, getFileSnapshots(dir))
+
+	before = getFileSnapshots(filePath)
+	t.Len(before, 1)
+	t.Require().NoError(os.Chtimes(filePath, time.Now(), time.Now().Add(time.Hour)))
+	t.NotEqual(before, getFileSnapshots(filePath))
+}
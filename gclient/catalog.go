@@ -16,26 +16,32 @@ import (
 // NewCatalogFromSheet takes a valid spreadsheet service and a spreadsheet
 // document ID and creates a catalog from the info in the spreadsheet
 func NewCatalogFromSheet(service *sheets.Service, documentID string) (*catalog.Catalog, error) {
-	// initialize the catalog
-	catalog := catalog.Catalog{
-		Created: time.Now(),
-	}
-
 	// get document information
 	document, err := service.Spreadsheets.Get(documentID).Do()
 	if err != nil {
-		return &catalog, err
+		return &catalog.Catalog{Created: time.Now()}, err
 	}
 
 	log.Printf("Reading catalog from spreadsheet %s (ID: %s)", document.Properties.Title, documentID)
-	messages, msgSeries, err := readMessagesFromDocument(service, documentID)
+	return newCatalogFromWorkbook(&sheetWorkbook{service: service, documentID: documentID, document: document})
+}
+
+// newCatalogFromWorkbook creates a catalog from the info in a workbook. Every tab is a tab of
+// messages, except the "Series" tab and tabs whose names start with "_"
+func newCatalogFromWorkbook(book workbook) (*catalog.Catalog, error) {
+	// initialize the catalog
+	catalog := catalog.Catalog{
+		Created: time.Now(),
+	}
+
+	messages, msgSeries, err := readMessagesFromDocument(book)
 	if err != nil {
 		return &catalog, err
 	}
 	catalog.Messages = messages
 
 	// Series tab is a fallback: only append entries whose name isn't already in msgSeries
-	tabSeries, err := readSeriesFromDocument(service, documentID)
+	tabSeries, err := readSeriesFromDocument(book)
 	if err != nil {
 		return &catalog, err
 	}
@@ -74,6 +80,8 @@ const (
 	seriesCDJacket    string = "CD Jacket"
 	seriesDVDJacket   string = "DVD Jacket"
 	seriesThumbnail   string = "Cover Art"
+	seriesMinistry    string = "Ministry" // optional
+	seriesAlsoIn      string = "Also In"  // optional
 )
 
 var requiredSeriesColumns []string = []string{
@@ -87,12 +95,12 @@ var requiredSeriesColumns []string = []string{
 // readSeriesFromDocument finds the "Series" tab and reads the series data from
 // it. If the tab does not exist, it returns an empty slice and no error so the
 // caller can treat the tab as an optional fallback.
-func readSeriesFromDocument(service *sheets.Service, documentID string) ([]catalog.CatalogSeri, error) {
+func readSeriesFromDocument(book workbook) ([]catalog.CatalogSeri, error) {
 	tabName := "Series"
 	log.Printf("Reading the Series from tab '%s'\n", tabName)
 
 	// get the first row as column titles
-	columns, err := getIndexOfColumns(service, documentID, tabName, 1)
+	columns, err := getIndexOfColumns(book, tabName, 1)
 	if err != nil {
 		log.Printf("Series tab '%s' not found or unreadable, skipping: %v", tabName, err)
 		return nil, nil
@@ -114,16 +122,15 @@ func readSeriesFromDocument(service *sheets.Service, documentID string) ([]catal
 	var series []catalog.CatalogSeri
 
 	// read a the series data from the spreadsheet
-	seriesRange := fmt.Sprintf("'%s'!2:80000", tabName)
-	values, err := service.Spreadsheets.Values.Get(documentID, seriesRange).Do()
+	rows, err := book.GetRows(tabName, 2, 80000)
 	if err != nil {
 		log.Printf("Unable to read the series: %v", err)
 		return series, err
 	}
 
 	// iterate through all the results, creating a new series for each one
-	log.Printf("  Found %d series", len(values.Values))
-	for seriesIndex, seriesRow := range values.Values {
+	log.Printf("  Found %d series", len(rows))
+	for seriesIndex, seriesRow := range rows {
 		seri, err := newCatalogSeriFromRow(columns, seriesRow)
 		if err != nil {
 			log.Printf("Unable to read series from row %d: %s", seriesIndex+2, err)
@@ -148,6 +155,16 @@ func newCatalogSeriFromRow(columns map[string]int, rowData []any) (catalog.Catal
 	seri.Visibility = catalog.NewViewFromString(getCellString(rowData, columns[seriesVisibility]))
 	seri.Thumbnail = getCellString(rowData, columns[seriesThumbnail])
 
+	// ministries are optional
+	if colIdx, ok := columns[seriesMinistry]; ok {
+		if v := getCellString(rowData, colIdx); v != "" {
+			seri.Ministry = catalog.NewMinistryFromString(v)
+		}
+	}
+	if colIdx, ok := columns[seriesAlsoIn]; ok {
+		seri.AlsoIn = catalog.NewMinistriesFromString(getCellString(rowData, colIdx))
+	}
+
 	// get dates
 	dString := getCellString(rowData, columns[seriesStartDate])
 	if dString == "" {
@@ -194,6 +211,8 @@ const (
 	msgAudio       string = "Audio"
 	msgVideo       string = "Video"
 	msgResources   string = "Resources"
+	msgAlsoIn      string = "Also In" // optional
+	msgTags        string = "Tags"    // optional
 )
 
 var requiredMessageColumns []string = []string{
@@ -209,7 +228,7 @@ var requiredMessageColumns []string = []string{
 // from them. It also extracts any Series/Booklet rows and returns them as a
 // separate series list.
 func readMessagesFromDocument(
-	service *sheets.Service, documentID string,
+	book workbook,
 ) (
 	[]catalog.CatalogMessage, []catalog.CatalogSeri, error,
 ) {
@@ -217,15 +236,14 @@ func readMessagesFromDocument(
 	var series []catalog.CatalogSeri
 
 	// get information about all the sheets
-	document, err := service.Spreadsheets.Get(documentID).Do()
+	titles, err := book.GetTabNames()
 	if err != nil {
 		return messages, series, err
 	}
 
 	// iterate through all the sheets: skip "_"-prefixed and "Series" tabs,
 	// treat all others as message tabs using the tab name as the default ministry
-	for _, sheet := range document.Sheets {
-		title := sheet.Properties.Title
+	for _, title := range titles {
 		log.Printf("Checking sheet %s\n", title)
 		if strings.HasPrefix(title, "_") {
 			log.Printf("Ignoring sheet '%s' (starts with '_')\n", title)
@@ -234,7 +252,7 @@ func readMessagesFromDocument(
 		if strings.EqualFold(title, "Series") {
 			continue
 		}
-		sheetMessages, sheetSeries, err := readMessagesFromSheet(service, documentID, title, title)
+		sheetMessages, sheetSeries, err := readMessagesFromSheet(book, title, title)
 		if err != nil {
 			log.Printf("Unable to read messages from sheet '%s': %s", title, err)
 			continue
@@ -249,11 +267,11 @@ func readMessagesFromDocument(
 // readMessagesFromSheet reads a series of messages from a single sheet in a document.
 // defaultMinistry is used for any message that does not have an explicit Ministry column value.
 // Rows with type Series or Booklet are returned as CatalogSeri rather than CatalogMessage.
-func readMessagesFromSheet(service *sheets.Service, documentID string, sheetName string, defaultMinistry string) ([]catalog.CatalogMessage, []catalog.CatalogSeri, error) {
+func readMessagesFromSheet(book workbook, sheetName string, defaultMinistry string) ([]catalog.CatalogMessage, []catalog.CatalogSeri, error) {
 	log.Printf("Reading the Messages from tab '%s'\n", sheetName)
 
 	// get the first row as column titles
-	columns, err := getIndexOfColumns(service, documentID, sheetName, 1)
+	columns, err := getIndexOfColumns(book, sheetName, 1)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -271,15 +289,14 @@ func readMessagesFromSheet(service *sheets.Service, documentID string, sheetName
 	var series []catalog.CatalogSeri
 
 	// read the data from the spreadsheet
-	messageRange := fmt.Sprintf("'%s'!2:80000", sheetName)
-	values, err := service.Spreadsheets.Values.Get(documentID, messageRange).Do()
+	rows, err := book.GetRows(sheetName, 2, 80000)
 	if err != nil {
 		log.Printf("Unable to read the messages: %v", err)
 		return messages, series, err
 	}
 
-	log.Printf("  Found %d rows", len(values.Values))
-	for messageIndex, messageRow := range values.Values {
+	log.Printf("  Found %d rows", len(rows))
+	for messageIndex, messageRow := range rows {
 		message, err := newCatalogMessageFromRow(columns, messageRow, defaultMinistry)
 		if err != nil {
 			log.Printf("Unable to read message from row %d: %s", messageIndex+2, err)
@@ -306,6 +323,8 @@ func newCatalogSeriFromMessageRow(msg catalog.CatalogMessage) catalog.CatalogSer
 	seri.Name = msg.Name
 	seri.Description = msg.Description
 	seri.Visibility = msg.Visibility
+	seri.Ministry = msg.Ministry
+	seri.AlsoIn = msg.AlsoIn
 	if msg.Thumb != nil {
 		seri.Thumbnail = msg.Thumb.URL
 	}
@@ -354,6 +373,9 @@ func newCatalogMessageFromRow(columns map[string]int, rowData []any, defaultMini
 		}
 	}
 	msg.Ministry = catalog.NewMinistryFromString(ministryStr)
+	if colIdx, ok := columns[msgAlsoIn]; ok {
+		msg.AlsoIn = catalog.NewMinistriesFromString(getCellString(rowData, colIdx))
+	}
 	msg.Type = catalog.NewMessageTypeFromString(getCellString(rowData, columns[msgType]))
 	msg.Visibility = catalog.NewViewFromString(getCellString(rowData, columns[msgVisibility]))
 
@@ -365,6 +387,11 @@ func newCatalogMessageFromRow(columns map[string]int, rowData []any, defaultMini
 		}
 	}
 
+	// tags are optional
+	if colIdx, ok := columns[msgTags]; ok {
+		msg.Tags = catalog.NewTagsFromString(getCellString(rowData, colIdx))
+	}
+
 	// series
 	msg.Series = catalog.NewSeriesReferencesFromStrings(
 		getCellString(rowData, columns[msgSeries]),
@@ -380,18 +407,19 @@ func newCatalogMessageFromRow(columns map[string]int, rowData []any, defaultMini
 // getIndexOfColumns takes a sheet name and returns all the column titles in a
 // map where the key is the column name, and the value is the index of the
 // column
-func getIndexOfColumns(service *sheets.Service, documentID string, tabName string, titleRow int) (map[string]int, error) {
+func getIndexOfColumns(book workbook, tabName string, titleRow int) (map[string]int, error) {
 	// the range of the column titles is always the entire row
-	titleRange := fmt.Sprintf("'%s'!%d:%d", tabName, titleRow, titleRow)
-
-	values, err := service.Spreadsheets.Values.Get(documentID, titleRange).Do()
+	rows, err := book.GetRows(tabName, titleRow, titleRow)
 	if err != nil {
 		return nil, err
 	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("tab '%s' has no column titles in row %d", tabName, titleRow)
+	}
 
 	columns := map[string]int{}
-	for columnIndex, columnName := range values.Values[0] {
-		columns[columnName.(string)] = columnIndex
+	for columnIndex, columnName := range rows[0] {
+		columns[fmt.Sprintf("%v", columnName)] = columnIndex
 	}
 
 	return columns, nil
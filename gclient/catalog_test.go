@@ -27,6 +27,11 @@ func (t *CatalogTestSuite) SetupSuite() {
 	t.NoError(err)
 }
 
+// newSheetWorkbook gets the test spreadsheet as a workbook
+func (t *CatalogTestSuite) newSheetWorkbook() workbook {
+	return &sheetWorkbook{service: t.service, documentID: testDocumentID}
+}
+
 // +---------------------------------------------------------------------------
 // | Unit tests (no Google API)
 // +---------------------------------------------------------------------------
@@ -265,7 +270,7 @@ func (t *CatalogTestSuite) TestNewCatalogMessageFromRow_InvalidDate() {
 
 func (t *CatalogTestSuite) TestReadColumns() {
 	// when
-	columns, err := getIndexOfColumns(t.service, testDocumentID, "Columns", 1)
+	columns, err := getIndexOfColumns(t.newSheetWorkbook(), "Columns", 1)
 	t.NoError(err)
 
 	// then
@@ -280,7 +285,7 @@ func (t *CatalogTestSuite) TestReadColumns() {
 
 func (t *CatalogTestSuite) TestReadSeries() {
 	// when
-	series, err := readSeriesFromDocument(t.service, testDocumentID)
+	series, err := readSeriesFromDocument(t.newSheetWorkbook())
 	t.NoError(err)
 
 	// then
@@ -309,7 +314,7 @@ func (t *CatalogTestSuite) TestReadSeries() {
 
 func (t *CatalogTestSuite) TestReadMessageSheet() {
 	// when
-	msgs, series, err := readMessagesFromSheet(t.service, testDocumentID, "Messages", "Messages")
+	msgs, series, err := readMessagesFromSheet(t.newSheetWorkbook(), "Messages", "Messages")
 	t.NoError(err)
 	_ = series
 
@@ -357,7 +362,7 @@ func (t *CatalogTestSuite) TestReadMessageSheet() {
 
 func (t *CatalogTestSuite) TestReadMessagesFromDocument() {
 	// when
-	messages, series, err := readMessagesFromDocument(t.service, testDocumentID)
+	messages, series, err := readMessagesFromDocument(t.newSheetWorkbook())
 
 	// then
 	t.NoError(err)
@@ -0,0 +1,103 @@
+package gclient
+
+// code that reads a catalog from a directory of CSV files, one for each tab of the spreadsheet
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/WordOfLifeMN/online/catalog"
+)
+
+// NewCatalogFromCSVDir creates a catalog from a directory of CSV files, each of which is a tab
+// of the spreadsheet with the same columns as the Google Sheet. The name of the tab is the name
+// of the file without ".csv" and anything up to the last " - ", which is how Google Sheets names
+// a downloaded tab ("Catalog - Messages.csv" is the "Messages" tab). The tabs are read in order
+// of their names
+func NewCatalogFromCSVDir(dirPath string) (*catalog.Catalog, error) {
+	book, err := readCSVWorkbook(dirPath)
+	if err != nil {
+		return &catalog.Catalog{}, err
+	}
+
+	log.Printf("Reading catalog from CSV files in %s", dirPath)
+	return newCatalogFromWorkbook(book)
+}
+
+// readCSVWorkbook reads all the CSV files in a directory as the tabs of a workbook
+func readCSVWorkbook(dirPath string) (*fileWorkbook, error) {
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read CSV directory %s: %w", dirPath, err)
+	}
+
+	book := &fileWorkbook{path: dirPath, tabs: map[string][][]any{}}
+	var tabNames []string
+	files := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".csv") {
+			continue
+		}
+
+		tabName := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if index := strings.LastIndex(tabName, " - "); index >= 0 {
+			tabName = tabName[index+len(" - "):]
+		}
+		if _, ok := files[tabName]; ok {
+			return nil, fmt.Errorf("%s has more than one file for the tab '%s'", dirPath, tabName)
+		}
+		files[tabName] = filepath.Join(dirPath, entry.Name())
+		tabNames = append(tabNames, tabName)
+	}
+	if len(tabNames) == 0 {
+		return nil, fmt.Errorf("there are no CSV files in %s", dirPath)
+	}
+	sort.Strings(tabNames)
+
+	for _, tabName := range tabNames {
+		rows, err := readCSVFile(files[tabName])
+		if err != nil {
+			return nil, err
+		}
+		if err := book.addTab(tabName, rows); err != nil {
+			return nil, err
+		}
+	}
+
+	return book, nil
+}
+
+// readCSVFile reads all the rows of a CSV file. Rows can have any number of cells
+func readCSVFile(filePath string) ([][]any, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open CSV file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read CSV file %s: %w", filePath, err)
+	}
+
+	rows := make([][]any, 0, len(records))
+	for recordIndex, record := range records {
+		row := make([]any, 0, len(record))
+		for cellIndex, cell := range record {
+			if recordIndex == 0 && cellIndex == 0 {
+				// spreadsheets often start a CSV file with a byte order mark
+				cell = strings.TrimPrefix(cell, "\ufeff")
+			}
+			row = append(row, cell)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
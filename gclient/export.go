@@ -0,0 +1,381 @@
+package gclient
+
+// code that writes a catalog in the shape of the spreadsheet, so a dump of the catalog can be
+// edited like the spreadsheet and read back in as a CSV file or an XLSX workbook
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/WordOfLifeMN/online/catalog"
+)
+
+// columns written for every row of a message tab, in order
+var exportMessageColumns []string = []string{
+	msgDate, msgName, msgSpeakers,
+	msgMinistry, msgAlsoIn,
+	msgType, msgVisibility,
+	msgSeries, msgSeriesIndex,
+	msgDescription, msgTags,
+	msgThumb, msgAudio, msgVideo,
+	msgResources,
+}
+
+// columns written for every row of the "Series" tab, in order
+var exportSeriesColumns []string = []string{
+	seriesID, seriesName, seriesDescription,
+	seriesStartDate, seriesEndDate,
+	seriesVisibility,
+	seriesBooklets,
+	seriesCDJacket, seriesDVDJacket, seriesThumbnail,
+	seriesMinistry, seriesAlsoIn,
+}
+
+// WriteCatalogAsCSV writes the catalog as a single tab of messages in CSV. Every row has its
+// ministry, so the file can be read back in no matter what it's named. Series are written as
+// "Series" rows (or "Booklet" rows if they have no messages) just before their first message
+func WriteCatalogAsCSV(cat *catalog.Catalog, output io.Writer) error {
+	book := newWorkbookFromCatalog(cat, false)
+
+	writer := csv.NewWriter(output)
+	if err := writer.Write(exportMessageColumns); err != nil {
+		return err
+	}
+	for _, tabName := range book.names {
+		for _, row := range book.tabs[tabName][1:] {
+			record := make([]string, 0, len(row))
+			for _, cell := range row {
+				record = append(record, cell.(string))
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteCatalogAsXLSX writes the catalog as an XLSX workbook with a tab of messages for each
+// ministry and a "Series" tab, like the spreadsheet. The series are only in the "Series" tab, so
+// their IDs, dates, and jackets are read back in unchanged
+func WriteCatalogAsXLSX(cat *catalog.Catalog, output io.Writer) error {
+	return writeXLSXWorkbook(newWorkbookFromCatalog(cat, true), output)
+}
+
+// +---------------------------------------------------------------------------
+// | Rows
+// +---------------------------------------------------------------------------
+
+// newWorkbookFromCatalog creates a workbook with a tab of messages for each ministry, in the order
+// the ministries are first found in the catalog. The first row of each tab has the column titles.
+// If seriesTab is true, the series are written to a "Series" tab after the message tabs, otherwise
+// they are rows in the message tabs
+func newWorkbookFromCatalog(cat *catalog.Catalog, seriesTab bool) *fileWorkbook {
+	book := &fileWorkbook{path: "catalog", tabs: map[string][][]any{}}
+	addRow := func(ministry catalog.Ministry, row []any) {
+		tabName := strings.ToUpper(string(ministry))
+		if _, ok := book.tabs[tabName]; !ok {
+			book.addTab(tabName, [][]any{getExportTitleRow(exportMessageColumns)})
+		}
+		book.tabs[tabName] = append(book.tabs[tabName], row)
+	}
+
+	if seriesTab {
+		for msgIndex := range cat.Messages {
+			msg := &cat.Messages[msgIndex]
+			addRow(msg.Ministry, newMessageRow(msg))
+		}
+		rows := [][]any{getExportTitleRow(exportSeriesColumns)}
+		for seriIndex := range cat.Series {
+			rows = append(rows, newSeriesTabRow(&cat.Series[seriIndex]))
+		}
+		book.addTab("Series", rows)
+		return book
+	}
+
+	// series are written just before their first message
+	written := map[string]bool{}
+	for msgIndex := range cat.Messages {
+		msg := &cat.Messages[msgIndex]
+		for _, ref := range msg.Series {
+			if written[ref.Name] {
+				continue
+			}
+			for seriIndex := range cat.Series {
+				seri := &cat.Series[seriIndex]
+				if seri.Name == ref.Name {
+					written[seri.Name] = true
+					ministry := seri.Ministry
+					if ministry == "" {
+						ministry = msg.Ministry
+					}
+					addRow(ministry, newSeriRow(seri, ministry, catalog.Series))
+					break
+				}
+			}
+		}
+		addRow(msg.Ministry, newMessageRow(msg))
+	}
+
+	// anything left has no messages, which is a booklet
+	for seriIndex := range cat.Series {
+		seri := &cat.Series[seriIndex]
+		if written[seri.Name] {
+			continue
+		}
+		written[seri.Name] = true
+		ministry := seri.Ministry
+		if ministry == "" {
+			ministry = catalog.WordOfLife
+			if len(book.names) > 0 {
+				ministry = catalog.NewMinistryFromString(book.names[0])
+			}
+		}
+		addRow(ministry, newSeriRow(seri, ministry, catalog.Booklet))
+	}
+
+	return book
+}
+
+// getExportTitleRow gets the row of column titles
+func getExportTitleRow(columns []string) []any {
+	row := make([]any, 0, len(columns))
+	for _, column := range columns {
+		row = append(row, column)
+	}
+	return row
+}
+
+// newMessageRow gets the cells of a message in the order of the exported columns
+func newMessageRow(msg *catalog.CatalogMessage) []any {
+	var names, tracks []string
+	for _, ref := range msg.Series {
+		names = append(names, ref.Name)
+		track := ""
+		if ref.Index != 0 {
+			track = strconv.Itoa(ref.Index)
+		}
+		tracks = append(tracks, track)
+	}
+
+	date := ""
+	if !msg.Date.IsZero() {
+		date = msg.Date.String()
+	}
+
+	return newExportRow(exportMessageColumns, map[string]string{
+		msgDate:        date,
+		msgName:        msg.Name,
+		msgSpeakers:    strings.Join(msg.Speakers, ";"),
+		msgMinistry:    string(msg.Ministry),
+		msgAlsoIn:      formatMinistries(msg.AlsoIn),
+		msgType:        string(msg.Type),
+		msgVisibility:  string(msg.Visibility),
+		msgSeries:      strings.Join(names, ";"),
+		msgSeriesIndex: strings.Join(tracks, ";"),
+		msgDescription: msg.Description,
+		msgTags:        strings.Join(msg.Tags, ";"),
+		msgThumb:       formatResource(msg.Thumb),
+		msgAudio:       formatResource(msg.Audio),
+		msgVideo:       formatResource(msg.Video),
+		msgResources:   formatResources(msg.Resources),
+	})
+}
+
+// newSeriRow gets the cells of a Series or Booklet row for a series in the tab of a ministry. The
+// dates, ID, and jacket of the series aren't in the row: the dates and ID come from its messages
+// and name when it's read
+func newSeriRow(seri *catalog.CatalogSeri, ministry catalog.Ministry, rowType catalog.MessageType) []any {
+	thumbnail := ""
+	if seri.Thumbnail != "" {
+		thumbnail = formatResource(&catalog.OnlineResource{URL: seri.Thumbnail})
+	}
+
+	return newExportRow(exportMessageColumns, map[string]string{
+		msgName:        seri.Name,
+		msgMinistry:    string(ministry),
+		msgAlsoIn:      formatMinistries(seri.AlsoIn),
+		msgType:        string(rowType),
+		msgVisibility:  string(seri.Visibility),
+		msgDescription: seri.Description,
+		msgThumb:       thumbnail,
+		msgResources:   formatResources(seri.Booklets),
+	})
+}
+
+// newSeriesTabRow gets the cells of a series in the order of the columns of the "Series" tab. The
+// jacket is written as the DVD jacket, which is the one that is read first
+func newSeriesTabRow(seri *catalog.CatalogSeri) []any {
+	formatDate := func(date catalog.DateOnly) string {
+		if date.IsZero() {
+			return ""
+		}
+		return date.String()
+	}
+
+	return newExportRow(exportSeriesColumns, map[string]string{
+		seriesID:          seri.ID,
+		seriesName:        seri.Name,
+		seriesDescription: seri.Description,
+		seriesStartDate:   formatDate(seri.StartDate),
+		seriesEndDate:     formatDate(seri.StopDate),
+		seriesVisibility:  string(seri.Visibility),
+		seriesBooklets:    formatResources(seri.Booklets),
+		seriesDVDJacket:   seri.Jacket,
+		seriesThumbnail:   seri.Thumbnail,
+		seriesMinistry:    string(seri.Ministry),
+		seriesAlsoIn:      formatMinistries(seri.AlsoIn),
+	})
+}
+
+// newExportRow puts the cells of a row in the order of the columns
+func newExportRow(columns []string, cells map[string]string) []any {
+	row := make([]any, 0, len(columns))
+	for _, column := range columns {
+		row = append(row, cells[column])
+	}
+	return row
+}
+
+// formatMinistries formats a list of ministries the way they are written in the spreadsheet
+func formatMinistries(ministries []catalog.Ministry) string {
+	var names []string
+	for _, ministry := range ministries {
+		names = append(names, string(ministry))
+	}
+	return strings.Join(names, ";")
+}
+
+// formatResource formats a resource the way it is written in the spreadsheet: "name|url", or just
+// the URL if the name is the one that would be made from the URL, followed by any metadata as
+// JSON. This is the opposite of catalog.NewResourceFromString
+func formatResource(resource *catalog.OnlineResource) string {
+	if resource == nil || resource.URL == "" {
+		return ""
+	}
+
+	s := resource.URL
+	if resource.Name != "" && resource.Name != resource.GetNameFromURL() {
+		s = resource.Name + "|" + resource.URL
+	}
+	if len(resource.Metadata) > 0 {
+		if metadata, err := json.Marshal(resource.Metadata); err == nil {
+			s += " " + string(metadata)
+		}
+	}
+	return s
+}
+
+// formatResources formats a list of resources the way they are written in the spreadsheet
+func formatResources(resources []catalog.OnlineResource) string {
+	var list []string
+	for index := range resources {
+		if s := formatResource(&resources[index]); s != "" {
+			list = append(list, s)
+		}
+	}
+	return strings.Join(list, ";")
+}
+
+// +---------------------------------------------------------------------------
+// | XLSX
+// +---------------------------------------------------------------------------
+
+// writeXLSXWorkbook writes a workbook as an XLSX file. All the cells are written as text, the way
+// the cells are read from the spreadsheet
+func writeXLSXWorkbook(book *fileWorkbook, output io.Writer) error {
+	archive := zip.NewWriter(output)
+
+	var contentTypes, sheets, relationships strings.Builder
+	for index, tabName := range book.names {
+		number := index + 1
+		fmt.Fprintf(&contentTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, number)
+		fmt.Fprintf(&sheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escapeXML(tabName), number, number)
+		fmt.Fprintf(&relationships, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, number, number)
+	}
+
+	type xlsxPart struct {
+		name    string // path of the file in the zip
+		content string // XML of the file
+	}
+	parts := []xlsxPart{
+		{"[Content_Types].xml", xml.Header +
+			`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			contentTypes.String() +
+			`</Types>`},
+		{"_rels/.rels", xml.Header +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xml.Header +
+			`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + sheets.String() + `</sheets>` +
+			`</workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			relationships.String() +
+			`</Relationships>`},
+	}
+	for index, tabName := range book.names {
+		parts = append(parts, xlsxPart{fmt.Sprintf("xl/worksheets/sheet%d.xml", index+1), getXLSXSheetXML(book.tabs[tabName])})
+	}
+
+	for _, part := range parts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// getXLSXSheetXML gets the XML of a sheet with the given rows. Empty cells are left out
+func getXLSXSheetXML(rows [][]any) string {
+	var sheet strings.Builder
+	sheet.WriteString(xml.Header)
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for rowIndex, row := range rows {
+		fmt.Fprintf(&sheet, `<row r="%d">`, rowIndex+1)
+		for columnIndex, cell := range row {
+			value := fmt.Sprintf("%v", cell)
+			if value == "" {
+				continue
+			}
+			fmt.Fprintf(&sheet, `<c r="%s%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`,
+				getXLSXColumnName(columnIndex), rowIndex+1, escapeXML(value))
+		}
+		sheet.WriteString(`</row>`)
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+	return sheet.String()
+}
+
+// getXLSXColumnName gets the name of a column from its index, like "AB" for 27. This is the
+// opposite of getXLSXColumnIndex
+func getXLSXColumnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+// escapeXML escapes text to be put in XML
+func escapeXML(s string) string {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(s))
+	return escaped.String()
+}
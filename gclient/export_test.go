@@ -0,0 +1,242 @@
+package gclient
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/stretchr/testify/suite"
+)
+
+// Runs the test suite as a test
+func TestExportTestSuite(t *testing.T) {
+	suite.Run(t, new(ExportTestSuite))
+}
+
+type ExportTestSuite struct {
+	suite.Suite
+	cat *catalog.Catalog
+}
+
+func (t *ExportTestSuite) SetupTest() {
+	t.cat = &catalog.Catalog{
+		Series: []catalog.CatalogSeri{
+			{ID: "S1", Name: "Faith", Description: "Believing God", Visibility: catalog.Public, Thumbnail: "https://example.com/faith.jpg"},
+			{ID: "S2", Name: "Study Guide", Visibility: catalog.Partner, Ministry: catalog.CenterOfRelationshipExperience,
+				Booklets: []catalog.OnlineResource{{URL: "https://example.com/guide.pdf", Name: "Guide"}}},
+		},
+		Messages: []catalog.CatalogMessage{
+			{
+				Date:        catalog.MustParseDateOnly("2021-09-12"),
+				Name:        "Faith, Part 1",
+				Description: "Line 1\nLine 2 with \"quotes\" & <brackets>",
+				Speakers:    []string{"Vern Peltz", "Mary Peltz"},
+				Tags:        []string{"faith", "hope"},
+				Ministry:    catalog.WordOfLife,
+				AlsoIn:      []catalog.Ministry{catalog.CenterOfRelationshipExperience},
+				Type:        catalog.Message,
+				Visibility:  catalog.Public,
+				Series:      []catalog.SeriesReference{{Name: "Faith", Index: 1}},
+				Audio:       &catalog.OnlineResource{URL: "https://example.com/faith1.mp3", Name: "faith1"},
+				Video:       &catalog.OnlineResource{URL: "https://youtu.be/999", Name: "Video", Metadata: map[string]string{"id": "999"}},
+				Resources:   []catalog.OnlineResource{{URL: "https://example.com/notes.pdf", Name: "Notes"}, {URL: "https://example.com/more.pdf", Name: "more"}},
+			},
+			{
+				Date:       catalog.MustParseDateOnly("2021-10-03"),
+				Name:       "Marriage",
+				Speakers:   []string{"Mary Peltz"},
+				Ministry:   catalog.CenterOfRelationshipExperience,
+				Type:       catalog.Training,
+				Visibility: catalog.Private,
+			},
+		},
+	}
+}
+
+// checkCatalog checks a catalog that was read back in after being exported
+func (t *ExportTestSuite) checkCatalog(cat *catalog.Catalog) {
+	if t.Len(cat.Messages, 2) {
+		msg := cat.Messages[0]
+		expected := t.cat.Messages[0]
+		t.Equal(expected.Date, msg.Date)
+		t.Equal(expected.Name, msg.Name)
+		t.Equal(expected.Description, msg.Description)
+		t.Equal(expected.Speakers, msg.Speakers)
+		t.Equal(expected.Tags, msg.Tags)
+		t.Equal(expected.Ministry, msg.Ministry)
+		t.Equal(expected.AlsoIn, msg.AlsoIn)
+		t.Equal(expected.Type, msg.Type)
+		t.Equal(expected.Visibility, msg.Visibility)
+		t.Equal(expected.Series, msg.Series)
+		t.Equal(expected.Audio, msg.Audio)
+		t.Equal(expected.Video, msg.Video)
+		t.Equal(expected.Resources, msg.Resources)
+
+		t.Equal("Marriage", cat.Messages[1].Name)
+		t.Equal(catalog.CenterOfRelationshipExperience, cat.Messages[1].Ministry)
+		t.Equal(catalog.Training, cat.Messages[1].Type)
+	}
+
+	if t.Len(cat.Series, 2) {
+		t.Equal("Faith", cat.Series[0].Name)
+		t.Equal("Believing God", cat.Series[0].Description)
+		t.Equal("https://example.com/faith.jpg", cat.Series[0].Thumbnail)
+		t.Equal("Study Guide", cat.Series[1].Name)
+		t.Equal(catalog.Partner, cat.Series[1].Visibility)
+		t.Equal(t.cat.Series[1].Booklets, cat.Series[1].Booklets)
+	}
+}
+
+func (t *ExportTestSuite) TestNewWorkbookFromCatalog() {
+	book := newWorkbookFromCatalog(t.cat, false)
+	t.Equal([]string{"WOL", "CORE"}, book.names)
+
+	// the series row is just before its first message, and the booklet is at the end
+	if t.Len(book.tabs["WOL"], 3) {
+		t.Equal(msgDate, book.tabs["WOL"][0][0])
+		t.Equal("Faith", book.tabs["WOL"][1][1])
+		t.Equal("series", book.tabs["WOL"][1][5])
+		t.Equal("Faith, Part 1", book.tabs["WOL"][2][1])
+	}
+	if t.Len(book.tabs["CORE"], 3) {
+		t.Equal("Marriage", book.tabs["CORE"][1][1])
+		t.Equal("Study Guide", book.tabs["CORE"][2][1])
+		t.Equal("booklet", book.tabs["CORE"][2][5])
+	}
+
+	cat, err := newCatalogFromWorkbook(book)
+	t.Require().NoError(err)
+	t.checkCatalog(cat)
+	// the ministry of a series row is the ministry of its tab
+	t.Equal(catalog.WordOfLife, cat.Series[0].Ministry)
+}
+
+func (t *ExportTestSuite) TestNewWorkbookFromCatalog_SeriesTab() {
+	book := newWorkbookFromCatalog(t.cat, true)
+	t.Equal([]string{"WOL", "CORE", "Series"}, book.names)
+
+	// the series are only in the series tab
+	t.Len(book.tabs["WOL"], 2)
+	t.Len(book.tabs["CORE"], 2)
+	if t.Len(book.tabs["Series"], 3) {
+		t.Equal(seriesID, book.tabs["Series"][0][0])
+		t.Equal("S1", book.tabs["Series"][1][0])
+		t.Equal("Faith", book.tabs["Series"][1][1])
+		t.Equal("S2", book.tabs["Series"][2][0])
+	}
+
+	cat, err := newCatalogFromWorkbook(book)
+	t.Require().NoError(err)
+	t.checkCatalog(cat)
+	t.Equal("S1", cat.Series[0].ID)
+	t.Equal(catalog.Ministry(""), cat.Series[0].Ministry)
+}
+
+func (t *ExportTestSuite) TestWriteCatalogAsCSV() {
+	dir := t.T().TempDir()
+	var output bytes.Buffer
+	t.Require().NoError(WriteCatalogAsCSV(t.cat, &output))
+	t.Require().NoError(os.WriteFile(filepath.Join(dir, "catalog.csv"), output.Bytes(), 0644))
+
+	cat, err := NewCatalogFromCSVDir(dir)
+	t.Require().NoError(err)
+	t.checkCatalog(cat)
+}
+
+func (t *ExportTestSuite) TestWriteCatalogAsXLSX() {
+	filePath := filepath.Join(t.T().TempDir(), "catalog.xlsx")
+	var output bytes.Buffer
+	t.Require().NoError(WriteCatalogAsXLSX(t.cat, &output))
+	t.Require().NoError(os.WriteFile(filePath, output.Bytes(), 0644))
+
+	book, err := readXLSXWorkbook(filePath)
+	t.Require().NoError(err)
+	t.Equal([]string{"WOL", "CORE", "Series"}, book.names)
+
+	cat, err := NewCatalogFromXLSX(filePath)
+	t.Require().NoError(err)
+	t.checkCatalog(cat)
+}
+
+func (t *ExportTestSuite) TestWriteCatalogAsXLSX_RoundTrip() {
+	expected, err := catalog.NewCatalogFromJSON("../testdata/small-catalog.json")
+	t.Require().NoError(err)
+
+	filePath := filepath.Join(t.T().TempDir(), "catalog.xlsx")
+	var output bytes.Buffer
+	t.Require().NoError(WriteCatalogAsXLSX(expected, &output))
+	t.Require().NoError(os.WriteFile(filePath, output.Bytes(), 0644))
+
+	// the series read back in are the same, so their IDs and pages don't change
+	cat, err := NewCatalogFromXLSX(filePath)
+	t.Require().NoError(err)
+	for index := range cat.Series {
+		if len(cat.Series[index].Booklets) == 0 {
+			cat.Series[index].Booklets = nil
+		}
+	}
+	t.Equal(expected.Series, cat.Series)
+
+	// the messages are in a tab for each ministry, and the names of resources that are just a URL
+	// are made from the URL when they're read
+	getURL := func(resource *catalog.OnlineResource) string {
+		if resource == nil {
+			return ""
+		}
+		return resource.URL
+	}
+	t.Require().Len(cat.Messages, len(expected.Messages))
+	for _, want := range expected.Messages {
+		index := slices.IndexFunc(cat.Messages, func(msg catalog.CatalogMessage) bool {
+			return msg.Date == want.Date && msg.Name == want.Name
+		})
+		if !t.GreaterOrEqual(index, 0, want.Name) {
+			continue
+		}
+		msg := cat.Messages[index]
+		t.Equal(want.Description, msg.Description)
+		t.Equal(want.Speakers, msg.Speakers)
+		t.Equal(want.Tags, msg.Tags)
+		t.Equal(want.Ministry, msg.Ministry)
+		t.Equal(want.AlsoIn, msg.AlsoIn)
+		t.Equal(want.Type, msg.Type)
+		t.Equal(want.Visibility, msg.Visibility)
+		t.Equal(len(want.Series), len(msg.Series))
+		for seriIndex := range want.Series {
+			t.Equal(want.Series[seriIndex], msg.Series[seriIndex])
+		}
+		t.Equal(getURL(want.Thumb), getURL(msg.Thumb))
+		t.Equal(getURL(want.Audio), getURL(msg.Audio))
+		t.Equal(getURL(want.Video), getURL(msg.Video))
+		if t.Equal(len(want.Resources), len(msg.Resources)) {
+			for resourceIndex := range want.Resources {
+				t.Equal(want.Resources[resourceIndex].URL, msg.Resources[resourceIndex].URL)
+				t.Equal(want.Resources[resourceIndex].Name, msg.Resources[resourceIndex].Name)
+			}
+		}
+	}
+}
+
+func (t *ExportTestSuite) TestFormatResource() {
+	t.Equal("", formatResource(nil))
+	t.Equal("", formatResource(&catalog.OnlineResource{Name: "nothing"}))
+	t.Equal("https://example.com/a+file.pdf", formatResource(&catalog.OnlineResource{URL: "https://example.com/a+file.pdf", Name: "a file"}))
+	t.Equal("Notes|https://example.com/a.pdf", formatResource(&catalog.OnlineResource{URL: "https://example.com/a.pdf", Name: "Notes"}))
+	t.Equal(`https://rumble.com/v1 {"embed":"https://rumble.com/embed/v1","id":"v1"}`, formatResource(&catalog.OnlineResource{
+		URL:      "https://rumble.com/v1",
+		Name:     "v1",
+		Metadata: map[string]string{"id": "v1", "embed": "https://rumble.com/embed/v1"},
+	}))
+}
+
+func (t *ExportTestSuite) TestGetXLSXColumnName() {
+	for index, expected := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		t.Equal(expected, getXLSXColumnName(index))
+		column, err := getXLSXColumnIndex(expected + "1")
+		t.NoError(err)
+		t.Equal(index, column)
+	}
+}
@@ -0,0 +1,100 @@
+package gclient
+
+// the sources of spreadsheet data that a catalog can be read from: a Google Sheet, an exported
+// XLSX workbook, or a directory of CSV files with one file per tab
+
+import (
+	"fmt"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// workbook is a spreadsheet document made of tabs of rows. Cells are read as the values
+// displayed in the spreadsheet, so dates are strings like "2021-09-10"
+type workbook interface {
+	// GetTabNames gets the names of all the tabs, in the order they are in the document
+	GetTabNames() ([]string, error)
+
+	// GetRows gets the rows of a tab from firstRow to lastRow (1 is the first row). Rows past
+	// the end of the tab are not returned, and empty cells at the end of a row may be missing
+	GetRows(tabName string, firstRow int, lastRow int) ([][]any, error)
+}
+
+// +---------------------------------------------------------------------------
+// | Google Sheets
+// +---------------------------------------------------------------------------
+
+// sheetWorkbook is a Google Sheet read through the Sheets API
+type sheetWorkbook struct {
+	service    *sheets.Service     // service to read the sheet with
+	documentID string              // ID of the spreadsheet document
+	document   *sheets.Spreadsheet // information about the document, nil until it's needed
+}
+
+// GetTabNames gets the names of the sheets in the document
+func (b *sheetWorkbook) GetTabNames() ([]string, error) {
+	if b.document == nil {
+		document, err := b.service.Spreadsheets.Get(b.documentID).Do()
+		if err != nil {
+			return nil, err
+		}
+		b.document = document
+	}
+
+	var names []string
+	for _, sheet := range b.document.Sheets {
+		names = append(names, sheet.Properties.Title)
+	}
+	return names, nil
+}
+
+// GetRows gets a range of rows of a sheet
+func (b *sheetWorkbook) GetRows(tabName string, firstRow int, lastRow int) ([][]any, error) {
+	values, err := b.service.Spreadsheets.Values.Get(b.documentID, fmt.Sprintf("'%s'!%d:%d", tabName, firstRow, lastRow)).Do()
+	if err != nil {
+		return nil, err
+	}
+	return values.Values, nil
+}
+
+// +---------------------------------------------------------------------------
+// | Workbooks read from files
+// +---------------------------------------------------------------------------
+
+// fileWorkbook is a workbook whose tabs have all been read into memory
+type fileWorkbook struct {
+	path  string             // file or directory the workbook was read from
+	names []string           // names of the tabs, in order
+	tabs  map[string][][]any // rows of each tab, by name
+}
+
+// GetTabNames gets the names of the tabs of the workbook
+func (b *fileWorkbook) GetTabNames() ([]string, error) {
+	return b.names, nil
+}
+
+// GetRows gets a range of rows of a tab
+func (b *fileWorkbook) GetRows(tabName string, firstRow int, lastRow int) ([][]any, error) {
+	rows, ok := b.tabs[tabName]
+	if !ok {
+		return nil, fmt.Errorf("%s has no tab '%s'", b.path, tabName)
+	}
+	if firstRow < 1 || lastRow < firstRow {
+		return nil, fmt.Errorf("rows %d to %d of tab '%s' are not a valid range", firstRow, lastRow, tabName)
+	}
+
+	if firstRow > len(rows) {
+		return nil, nil
+	}
+	return rows[firstRow-1 : min(lastRow, len(rows))], nil
+}
+
+// addTab adds a tab to the workbook
+func (b *fileWorkbook) addTab(name string, rows [][]any) error {
+	if _, ok := b.tabs[name]; ok {
+		return fmt.Errorf("%s has more than one tab named '%s'", b.path, name)
+	}
+	b.names = append(b.names, name)
+	b.tabs[name] = rows
+	return nil
+}
@@ -0,0 +1,233 @@
+package gclient
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/WordOfLifeMN/online/catalog"
+	"github.com/WordOfLifeMN/online/util"
+	"github.com/stretchr/testify/suite"
+)
+
+// Runs the test suite as a test
+func TestWorkbookTestSuite(t *testing.T) {
+	suite.Run(t, new(WorkbookTestSuite))
+}
+
+type WorkbookTestSuite struct {
+	suite.Suite
+}
+
+// writeFiles writes files with the given names and contents to a new directory
+func (t *WorkbookTestSuite) writeFiles(files map[string]string) string {
+	dir := t.T().TempDir()
+	for name, content := range files {
+		t.Require().NoError(os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+// writeXLSX writes an XLSX file made of the given parts
+func (t *WorkbookTestSuite) writeXLSX(parts map[string]string) string {
+	filePath := filepath.Join(t.T().TempDir(), "catalog.xlsx")
+	file, err := os.Create(filePath)
+	t.Require().NoError(err)
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	for name, content := range parts {
+		part, err := archive.Create(name)
+		t.Require().NoError(err)
+		_, err = part.Write([]byte(content))
+		t.Require().NoError(err)
+	}
+	t.Require().NoError(archive.Close())
+	return filePath
+}
+
+// checkCatalog checks a catalog read from the test workbook, which has a message tab for
+// Word of Life and for CORE, a Series tab, and a tab that should be ignored
+func (t *WorkbookTestSuite) checkCatalog(cat *catalog.Catalog) {
+	if t.Len(cat.Messages, 2) {
+		t.Equal("Faith 1", cat.Messages[0].Name)
+		t.Equal(catalog.MustParseDateOnly("2021-09-12"), cat.Messages[0].Date)
+		t.Equal([]string{"Vern Peltz", "Mary Peltz"}, cat.Messages[0].Speakers)
+		t.Equal(catalog.CenterOfRelationshipExperience, cat.Messages[0].Ministry)
+		t.Equal([]catalog.SeriesReference{{Name: "Faith", Index: 1}}, cat.Messages[0].Series)
+
+		t.Equal("Hope", cat.Messages[1].Name)
+		t.Equal(catalog.MustParseDateOnly("2020-01-05"), cat.Messages[1].Date)
+		t.Equal(catalog.WordOfLife, cat.Messages[1].Ministry)
+		t.Equal(catalog.Partner, cat.Messages[1].Visibility)
+	}
+
+	// the Series row becomes a series, and the Series tab only adds what's missing
+	if t.Len(cat.Series, 2) {
+		t.Equal("Faith", cat.Series[0].Name)
+		t.Equal(util.ComputeHash("Faith"), cat.Series[0].ID)
+		t.Equal(catalog.CenterOfRelationshipExperience, cat.Series[0].Ministry)
+		t.Equal("Old", cat.Series[1].Name)
+		t.Equal("S1", cat.Series[1].ID)
+		t.Equal(catalog.MustParseDateOnly("2018-03-04"), cat.Series[1].StartDate)
+	}
+}
+
+const workbookMessageColumns = "Date,Name,Description,Speaker,Ministry,Type,Visibility,Series Name,Track,Audio,Video,Resources"
+const workbookSeriesColumns = "ID,Name,Description,Date Started,Date Ended,Visibility,Booklets,CD Jacket,DVD Jacket,Cover Art"
+
+func (t *WorkbookTestSuite) TestNewCatalogFromCSVDir() {
+	dir := t.writeFiles(map[string]string{
+		"Catalog - CORE.csv": "\ufeff" + workbookMessageColumns + "\n" +
+			",Faith,Believing God,,,Series,Public,,,,,\n" +
+			"2021-09-12,Faith 1,,Vern Peltz;Mary Peltz,,Message,Public,Faith,1,,,\n",
+		"Catalog - WOL.csv": workbookMessageColumns + "\n" +
+			"2020-01-05,Hope,\"Hope, and more\",Vern Peltz,,Message,Partner,,,,,\n",
+		"Catalog - Series.csv": workbookSeriesColumns + "\n" +
+			"S9,Faith,,,,Public,,,,\n" +
+			"S1,Old,,2018-03-04,2018-04-01,Public,,,,\n",
+		"Catalog - _Notes.csv": "Anything,At All\n",
+		"README.txt":           "not a tab",
+	})
+
+	cat, err := NewCatalogFromCSVDir(dir)
+	t.Require().NoError(err)
+	t.checkCatalog(cat)
+	t.Equal("Hope, and more", cat.Messages[1].Description)
+}
+
+func (t *WorkbookTestSuite) TestNewCatalogFromCSVDir_Errors() {
+	_, err := NewCatalogFromCSVDir(t.writeFiles(map[string]string{"README.txt": "not a tab"}))
+	t.Error(err)
+
+	_, err = NewCatalogFromCSVDir(t.writeFiles(map[string]string{
+		"WOL.csv":           workbookMessageColumns + "\n",
+		"Catalog - WOL.csv": workbookMessageColumns + "\n",
+	}))
+	if t.Error(err) {
+		t.Contains(err.Error(), "'WOL'")
+	}
+
+	_, err = NewCatalogFromCSVDir(filepath.Join(t.T().TempDir(), "missing"))
+	t.Error(err)
+}
+
+func (t *WorkbookTestSuite) TestNewCatalogFromXLSX() {
+	// a sheet in the style Excel writes: shared strings and dates as numbers
+	messageTitles := `<row r="1">`
+	for index, title := range []string{"Date", "Name", "Description", "Speaker", "Ministry", "Type",
+		"Visibility", "Series Name", "Track", "Audio", "Video", "Resources"} {
+		messageTitles += `<c r="` + string(rune('A'+index)) + `1" t="inlineStr"><is><t>` + title + `</t></is></c>`
+	}
+	messageTitles += `</row>`
+
+	filePath := t.writeXLSX(map[string]string{
+		"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets>
+<sheet name="CORE" sheetId="3" r:id="rId3"/>
+<sheet name="_Notes" sheetId="2" r:id="rId2"/>
+<sheet name="WOL" sheetId="1" r:id="rId1"/>
+<sheet name="Series" sheetId="4" r:id="rId4"/>
+</sheets>
+</workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Target="worksheets/sheet2.xml"/>
+<Relationship Id="rId3" Target="/xl/worksheets/sheet3.xml"/>
+<Relationship Id="rId4" Target="worksheets/sheet4.xml"/>
+<Relationship Id="rId5" Target="styles.xml"/>
+</Relationships>`,
+		"xl/sharedStrings.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>Message</t></si>
+<si><r><t>Vern </t></r><r><rPr><b/></rPr><t>Peltz</t></r></si>
+<si><t>Public</t></si>
+</sst>`,
+		"xl/styles.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts><numFmt numFmtId="164" formatCode="yyyy\-mm\-dd"/><numFmt numFmtId="165" formatCode="&quot;day &quot;0"/></numFmts>
+<cellXfs><xf numFmtId="0"/><xf numFmtId="164"/><xf numFmtId="14"/><xf numFmtId="165"/></cellXfs>
+</styleSheet>`,
+		"xl/worksheets/sheet1.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			messageTitles +
+			`<row r="3"><c r="A3" s="2"><v>43835</v></c><c r="B3" t="inlineStr"><is><t>Hope</t></is></c>` +
+			`<c r="D3" t="s"><v>1</v></c><c r="F3" t="s"><v>0</v></c><c r="G3" t="inlineStr"><is><t>Partner</t></is></c></row>` +
+			`</sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="inlineStr"><is><t>Anything</t></is></c></row>
+</sheetData></worksheet>`,
+		"xl/worksheets/sheet3.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			messageTitles +
+			`<row r="2"><c r="B2" t="inlineStr"><is><t>Faith</t></is></c><c r="F2" t="inlineStr"><is><t>Series</t></is></c>` +
+			`<c r="G2" t="s"><v>2</v></c></row>` +
+			`<row r="3"><c r="A3" s="1"><v>44451</v></c><c r="B3" t="str"><v>Faith 1</v></c>` +
+			`<c r="D3" t="inlineStr"><is><t>Vern Peltz;Mary Peltz</t></is></c><c r="F3" t="s"><v>0</v></c>` +
+			`<c r="G3" t="s"><v>2</v></c><c r="H3" t="inlineStr"><is><t>Faith</t></is></c><c r="I3" s="3"><v>1</v></c></row>` +
+			`</sheetData></worksheet>`,
+		"xl/worksheets/sheet4.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="inlineStr"><is><t>ID</t></is></c><c r="B1" t="inlineStr"><is><t>Name</t></is></c>` +
+			`<c r="C1" t="inlineStr"><is><t>Description</t></is></c><c r="D1" t="inlineStr"><is><t>Date Started</t></is></c>` +
+			`<c r="E1" t="inlineStr"><is><t>Date Ended</t></is></c><c r="F1" t="inlineStr"><is><t>Visibility</t></is></c>` +
+			`<c r="G1" t="inlineStr"><is><t>Booklets</t></is></c><c r="H1" t="inlineStr"><is><t>CD Jacket</t></is></c>` +
+			`<c r="I1" t="inlineStr"><is><t>DVD Jacket</t></is></c><c r="J1" t="inlineStr"><is><t>Cover Art</t></is></c></row>
+<row r="2"><c r="A2" t="inlineStr"><is><t>S9</t></is></c><c r="B2" t="inlineStr"><is><t>Faith</t></is></c></row>
+<row r="3"><c r="A3" t="inlineStr"><is><t>S1</t></is></c><c r="B3" t="inlineStr"><is><t>Old</t></is></c><c r="D3" s="1"><v>43163</v></c></row>
+</sheetData></worksheet>`,
+	})
+
+	cat, err := NewCatalogFromXLSX(filePath)
+	t.Require().NoError(err)
+	t.checkCatalog(cat)
+	t.Equal([]string{"Vern Peltz"}, cat.Messages[1].Speakers)
+}
+
+func (t *WorkbookTestSuite) TestNewCatalogFromXLSX_Errors() {
+	filePath := filepath.Join(t.T().TempDir(), "catalog.xlsx")
+	t.Require().NoError(os.WriteFile(filePath, []byte("not a zip"), 0644))
+	_, err := NewCatalogFromXLSX(filePath)
+	t.Error(err)
+
+	_, err = NewCatalogFromXLSX(t.writeXLSX(map[string]string{"xl/styles.xml": "<styleSheet/>"}))
+	if t.Error(err) {
+		t.Contains(err.Error(), "xl/workbook.xml")
+	}
+}
+
+func (t *WorkbookTestSuite) TestFileWorkbookGetRows() {
+	book := &fileWorkbook{path: "test", tabs: map[string][][]any{}}
+	t.NoError(book.addTab("A", [][]any{{"1"}, {"2"}, {"3"}}))
+	t.Error(book.addTab("A", nil))
+
+	names, err := book.GetTabNames()
+	t.NoError(err)
+	t.Equal([]string{"A"}, names)
+
+	rows, err := book.GetRows("A", 2, 80000)
+	t.NoError(err)
+	t.Equal([][]any{{"2"}, {"3"}}, rows)
+	rows, err = book.GetRows("A", 4, 4)
+	t.NoError(err)
+	t.Empty(rows)
+
+	_, err = book.GetRows("B", 1, 1)
+	t.Error(err)
+	_, err = book.GetRows("A", 0, 1)
+	t.Error(err)
+}
+
+func (t *WorkbookTestSuite) TestGetXLSXColumnIndex() {
+	for ref, expected := range map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "AB3": 27} {
+		index, err := getXLSXColumnIndex(ref)
+		t.NoError(err)
+		t.Equal(expected, index, ref)
+	}
+	_, err := getXLSXColumnIndex("12")
+	t.Error(err)
+}
@@ -0,0 +1,306 @@
+package gclient
+
+// code that reads a catalog from an XLSX workbook, like one downloaded from Google Sheets. An
+// XLSX file is a zip of XML files (ECMA-376), and only the parts needed to read the values of
+// the cells are read: the list of sheets, the shared strings, the number formats (to find
+// dates), and the cells of each sheet
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WordOfLifeMN/online/catalog"
+)
+
+// NewCatalogFromXLSX creates a catalog from an XLSX workbook that has the same tabs and columns
+// as the Google Sheet
+func NewCatalogFromXLSX(filePath string) (*catalog.Catalog, error) {
+	book, err := readXLSXWorkbook(filePath)
+	if err != nil {
+		return &catalog.Catalog{}, err
+	}
+
+	log.Printf("Reading catalog from workbook %s", filePath)
+	return newCatalogFromWorkbook(book)
+}
+
+// +---------------------------------------------------------------------------
+// | XML of the parts of the workbook
+// +---------------------------------------------------------------------------
+
+// xlsxWorkbookXML is xl/workbook.xml, which lists the sheets
+type xlsxWorkbookXML struct {
+	Properties struct {
+		Date1904 bool `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// xlsxRelationshipsXML is xl/_rels/workbook.xml.rels, which has the file of each sheet
+type xlsxRelationshipsXML struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxTextXML is text that may be split into runs with different formatting
+type xlsxTextXML struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+// xlsxSharedStringsXML is xl/sharedStrings.xml, which has the text of the string cells
+type xlsxSharedStringsXML struct {
+	Items []xlsxTextXML `xml:"si"`
+}
+
+// xlsxStylesXML is xl/styles.xml, which has the number format of each cell style
+type xlsxStylesXML struct {
+	NumberFormats []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellFormats []struct {
+		NumberFormatID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+// xlsxSheetXML is a sheet, like xl/worksheets/sheet1.xml
+type xlsxSheetXML struct {
+	Rows []struct {
+		Number int `xml:"r,attr"`
+		Cells  []struct {
+			Ref    string      `xml:"r,attr"`
+			Type   string      `xml:"t,attr"`
+			Style  int         `xml:"s,attr"`
+			Value  string      `xml:"v"`
+			Inline xlsxTextXML `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// String gets all the text
+func (x xlsxTextXML) String() string {
+	if len(x.Runs) == 0 {
+		return x.Text
+	}
+	var text strings.Builder
+	for _, run := range x.Runs {
+		text.WriteString(run.Text)
+	}
+	return text.String()
+}
+
+// +---------------------------------------------------------------------------
+// | Reading
+// +---------------------------------------------------------------------------
+
+// readXLSXWorkbook reads all the sheets of an XLSX file as the tabs of a workbook. Cells are
+// read as text, and numbers with a date format are converted to dates like "2021-09-10"
+func readXLSXWorkbook(filePath string) (*fileWorkbook, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open workbook %s: %w", filePath, err)
+	}
+	defer archive.Close()
+
+	var workbookXML xlsxWorkbookXML
+	if err := readXLSXPart(&archive.Reader, "xl/workbook.xml", &workbookXML, false); err != nil {
+		return nil, fmt.Errorf("cannot read workbook %s: %w", filePath, err)
+	}
+	var relationshipsXML xlsxRelationshipsXML
+	if err := readXLSXPart(&archive.Reader, "xl/_rels/workbook.xml.rels", &relationshipsXML, false); err != nil {
+		return nil, fmt.Errorf("cannot read workbook %s: %w", filePath, err)
+	}
+	var sharedStringsXML xlsxSharedStringsXML
+	if err := readXLSXPart(&archive.Reader, "xl/sharedStrings.xml", &sharedStringsXML, true); err != nil {
+		return nil, fmt.Errorf("cannot read workbook %s: %w", filePath, err)
+	}
+	var stylesXML xlsxStylesXML
+	if err := readXLSXPart(&archive.Reader, "xl/styles.xml", &stylesXML, true); err != nil {
+		return nil, fmt.Errorf("cannot read workbook %s: %w", filePath, err)
+	}
+
+	// prepare to convert cells to text
+	sharedStrings := make([]string, 0, len(sharedStringsXML.Items))
+	for _, item := range sharedStringsXML.Items {
+		sharedStrings = append(sharedStrings, item.String())
+	}
+	dateStyles := getXLSXDateStyles(stylesXML)
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if workbookXML.Properties.Date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	targets := map[string]string{}
+	for _, relationship := range relationshipsXML.Relationships {
+		target := relationship.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join("xl", target)
+		}
+		targets[relationship.ID] = target
+	}
+
+	book := &fileWorkbook{path: filePath, tabs: map[string][][]any{}}
+	for _, sheet := range workbookXML.Sheets {
+		target, ok := targets[sheet.RID]
+		if !ok {
+			return nil, fmt.Errorf("cannot find sheet '%s' in workbook %s", sheet.Name, filePath)
+		}
+
+		var sheetXML xlsxSheetXML
+		if err := readXLSXPart(&archive.Reader, target, &sheetXML, false); err != nil {
+			return nil, fmt.Errorf("cannot read sheet '%s' of workbook %s: %w", sheet.Name, filePath, err)
+		}
+
+		var rows [][]any
+		for _, rowXML := range sheetXML.Rows {
+			// empty rows are left out of the file, but not out of the tab
+			rowNumber := rowXML.Number
+			if rowNumber == 0 {
+				rowNumber = len(rows) + 1
+			}
+			for len(rows) < rowNumber {
+				rows = append(rows, []any{})
+			}
+
+			row := []any{}
+			for _, cell := range rowXML.Cells {
+				column := len(row)
+				if cell.Ref != "" {
+					if column, err = getXLSXColumnIndex(cell.Ref); err != nil {
+						return nil, fmt.Errorf("cannot read sheet '%s' of workbook %s: %w", sheet.Name, filePath, err)
+					}
+				}
+				for len(row) < column {
+					row = append(row, "")
+				}
+
+				var value string
+				switch cell.Type {
+				case "s":
+					index, err := strconv.Atoi(cell.Value)
+					if err != nil || index < 0 || index >= len(sharedStrings) {
+						return nil, fmt.Errorf("cell %s of sheet '%s' has an unknown string '%s'", cell.Ref, sheet.Name, cell.Value)
+					}
+					value = sharedStrings[index]
+				case "inlineStr":
+					value = cell.Inline.String()
+				case "b":
+					value = map[string]string{"1": "TRUE", "0": "FALSE"}[cell.Value]
+				case "", "n":
+					value = cell.Value
+					if dateStyles[cell.Style] {
+						if serial, err := strconv.ParseFloat(cell.Value, 64); err == nil {
+							value = epoch.AddDate(0, 0, int(serial)).Format("2006-01-02")
+						}
+					}
+				default:
+					// formula strings, errors, and ISO dates are already text
+					value = cell.Value
+				}
+				row = append(row, value)
+			}
+			rows[rowNumber-1] = row
+		}
+
+		if err := book.addTab(sheet.Name, rows); err != nil {
+			return nil, err
+		}
+	}
+
+	return book, nil
+}
+
+// readXLSXPart reads and parses one of the XML files in an XLSX file. Some parts are optional,
+// and if they are missing then the value is left empty
+func readXLSXPart(archive *zip.Reader, name string, value any, optional bool) error {
+	file, err := archive.Open(name)
+	if err != nil {
+		if optional {
+			return nil
+		}
+		return fmt.Errorf("cannot find %s: %w", name, err)
+	}
+	defer file.Close()
+
+	bytes, err := io.ReadAll(file)
+	if err != nil {
+		return fmt.Errorf("cannot read %s: %w", name, err)
+	}
+	if err := xml.Unmarshal(bytes, value); err != nil {
+		return fmt.Errorf("cannot parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// getXLSXDateStyles finds the cell styles that display numbers as dates. These are the styles
+// with one of the built-in date formats, or with a custom format that has a year or a day in it
+func getXLSXDateStyles(stylesXML xlsxStylesXML) map[int]bool {
+	dateFormats := map[int]bool{}
+	for id := 14; id <= 17; id++ {
+		dateFormats[id] = true
+	}
+	dateFormats[22] = true
+	for _, format := range stylesXML.NumberFormats {
+		// ignore quoted text, escaped characters, and [colors] or [conditions]
+		code := strings.ToLower(format.Code)
+		var visible strings.Builder
+		quoted, bracketed := false, false
+		for index := 0; index < len(code); index++ {
+			switch char := code[index]; {
+			case char == '"':
+				quoted = !quoted
+			case quoted:
+			case char == '[':
+				bracketed = true
+			case char == ']':
+				bracketed = false
+			case bracketed:
+			case char == '\\':
+				index++
+			default:
+				visible.WriteByte(char)
+			}
+		}
+		dateFormats[format.ID] = strings.ContainsAny(visible.String(), "yd")
+	}
+
+	dateStyles := map[int]bool{}
+	for style, format := range stylesXML.CellFormats {
+		dateStyles[style] = dateFormats[format.NumberFormatID]
+	}
+	return dateStyles
+}
+
+// getXLSXColumnIndex gets the index of the column (0 for A) of a cell reference like "AB12"
+func getXLSXColumnIndex(ref string) (int, error) {
+	column := 0
+	letters := 0
+	for _, char := range strings.ToUpper(ref) {
+		if char < 'A' || char > 'Z' {
+			break
+		}
+		column = column*26 + int(char-'A'+1)
+		letters++
+	}
+	if letters == 0 {
+		return 0, fmt.Errorf("'%s' is not a cell reference", ref)
+	}
+	return column - 1, nil
+}
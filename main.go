@@ -1,6 +1,11 @@
 package main
 
-import "github.com/WordOfLifeMN/online/cmd"
+import (
+	// the time zones are built in, since Windows doesn't have them unless Go is installed
+	_ "time/tzdata"
+
+	"github.com/WordOfLifeMN/online/cmd"
+)
 
 func main() {
 	cmd.Execute()
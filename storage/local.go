@@ -0,0 +1,129 @@
+package storage
+
+// storage in a directory of the local file system
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// dirStorage keeps objects as files in a directory. The key of an object is its path relative to
+// the directory. The options of an object aren't kept
+type dirStorage struct {
+	dir string // directory the objects are in
+}
+
+// newDirStorage creates a storage in a directory. The directory is created when the first object
+// is put in it
+func newDirStorage(dir string) *dirStorage {
+	return &dirStorage{dir: dir}
+}
+
+// getFilePath gets the path of the file of an object
+func (s *dirStorage) getFilePath(key string) (string, error) {
+	filePath := filepath.Join(s.dir, filepath.FromSlash(key))
+	if key == "" || !strings.HasPrefix(filePath, filepath.Clean(s.dir)+string(filepath.Separator)) {
+		return "", fmt.Errorf("key '%s' is not in the storage directory %s", key, s.dir)
+	}
+	return filePath, nil
+}
+
+// List gets all the files whose keys start with the prefix
+func (s *dirStorage) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	err := filepath.WalkDir(s.dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && filePath == s.dir {
+				// nothing has been stored yet
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(s.dir, filePath)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(relPath)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{Key: key, Size: int64(len(content)), ETag: ComputeETag(content)})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list the files in %s: %w", s.dir, err)
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// Head gets the information about a file
+func (s *dirStorage) Head(ctx context.Context, key string) (Object, error) {
+	content, err := s.Get(ctx, key)
+	if err != nil {
+		return Object{}, err
+	}
+	return Object{Key: key, Size: int64(len(content)), ETag: ComputeETag(content)}, nil
+}
+
+// Get reads the contents of a file
+func (s *dirStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	filePath, err := s.getFilePath(key)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("cannot read %s: %w", filePath, ErrNotExist)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", filePath, err)
+	}
+	return content, nil
+}
+
+// Put writes the contents of a file
+func (s *dirStorage) Put(ctx context.Context, key string, content []byte, options PutOptions) error {
+	filePath, err := s.getFilePath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), os.FileMode(0777)); err != nil {
+		return fmt.Errorf("cannot create directory for %s: %w", filePath, err)
+	}
+	if err := os.WriteFile(filePath, content, os.FileMode(0666)); err != nil {
+		return fmt.Errorf("cannot write %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// Delete removes a file, and its directory if that leaves it empty
+func (s *dirStorage) Delete(ctx context.Context, key string) error {
+	filePath, err := s.getFilePath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot remove %s: %w", filePath, err)
+	}
+	if dir := filepath.Dir(filePath); dir != filepath.Clean(s.dir) {
+		// fails if there are still files in it, which is fine
+		os.Remove(dir)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestDirStorageTestSuite(t *testing.T) {
+	suite.Run(t, new(DirStorageTestSuite))
+}
+
+type DirStorageTestSuite struct {
+	suite.Suite
+}
+
+func (t *DirStorageTestSuite) TestPutListDelete() {
+	dir := filepath.Join(t.T().TempDir(), "online")
+	store, err := Open(dir, Options{})
+	t.Require().NoError(err)
+	ctx := context.Background()
+
+	// nothing there yet
+	objects, err := store.List(ctx, "")
+	t.NoError(err)
+	t.Empty(objects)
+
+	t.Require().NoError(store.Put(ctx, "catalog.html", []byte("<html/>"), PutOptions{ContentType: "text/html"}))
+	t.Require().NoError(store.Put(ctx, "static/img/logo.png", []byte("png"), PutOptions{}))
+	t.FileExists(filepath.Join(dir, "static", "img", "logo.png"))
+
+	objects, err = store.List(ctx, "")
+	t.Require().NoError(err)
+	t.Equal([]Object{
+		{Key: "catalog.html", Size: 7, ETag: ComputeETag([]byte("<html/>"))},
+		{Key: "static/img/logo.png", Size: 3, ETag: ComputeETag([]byte("png"))},
+	}, objects)
+
+	object, err := store.Head(ctx, "static/img/logo.png")
+	t.Require().NoError(err)
+	t.Equal(Object{Key: "static/img/logo.png", Size: 3, ETag: ComputeETag([]byte("png"))}, object)
+	content, err := store.Get(ctx, "catalog.html")
+	t.Require().NoError(err)
+	t.Equal("<html/>", string(content))
+	_, err = store.Get(ctx, "missing.html")
+	t.ErrorIs(err, ErrNotExist)
+	_, err = store.Head(ctx, "missing.html")
+	t.ErrorIs(err, ErrNotExist)
+
+	objects, err = store.List(ctx, "static/")
+	t.Require().NoError(err)
+	t.Len(objects, 1)
+
+	// empty directories are cleaned up
+	t.NoError(store.Delete(ctx, "static/img/logo.png"))
+	t.NoError(store.Delete(ctx, "static/img/logo.png"))
+	t.NoDirExists(filepath.Join(dir, "static", "img"))
+	t.FileExists(filepath.Join(dir, "catalog.html"))
+}
+
+func (t *DirStorageTestSuite) TestKeyOutsideDir() {
+	dir := t.T().TempDir()
+	store := newDirStorage(filepath.Join(dir, "online"))
+
+	t.Error(store.Put(context.Background(), "../escaped.html", []byte("x"), PutOptions{}))
+	t.NoFileExists(filepath.Join(dir, "escaped.html"))
+	t.Error(store.Delete(context.Background(), ""))
+
+	_, err := os.Stat(filepath.Join(dir, "online"))
+	t.True(os.IsNotExist(err))
+}
+
+func (t *DirStorageTestSuite) TestComputeETag() {
+	// same as S3 gives an object uploaded in one part
+	t.Equal("d41d8cd98f00b204e9800998ecf8427e", ComputeETag(nil))
+	t.Equal("5d41402abc4b2a76b9719d911017c592", ComputeETag([]byte("hello")))
+}
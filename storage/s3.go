@@ -0,0 +1,432 @@
+package storage
+
+// storage in an S3 bucket, or a service that works like S3. This talks to the S3 REST API
+// directly and signs the requests with AWS signature version 4
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// region of the buckets when none is specified
+	defaultS3Region = "us-west-2"
+
+	// format of the time stamps in signed requests
+	amzDateFormat = "20060102T150405Z"
+)
+
+// awsCredentials are the keys that requests are signed with
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // only for temporary credentials
+}
+
+// s3Storage keeps objects in an S3 bucket
+type s3Storage struct {
+	client      *http.Client
+	endpoint    *url.URL       // URL of the S3 service. buckets are in the path, since ours have dots in them
+	region      string         // region of the bucket
+	bucket      string         // name of the bucket
+	prefix      string         // prefix of all the keys in the bucket, "" or ending with "/"
+	credentials awsCredentials // credentials to sign the requests with
+	now         func() time.Time
+}
+
+// newS3Storage creates a storage for a bucket. The AWS credentials are the same ones the AWS CLI
+// uses, from the environment or the credentials file
+func newS3Storage(bucket string, prefix string, options Options) (*s3Storage, error) {
+	credentials, err := getAWSCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	region := options.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+	endpoint := options.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Host == "" {
+		return nil, fmt.Errorf("S3 endpoint '%s' is not a URL", endpoint)
+	}
+
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	return &s3Storage{
+		client:      http.DefaultClient,
+		endpoint:    endpointURL,
+		region:      region,
+		bucket:      bucket,
+		prefix:      prefix,
+		credentials: credentials,
+		now:         time.Now,
+	}, nil
+}
+
+// getAWSCredentials gets the AWS credentials from the environment, or else from the profile in
+// the AWS credentials file
+func getAWSCredentials() (awsCredentials, error) {
+	credentials := awsCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if credentials.AccessKeyID != "" && credentials.SecretAccessKey != "" {
+		return credentials, nil
+	}
+
+	filePath := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if filePath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return credentials, fmt.Errorf("cannot find the AWS credentials: %w", err)
+		}
+		filePath = filepath.Join(home, ".aws", "credentials")
+	}
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return credentials, fmt.Errorf("no AWS credentials in the environment, and cannot read %s: %w", filePath, err)
+	}
+	defer file.Close()
+	credentials, err = readAWSCredentials(file, profile)
+	if err != nil {
+		return credentials, fmt.Errorf("cannot read AWS credentials from %s: %w", filePath, err)
+	}
+	return credentials, nil
+}
+
+// readAWSCredentials reads the credentials of a profile from an AWS credentials file
+func readAWSCredentials(reader io.Reader, profile string) (awsCredentials, error) {
+	var credentials awsCredentials
+	section := ""
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if section != profile {
+			continue
+		}
+
+		name, value, _ := strings.Cut(line, "=")
+		switch strings.TrimSpace(name) {
+		case "aws_access_key_id":
+			credentials.AccessKeyID = strings.TrimSpace(value)
+		case "aws_secret_access_key":
+			credentials.SecretAccessKey = strings.TrimSpace(value)
+		case "aws_session_token":
+			credentials.SessionToken = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return credentials, err
+	}
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return credentials, fmt.Errorf("no credentials for profile '%s'", profile)
+	}
+	return credentials, nil
+}
+
+// +-----------------------------------------------------------------------
+// | Storage interface
+// +-----------------------------------------------------------------------
+
+// xmlListBucketResult is the response of the ListObjectsV2 request
+type xmlListBucketResult struct {
+	IsTruncated           bool
+	NextContinuationToken string
+	Contents              []struct {
+		Key  string
+		ETag string
+		Size int64
+	}
+}
+
+// List gets all the objects in the bucket whose keys start with the prefix
+func (s *s3Storage) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", s.prefix+prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		body, err := s.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list the objects in s3://%s/%s: %w", s.bucket, s.prefix+prefix, err)
+		}
+		var result xmlListBucketResult
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("cannot read the list of objects in s3://%s/%s: %w", s.bucket, s.prefix+prefix, err)
+		}
+
+		for _, content := range result.Contents {
+			if strings.HasSuffix(content.Key, "/") {
+				// folder created in the console
+				continue
+			}
+			objects = append(objects, Object{
+				Key:  strings.TrimPrefix(content.Key, s.prefix),
+				Size: content.Size,
+				ETag: strings.Trim(content.ETag, `"`),
+			})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// Head gets the information about an object in the bucket
+func (s *s3Storage) Head(ctx context.Context, key string) (Object, error) {
+	response, _, err := s.send(ctx, http.MethodHead, key, nil, nil, nil)
+	if err != nil {
+		return Object{}, fmt.Errorf("cannot get s3://%s/%s: %w", s.bucket, s.prefix+key, err)
+	}
+	return Object{
+		Key:  key,
+		Size: response.ContentLength,
+		ETag: strings.Trim(response.Header.Get("ETag"), `"`),
+	}, nil
+}
+
+// Get downloads the contents of an object
+func (s *s3Storage) Get(ctx context.Context, key string) ([]byte, error) {
+	_, body, err := s.send(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get s3://%s/%s: %w", s.bucket, s.prefix+key, err)
+	}
+	return body, nil
+}
+
+// Put uploads the contents of an object
+func (s *s3Storage) Put(ctx context.Context, key string, content []byte, options PutOptions) error {
+	sum := md5.Sum(content)
+	header := http.Header{}
+	header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	if options.ContentType != "" {
+		header.Set("Content-Type", options.ContentType)
+	}
+	if options.CacheControl != "" {
+		header.Set("Cache-Control", options.CacheControl)
+	}
+	if options.Public {
+		header.Set("X-Amz-Acl", "public-read")
+	}
+
+	if _, err := s.do(ctx, http.MethodPut, key, nil, header, content); err != nil {
+		return fmt.Errorf("cannot upload s3://%s/%s: %w", s.bucket, s.prefix+key, err)
+	}
+	return nil
+}
+
+// Delete removes an object from the bucket
+func (s *s3Storage) Delete(ctx context.Context, key string) error {
+	if _, err := s.do(ctx, http.MethodDelete, key, nil, nil, nil); err != nil {
+		return fmt.Errorf("cannot delete s3://%s/%s: %w", s.bucket, s.prefix+key, err)
+	}
+	return nil
+}
+
+// +-----------------------------------------------------------------------
+// | Requests
+// +-----------------------------------------------------------------------
+
+// xmlError is the body of a response that failed
+type xmlError struct {
+	Code    string
+	Message string
+}
+
+// do sends a signed request for an object, or for the bucket if the key is "", and gets the body
+// of the response
+func (s *s3Storage) do(ctx context.Context, method string, key string, query url.Values, header http.Header, content []byte) ([]byte, error) {
+	_, body, err := s.send(ctx, method, key, query, header, content)
+	return body, err
+}
+
+// send sends a signed request for an object, or for the bucket if the key is "", and gets the
+// response and its body. Objects that don't exist are ErrNotExist, except when deleting them
+func (s *s3Storage) send(ctx context.Context, method string, key string, query url.Values, header http.Header, content []byte) (*http.Response, []byte, error) {
+	objectPath := "/" + s.bucket + "/"
+	if key != "" {
+		objectPath += s.prefix + key
+	}
+	requestURL := *s.endpoint
+	requestURL.Path = strings.TrimSuffix(s.endpoint.Path, "/") + objectPath
+	requestURL.RawPath = strings.TrimSuffix(s.endpoint.EscapedPath(), "/") + uriEncode(objectPath, false)
+	requestURL.RawQuery = getCanonicalQuery(query)
+
+	request, err := http.NewRequestWithContext(ctx, method, requestURL.String(), bytes.NewReader(content))
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	payloadHash := sha256.Sum256(content)
+	signRequest(request, hex.EncodeToString(payloadHash[:]), s.credentials, s.region, s.now())
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if response.StatusCode == http.StatusNotFound && key != "" {
+		if method == http.MethodDelete {
+			return response, body, nil
+		}
+		return nil, nil, ErrNotExist
+	}
+	if response.StatusCode >= 300 {
+		var xmlErr xmlError
+		if xml.Unmarshal(body, &xmlErr) == nil && xmlErr.Code != "" {
+			return nil, nil, fmt.Errorf("%s (%s)", xmlErr.Message, xmlErr.Code)
+		}
+		return nil, nil, fmt.Errorf("%s", response.Status)
+	}
+	return response, body, nil
+}
+
+// signRequest adds the AWS signature version 4 headers to a request. The payload hash is the hex
+// SHA256 of the body of the request
+func signRequest(request *http.Request, payloadHash string, credentials awsCredentials, region string, now time.Time) {
+	amzDate := now.UTC().Format(amzDateFormat)
+	scope := amzDate[:8] + "/" + region + "/s3/aws4_request"
+
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if credentials.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	// the headers that are signed
+	headers := map[string]string{"host": request.URL.Host}
+	for name, values := range request.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || name == "content-md5" || name == "range" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		getCanonicalQuery(request.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), amzDate[:8])
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		credentials.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 computes the HMAC-SHA256 of the data with the key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// getCanonicalQuery gets the query string the way AWS signs it, sorted by name and with
+// everything but the unreserved characters escaped
+func getCanonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var params []string
+	for _, name := range names {
+		values := append([]string{}, query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			params = append(params, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// uriEncode escapes everything but the unreserved characters, and optionally the slashes
+func uriEncode(value string, encodeSlash bool) string {
+	var sb strings.Builder
+	for _, b := range []byte(value) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~':
+			sb.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,79 @@
+package storage
+
+// storage for the files that are published online, like the pages of the catalog. The files can be
+// kept in an S3 bucket (or anything that works like one), or in a directory for testing
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotExist is the error when an object is not in the storage
+var ErrNotExist = errors.New("object does not exist")
+
+// Object is a file in storage
+type Object struct {
+	Key  string // path of the object, with "/" between directories
+	Size int64  // size of the contents in bytes
+	ETag string // MD5 of the contents in hex, or something else for objects uploaded in parts
+}
+
+// PutOptions are the optional settings of an object that is put in storage
+type PutOptions struct {
+	ContentType  string // MIME type of the contents, "" for the default
+	CacheControl string // Cache-Control header to serve the object with, "" for none
+	Public       bool   // anyone can read the object
+}
+
+// Storage is somewhere objects can be stored
+type Storage interface {
+	// List gets all the objects whose keys start with the prefix, sorted by key
+	List(ctx context.Context, prefix string) ([]Object, error)
+
+	// Head gets the information about an object without its contents. The error wraps
+	// ErrNotExist if there is no object with the key
+	Head(ctx context.Context, key string) (Object, error)
+
+	// Get gets the contents of an object. The error wraps ErrNotExist if there is no object with
+	// the key
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Put stores the contents of an object, replacing it if it already exists
+	Put(ctx context.Context, key string, content []byte, options PutOptions) error
+
+	// Delete removes an object. It is not an error if the object doesn't exist
+	Delete(ctx context.Context, key string) error
+}
+
+// Options configures how the storage is reached
+type Options struct {
+	Endpoint string // URL of the S3 service, "" for AWS. used for S3-compatible services
+	Region   string // AWS region of the bucket
+}
+
+// Open gets the storage for a target, which is either "s3://bucket/prefix" for an S3 bucket (the
+// prefix is optional), or the path of a directory
+func Open(target string, options Options) (Storage, error) {
+	if rest, ok := strings.CutPrefix(target, "s3://"); ok {
+		bucket, prefix, _ := strings.Cut(rest, "/")
+		if bucket == "" {
+			return nil, fmt.Errorf("storage target %s has no bucket", target)
+		}
+		return newS3Storage(bucket, prefix, options)
+	}
+	if target == "" {
+		return nil, fmt.Errorf("no storage target specified")
+	}
+	return newDirStorage(strings.TrimPrefix(target, "file://")), nil
+}
+
+// ComputeETag computes the ETag of an object with the contents, which is what S3 gives an object
+// that was uploaded in one part
+func ComputeETag(content []byte) string {
+	sum := md5.Sum(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,116 @@
+// Searches the transcript index in searchIndexDir. See catalog/search.go for the layout of the
+// index files. A passage matches if it has all the words of the query
+
+// Get references to the DOM elements
+const searchInput = document.getElementById('searchInput');
+const resultsMessage = document.getElementById('resultsMessage');
+const searchResults = document.getElementById('searchResults');
+const maxResults = 50;
+let searchNumber = 0; // ignore the results of searches that were replaced by a newer one
+
+// Fetch an index file once and remember it
+const indexFiles = new Map();
+const fetchIndexFile = (name) => {
+    if (!indexFiles.has(name)) {
+        indexFiles.set(name, fetch(searchIndexDir + '/' + name).then(response => {
+            if (!response.ok) {
+                throw new Error('cannot read ' + name + ': ' + response.statusText);
+            }
+            return response.json();
+        }));
+    }
+    return indexFiles.get(name);
+};
+
+// Split text into words the same way catalog.TokenizeSearchText does
+const tokenize = (text, stopWords) => text
+    .toLowerCase()
+    .replace(/['’]/g, '')
+    .split(/[^\p{L}\p{N}]+/u)
+    .filter(word => [...word].length >= 2 && !stopWords.has(word));
+
+// Find the index file with a word, the same way catalog.GetSearchShard does (FNV-1a)
+const indexFileOf = (word, indexFileCount) => {
+    let hash = 0x811c9dc5;
+    for (const b of new TextEncoder().encode(word)) {
+        hash = Math.imul(hash ^ b, 0x01000193) >>> 0;
+    }
+    return 'index-' + String(hash % indexFileCount).padStart(2, '0') + '.json';
+};
+
+const escapeHTML = (s) => s.replace(/[&<>"']/g, c => ({'&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;'})[c]);
+
+// Escape text for HTML and mark the parts of it that match. The text is split before it's escaped,
+// so a search for "amp" doesn't match inside "&amp;"
+const highlightHTML = (text, highlight) => text.split(highlight)
+    .map((part, index) => index % 2 === 1 ? '<mark>' + escapeHTML(part) + '</mark>' : escapeHTML(part))
+    .join('');
+
+const formatTime = (seconds) => {
+    const s = Math.floor(seconds);
+    const h = Math.floor(s / 3600);
+    const m = Math.floor((s % 3600) / 60);
+    const mmss = String(m).padStart(h ? 2 : 1, '0') + ':' + String(s % 60).padStart(2, '0');
+    return h ? h + ':' + mmss : mmss;
+};
+
+// Main search function
+const searchTranscripts = async () => {
+    const thisSearch = ++searchNumber;
+    const meta = await fetchIndexFile('meta.json');
+    const words = [...new Set(tokenize(searchInput.value, new Set(meta['stop-words'])))];
+    if (words.length === 0) {
+        searchResults.innerHTML = '';
+        resultsMessage.textContent = '';
+        return;
+    }
+
+    // find the passages with all the words
+    const lists = await Promise.all(words.map(async word => {
+        const index = await fetchIndexFile(indexFileOf(word, meta['index-files']));
+        let passage = 0;
+        return (index[word] || []).map(delta => passage += delta);
+    }));
+    let passages = lists.reduce((found, list) => {
+        const inList = new Set(list);
+        return found.filter(passage => inList.has(passage));
+    });
+    const total = passages.length;
+    passages = passages.slice(0, maxResults);
+
+    // get the text of the passages
+    const perFile = meta['passages-per-file'];
+    const rows = await Promise.all(passages.map(async passage => {
+        const name = 'passages-' + String(Math.floor(passage / perFile)).padStart(3, '0') + '.json';
+        return (await fetchIndexFile(name))[passage % perFile];
+    }));
+    if (thisSearch !== searchNumber) {
+        return;
+    }
+
+    // show the results
+    const highlight = new RegExp('(' + words.join('|') + ')', 'giu');
+    searchResults.innerHTML = rows.map(([messageNumber, start, text]) => {
+        const msg = meta.messages[messageNumber];
+        const listen = (start >= 0 && msg.audio)
+            ? ' &bull; <a href="' + escapeHTML(msg.audio) + '#t=' + Math.floor(start) + '" target="wolmAudio">&#x25B6; listen at ' + formatTime(start) + '</a>'
+            : '';
+        return '<div style="margin-bottom: 16px;">' +
+            '<b><a href="' + escapeHTML(msg.page) + '">' + escapeHTML(msg.name) + '</a></b>' +
+            '<div style="font-size: 0.7rem;">' + escapeHTML(msg.speakers) + ' &bull; ' + escapeHTML(msg.date) + listen + '</div>' +
+            '<div>&hellip;' + highlightHTML(text, highlight) + '&hellip;</div>' +
+            '</div>';
+    }).join('');
+    resultsMessage.textContent = total === 0
+        ? 'No results found'
+        : (total > maxResults ? 'Showing ' + maxResults + ' of ' + total + ' results' : total + ' result' + (total === 1 ? '' : 's'));
+};
+
+// Search a moment after the user stops typing
+let searchTimer = null;
+searchInput.addEventListener('input', () => {
+    clearTimeout(searchTimer);
+    searchTimer = setTimeout(() => {
+        searchTranscripts().catch(err => { resultsMessage.textContent = err.message; });
+    }, 300);
+});